@@ -0,0 +1,75 @@
+package builder
+
+import (
+	"automateLife/shell"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChangedTestCommand narrows a test command to the packages holding the
+// changed files. It returns an empty command when none of the files can
+// affect the tests, and ok false when the command has to run as is: the
+// language or command is not one it knows how to narrow, or a change such
+// as go.mod affects every package.
+func ChangedTestCommand(language string, command string, dir string, files []string) (narrowed string, ok bool) {
+	switch strings.ToLower(language) {
+	case "go", "golang":
+		return changedGoPackages(command, dir, files)
+	}
+	return command, false
+}
+
+// changedGoPackages replaces the ./... pattern of a go test command with
+// the directories of the changed .go files that still exist
+func changedGoPackages(command string, dir string, files []string) (string, bool) {
+	commands, err := shell.Parse(command)
+	if err != nil || len(commands) != 1 {
+		return command, false
+	}
+	fields := commands[0].Args
+	pattern := -1
+	for i, field := range fields {
+		if field == "./..." {
+			pattern = i
+		}
+	}
+	if len(fields) < 2 || fields[0] != "go" || fields[1] != "test" || pattern < 0 {
+		return command, false
+	}
+
+	seen := map[string]bool{}
+	var packages []string
+	for _, file := range files {
+		switch path.Base(file) {
+		case "go.mod", "go.sum", "go.work", "go.work.sum":
+			return command, false
+		}
+		if !strings.HasSuffix(file, ".go") || strings.Contains("/"+file, "/testdata/") {
+			continue
+		}
+		pkg := "./" + path.Dir(file)
+		if pkg == "./." {
+			pkg = "."
+		}
+		if seen[pkg] {
+			continue
+		}
+		seen[pkg] = true
+		// A package whose last file was deleted is gone
+		if remaining, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(path.Dir(file)), "*.go")); len(remaining) == 0 {
+			continue
+		}
+		packages = append(packages, pkg)
+	}
+	if len(packages) == 0 {
+		return "", true
+	}
+	sort.Strings(packages)
+
+	narrowed := append([]string{}, fields[:pattern]...)
+	narrowed = append(narrowed, packages...)
+	narrowed = append(narrowed, fields[pattern+1:]...)
+	return shell.Command{Env: commands[0].Env, Args: narrowed}.String(), true
+}
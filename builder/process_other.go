@@ -0,0 +1,15 @@
+//go:build !unix
+
+package builder
+
+import (
+	"os"
+	"os/exec"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalGroup stops cmd itself, process groups can't be signalled here
+func signalGroup(cmd *exec.Cmd, sig os.Signal) {
+	cmd.Process.Kill()
+}
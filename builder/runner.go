@@ -1,61 +1,118 @@
 package builder
 
 import (
+	"automateLife/redact"
+	"automateLife/shell"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 )
 
-func RunCommand(command string) error {
+// runCommand runs command, which may be a script of several lines. With
+// useShell the script goes to /bin/sh as a whole, which stops at the first
+// failing command. Otherwise every command is split into words with shell
+// quoting rules and run on its own, stopping at the first failure.
+// Commands run without input, in a process group of their own, with env
+// added to automateLife's environment.
+func runCommand(ctx context.Context, dir string, command string, useShell bool, env []string, grace time.Duration, stdoutTo io.Writer, stderrTo io.Writer) error {
+	stdout := redact.NewWriter(stdoutTo)
+	stderr := redact.NewWriter(stderrTo)
+	defer stdout.Flush()
+	defer stderr.Flush()
 
-	parts := strings.Fields(command)
-	if len(parts) == 0 {
-		return fmt.Errorf("empty command")
+	run := func(cmd *exec.Cmd) error {
+		cmd.Dir = dir
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		return runProcess(ctx, cmd, grace)
 	}
 
-	cmd := exec.Command(parts[0], parts[1:]...)
-	cmd.Stdout = os.Stdout
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
+	if useShell {
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("empty command")
+		}
+		cmd := exec.Command("/bin/sh", "-e", "-c", command)
+		if len(env) > 0 {
+			cmd.Env = append(os.Environ(), env...)
+		}
+		return run(cmd)
+	}
 
-	return cmd.Run()
+	commands, err := shell.ParseEnv(command, func(name string) string {
+		// The last assignment wins, as in the environment of the command
+		for i := len(env) - 1; i >= 0; i-- {
+			if value, ok := strings.CutPrefix(env[i], name+"="); ok {
+				return value
+			}
+		}
+		return os.Getenv(name)
+	})
+	if err != nil {
+		return err
+	}
+	if len(commands) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	for _, c := range commands {
+		if len(commands) > 1 {
+			fmt.Fprintf(stdout, "+ %s\n", c)
+		}
+		cmd := exec.Command(c.Args[0], c.Args[1:]...)
+		// Variables set in front of the command win
+		if len(env) > 0 || len(c.Env) > 0 {
+			cmd.Env = append(append(os.Environ(), env...), c.Env...)
+		}
+		if err := run(cmd); err != nil {
+			if len(commands) > 1 {
+				return fmt.Errorf("%s: %w", c, err)
+			}
+			return err
+		}
+	}
+	return nil
 }
 
-func AutoInstallDependencies(language string) error {
+// DefaultInstallCommand returns the command installing the dependencies of
+// the project in the working directory, or an empty string when it has none
+// to install
+func DefaultInstallCommand(language string) (string, error) {
 	switch strings.ToLower(language) {
 	case "go", "golang":
 		if _, err := os.Stat("go.mod"); err == nil {
-			return RunCommand("go mod download")
+			return "go mod download", nil
 		}
-		return nil // No go.mod, skip dependency installation
+		return "", nil // No go.mod, skip dependency installation
 	case "node", "nodejs", "javascript", "typescript":
 		if _, err := os.Stat("package.json"); err == nil {
 			if _, err := os.Stat("yarn.lock"); err == nil {
-				return RunCommand("yarn install")
+				return "yarn install", nil
 			}
-			return RunCommand("npm install")
+			return "npm install", nil
 		}
-		return nil // No package.json, skip dependency installation
+		return "", nil // No package.json, skip dependency installation
 	case "python":
 		if _, err := os.Stat("requirements.txt"); err == nil {
-			return RunCommand("pip install -r requirements.txt")
+			return "pip install -r requirements.txt", nil
 		}
 		if _, err := os.Stat("Pipfile"); err == nil {
-			return RunCommand("pipenv install")
+			return "pipenv install", nil
 		}
-		return nil // No requirements file, skip dependency installation
+		return "", nil // No requirements file, skip dependency installation
 	case "dotnet", "c#", "csharp":
-		return RunCommand("dotnet restore")
+		return "dotnet restore", nil
 	case "rust":
-		return RunCommand("cargo fetch")
+		return "cargo fetch", nil
 	case "ruby":
 		if _, err := os.Stat("Gemfile"); err == nil {
-			return RunCommand("bundle install")
+			return "bundle install", nil
 		}
-		return nil // No Gemfile, skip dependency installation
+		return "", nil // No Gemfile, skip dependency installation
 	}
-	return fmt.Errorf("could not determine how to install dependencies for language: %s", language)
+	return "", fmt.Errorf("could not determine how to install dependencies for language: %s", language)
 }
 
 func GetDefaultTestCommand(language string) string {
@@ -0,0 +1,123 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultGracePeriod is how long a stopped command gets to exit before it
+// is killed
+const DefaultGracePeriod = 10 * time.Second
+
+// ErrInterrupted is returned when the command was stopped by SIGINT or
+// SIGTERM sent to automateLife
+var ErrInterrupted = errors.New("interrupted")
+
+// TimeoutError reports a step that ran out of time
+type TimeoutError struct {
+	Step     string
+	Elapsed  time.Duration
+	Limit    time.Duration
+	Pipeline bool // The whole pipeline ran out of time rather than the step
+}
+
+func (e *TimeoutError) Error() string {
+	elapsed := e.Elapsed.Round(100 * time.Millisecond)
+	if e.Pipeline {
+		return fmt.Sprintf("%s stopped after %s, the pipeline ran out of time", e.Step, elapsed)
+	}
+	return fmt.Sprintf("%s timed out after %s (limit %s)", e.Step, elapsed, e.Limit)
+}
+
+// Step is a command run as one stage of the pipeline
+type Step struct {
+	Name    string // Reported when the step times out, e.g. "install"
+	Command string
+	Shell   bool
+	Dir     string        // Working directory, the current one when empty
+	Env     []string      // NAME=value pairs added to the environment
+	Timeout time.Duration // Zero for no limit
+	Grace   time.Duration // Between SIGTERM and SIGKILL, DefaultGracePeriod when zero
+}
+
+// Run runs the step until it exits, its timeout passes or ctx ends. ctx
+// carries the deadline of the whole pipeline.
+func (s Step) Run(ctx context.Context, stdout io.Writer, stderr io.Writer) error {
+	runCtx := ctx
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := runCommand(runCtx, s.Dir, s.Command, s.Shell, s.Env, s.Grace, stdout, stderr)
+	if err == nil || errors.Is(err, ErrInterrupted) || runCtx.Err() == nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return &TimeoutError{Step: s.Name, Elapsed: time.Since(start), Pipeline: true}
+		}
+		return err
+	}
+	return &TimeoutError{Step: s.Name, Elapsed: time.Since(start), Limit: s.Timeout}
+}
+
+// runProcess runs cmd in a process group of its own, so that stopping it
+// also stops everything it started. When ctx ends, or automateLife gets
+// SIGINT or SIGTERM, the group receives SIGTERM (or the signal itself) and
+// is killed if it hasn't exited after the grace period, or right away on a
+// second signal.
+func runProcess(ctx context.Context, cmd *exec.Cmd, grace time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if grace <= 0 {
+		grace = DefaultGracePeriod
+	}
+	setProcessGroup(cmd)
+	// Children holding on to the output pipes mustn't keep Wait from returning
+	cmd.WaitDelay = grace + time.Second
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var stopErr error
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		stopErr = ctx.Err()
+		signalGroup(cmd, syscall.SIGTERM)
+	case sig := <-signals:
+		stopErr = ErrInterrupted
+		signalGroup(cmd, sig)
+	}
+
+	select {
+	case <-done:
+		return stopErr
+	case <-signals:
+		// Asked again, don't wait for a step that is slow to stop
+		stopErr = ErrInterrupted
+	case <-time.After(grace):
+	}
+	signalGroup(cmd, syscall.SIGKILL)
+	<-done
+	return stopErr
+}
@@ -0,0 +1,491 @@
+package ci
+
+import (
+	"automateLife/config"
+	"automateLife/shell"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatGitHub = "github-actions"
+	FormatAzure  = "azure-pipelines"
+	FormatGitLab = "gitlab-ci"
+)
+
+// Import holds the build settings translated from a CI definition
+type Import struct {
+	Format         string
+	InstallCommand string
+	BuildCommand   string
+	TestCommand    string
+	Variables      map[string]string
+	Untranslated   []string // Human readable notes for steps that could not be mapped
+}
+
+// step is a single command found in a CI definition together with
+// the job or stage name it belongs to, which helps classify it
+type step struct {
+	context string
+	command string
+}
+
+// Matches CI-specific expressions that have no meaning outside the CI runner
+var (
+	githubExpression = regexp.MustCompile(`\$\{\{.*?\}\}`)
+	azureMacro       = regexp.MustCompile(`\$\([A-Za-z_][A-Za-z0-9_.]*\)`)
+)
+
+// ImportFile parses a GitHub Actions workflow, Azure Pipelines definition
+// or GitLab CI file and maps its steps onto build commands
+func ImportFile(path string) (*Import, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CI file: %w", err)
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse CI file: %w", err)
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("CI file %s is empty", path)
+	}
+
+	imp := &Import{
+		Format:    detectFormat(path, doc),
+		Variables: make(map[string]string),
+	}
+
+	var steps []step
+	switch imp.Format {
+	case FormatGitHub:
+		steps = imp.parseGitHub(doc)
+	case FormatAzure:
+		steps = imp.parseAzure(doc)
+	case FormatGitLab:
+		steps = imp.parseGitLab(doc)
+	default:
+		return nil, fmt.Errorf("could not recognise %s as a GitHub Actions, Azure Pipelines or GitLab CI file", path)
+	}
+
+	imp.classify(steps)
+	return imp, nil
+}
+
+// Apply copies the imported commands and variables into cfg, keeping
+// existing values for anything the CI file did not describe
+func (i *Import) Apply(cfg *config.Config) {
+	if i.InstallCommand != "" {
+		cfg.Build.InstallCommand = i.InstallCommand
+	}
+	if i.BuildCommand != "" {
+		cfg.Build.BuildCommand = i.BuildCommand
+	}
+	if i.TestCommand != "" {
+		cfg.Build.TestCommand = i.TestCommand
+	}
+	// CI steps are shell scripts, keep running them as such when they need it
+	for _, command := range []string{i.InstallCommand, i.BuildCommand, i.TestCommand} {
+		if shell.NeedsShell(command) {
+			cfg.Build.Shell = true
+		}
+	}
+	if len(i.Variables) > 0 && cfg.Environment.Variables == nil {
+		cfg.Environment.Variables = make(map[string]string)
+	}
+	for key, value := range i.Variables {
+		cfg.Environment.Variables[key] = value
+	}
+}
+
+func detectFormat(path string, doc map[string]interface{}) string {
+	slashed := filepath.ToSlash(path)
+	base := filepath.Base(path)
+
+	switch {
+	case strings.Contains(slashed, ".github/workflows/"):
+		return FormatGitHub
+	case strings.HasPrefix(base, "azure-pipelines"):
+		return FormatAzure
+	case base == ".gitlab-ci.yml" || base == ".gitlab-ci.yaml":
+		return FormatGitLab
+	}
+
+	// Fall back to the shape of the document
+	if _, ok := doc["jobs"].(map[string]interface{}); ok {
+		if _, ok := doc["on"]; ok {
+			return FormatGitHub
+		}
+	}
+	for _, key := range []string{"trigger", "pool", "pr"} {
+		if _, ok := doc[key]; ok {
+			return FormatAzure
+		}
+	}
+	if _, ok := doc["steps"]; ok {
+		return FormatAzure
+	}
+	if _, ok := doc["stages"].([]interface{}); ok {
+		for _, value := range doc["stages"].([]interface{}) {
+			if _, ok := value.(map[string]interface{}); ok {
+				return FormatAzure
+			}
+		}
+		return FormatGitLab
+	}
+	return ""
+}
+
+func (i *Import) parseGitHub(doc map[string]interface{}) []step {
+	i.addVariables("workflow env", doc["env"], githubExpression)
+
+	var steps []step
+	jobs, _ := doc["jobs"].(map[string]interface{})
+	for _, jobName := range sortedKeys(jobs) {
+		job, _ := jobs[jobName].(map[string]interface{})
+		i.addVariables("job "+jobName+" env", job["env"], githubExpression)
+
+		jobSteps, _ := job["steps"].([]interface{})
+		for index, raw := range jobSteps {
+			s, _ := raw.(map[string]interface{})
+			name := stepName(s, jobName, index)
+			i.addVariables(name+" env", s["env"], githubExpression)
+
+			if run, ok := s["run"].(string); ok {
+				steps = append(steps, i.scriptStep(jobName+" "+name, run, githubExpression)...)
+				continue
+			}
+			if uses, ok := s["uses"].(string); ok {
+				// Cloning is handled by 'automateLife start'
+				if strings.HasPrefix(uses, "actions/checkout") {
+					continue
+				}
+				i.note("%s: action %q has no command equivalent", name, uses)
+			}
+		}
+	}
+	return steps
+}
+
+func (i *Import) parseAzure(doc map[string]interface{}) []step {
+	i.addAzureVariables("pipeline", doc["variables"])
+
+	var steps []step
+	steps = append(steps, i.azureSteps("pipeline", doc["steps"])...)
+
+	jobs, _ := doc["jobs"].([]interface{})
+	steps = append(steps, i.azureJobs(jobs)...)
+
+	stages, _ := doc["stages"].([]interface{})
+	for _, raw := range stages {
+		stage, _ := raw.(map[string]interface{})
+		i.addAzureVariables(fmt.Sprint(stage["stage"]), stage["variables"])
+		stageJobs, _ := stage["jobs"].([]interface{})
+		steps = append(steps, i.azureJobs(stageJobs)...)
+	}
+	return steps
+}
+
+func (i *Import) azureJobs(jobs []interface{}) []step {
+	var steps []step
+	for _, raw := range jobs {
+		job, _ := raw.(map[string]interface{})
+		name := fmt.Sprint(job["job"])
+		if name == "<nil>" {
+			name = fmt.Sprint(job["deployment"])
+		}
+		i.addAzureVariables(name, job["variables"])
+		steps = append(steps, i.azureSteps(name, job["steps"])...)
+	}
+	return steps
+}
+
+func (i *Import) azureSteps(context string, raw interface{}) []step {
+	var steps []step
+	list, _ := raw.([]interface{})
+	for index, item := range list {
+		s, _ := item.(map[string]interface{})
+		name := stepName(s, context, index)
+		i.addVariables(name+" env", s["env"], azureMacro)
+
+		translated := false
+		for _, key := range []string{"script", "bash"} {
+			if script, ok := s[key].(string); ok {
+				steps = append(steps, i.scriptStep(context+" "+name, script, azureMacro)...)
+				translated = true
+			}
+		}
+		if translated {
+			continue
+		}
+
+		switch {
+		case s["checkout"] != nil:
+			// Cloning is handled by 'automateLife start'
+		case s["task"] != nil:
+			i.note("%s: task %q has no command equivalent", name, s["task"])
+		case s["pwsh"] != nil, s["powershell"] != nil:
+			i.note("%s: PowerShell steps are not supported", name)
+		case s["template"] != nil:
+			i.note("%s: template %q was not expanded", name, s["template"])
+		}
+	}
+	return steps
+}
+
+func (i *Import) addAzureVariables(context string, raw interface{}) {
+	switch vars := raw.(type) {
+	case map[string]interface{}:
+		i.addVariables(context+" variables", vars, azureMacro)
+	case []interface{}:
+		// List form: - name: X / value: Y, or - group: Z
+		for _, item := range vars {
+			entry, _ := item.(map[string]interface{})
+			if name, ok := entry["name"].(string); ok {
+				i.addVariables(context+" variables", map[string]interface{}{name: entry["value"]}, azureMacro)
+			} else if group, ok := entry["group"]; ok {
+				i.note("%s variables: variable group %q must be configured manually", context, group)
+			}
+		}
+	}
+}
+
+// GitLab reserves these top-level keys, every other mapping is a job
+var gitlabKeywords = map[string]bool{
+	"stages": true, "variables": true, "image": true, "services": true,
+	"before_script": true, "after_script": true, "cache": true, "default": true,
+	"include": true, "workflow": true,
+}
+
+func (i *Import) parseGitLab(doc map[string]interface{}) []step {
+	i.addVariables("global variables", doc["variables"], nil)
+
+	var steps []step
+	globalBefore := scriptLines(doc["before_script"])
+	if defaults, ok := doc["default"].(map[string]interface{}); ok && len(globalBefore) == 0 {
+		globalBefore = scriptLines(defaults["before_script"])
+	}
+	for _, line := range globalBefore {
+		steps = append(steps, i.scriptStep("before_script", line, nil)...)
+	}
+
+	for _, jobName := range sortedKeys(doc) {
+		if gitlabKeywords[jobName] || strings.HasPrefix(jobName, ".") {
+			continue
+		}
+		job, ok := doc[jobName].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, ok := job["extends"]; ok {
+			i.note("job %s: 'extends' was not resolved", jobName)
+		}
+		i.addVariables("job "+jobName+" variables", job["variables"], nil)
+
+		context := jobName
+		if stage, ok := job["stage"].(string); ok {
+			context = stage + " " + jobName
+		}
+		for _, key := range []string{"before_script", "script"} {
+			for _, line := range scriptLines(job[key]) {
+				steps = append(steps, i.scriptStep(context, line, nil)...)
+			}
+		}
+	}
+	return steps
+}
+
+// scriptStep turns a run block into a single step, so that the commands of
+// one block stay together in the stage the whole block is classified into.
+// Commands relying on CI-only expressions are dropped, a continued line or
+// compound command such as if ... fi as a whole.
+func (i *Import) scriptStep(context string, script string, expression *regexp.Regexp) []step {
+	var kept []string
+	for _, command := range shellCommands(script) {
+		if expression != nil && expression.MatchString(command) {
+			i.note("%s: %q uses CI-only expressions", context, command)
+			continue
+		}
+		kept = append(kept, command)
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return []step{{context: strings.ToLower(context), command: strings.Join(kept, "\n")}}
+}
+
+// Words opening and closing compound commands
+var (
+	compoundOpen   = map[string]bool{"if": true, "for": true, "while": true, "until": true, "case": true, "select": true, "{": true}
+	compoundClose  = map[string]bool{"fi": true, "done": true, "esac": true, "}": true}
+	heredocPattern = regexp.MustCompile(`<<-?\s*['"]?([A-Za-z_][A-Za-z0-9_]*)['"]?`)
+	quotedPattern  = regexp.MustCompile(`'[^']*'|"(?:[^"\\]|\\.)*"`)
+)
+
+// shellCommands splits a script into its top-level commands, trimmed and
+// without blank lines and comments. Lines ending in a backslash, compound
+// commands and here-documents are kept whole with the lines they span.
+func shellCommands(script string) []string {
+	var commands, current []string
+	depth := 0
+	heredoc := ""
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if heredoc != "" {
+			current = append(current, line)
+			if strings.TrimLeft(line, "\t") == heredoc {
+				heredoc = ""
+			}
+		} else {
+			if len(current) == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "#")) {
+				continue
+			}
+			current = append(current, trimmed)
+
+			// Quoted text and comments can't open or close anything
+			code := quotedPattern.ReplaceAllString(trimmed, "''")
+			if comment := strings.Index(code, " #"); comment >= 0 {
+				code = code[:comment]
+			}
+			for _, word := range strings.FieldsFunc(code, func(r rune) bool { return strings.ContainsRune(" \t;&|()", r) }) {
+				switch {
+				case compoundOpen[word]:
+					depth++
+				case compoundClose[word] && depth > 0:
+					depth--
+				}
+			}
+			if match := heredocPattern.FindStringSubmatch(trimmed); match != nil {
+				heredoc = match[1]
+			}
+		}
+
+		continued := strings.HasSuffix(trimmed, "\\") && !strings.HasSuffix(trimmed, "\\\\")
+		if heredoc == "" && depth == 0 && !continued {
+			commands = append(commands, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		commands = append(commands, strings.Join(current, "\n"))
+	}
+	return commands
+}
+
+func (i *Import) addVariables(context string, raw interface{}, expression *regexp.Regexp) {
+	vars, _ := raw.(map[string]interface{})
+	for _, key := range sortedKeys(vars) {
+		value := fmt.Sprint(vars[key])
+		if vars[key] == nil {
+			value = ""
+		}
+		if expression != nil && expression.MatchString(value) {
+			i.note("%s: %s=%q uses CI-only expressions", context, key, value)
+			continue
+		}
+		i.Variables[key] = value
+	}
+}
+
+func (i *Import) classify(steps []step) {
+	var install, build, test []string
+	for _, s := range steps {
+		switch classifyCommand(s) {
+		case "install":
+			install = append(install, s.command)
+		case "build":
+			build = append(build, s.command)
+		case "test":
+			test = append(test, s.command)
+		default:
+			i.note("%s: %q could not be mapped to install, build or test", s.context, s.command)
+		}
+	}
+	// Scripts of several lines run one command after the other
+	i.InstallCommand = strings.Join(install, "\n")
+	i.BuildCommand = strings.Join(build, "\n")
+	i.TestCommand = strings.Join(test, "\n")
+}
+
+func classifyCommand(s step) string {
+	command := strings.ToLower(s.command)
+	fields := strings.Fields(command)
+
+	has := func(words ...string) bool {
+		for _, field := range fields {
+			for _, word := range words {
+				if field == word {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	switch {
+	case has("install", "restore", "download", "fetch", "ci", "sync") && !has("test"):
+		return "install"
+	case has("test", "pytest", "rspec", "jest", "vitest", "tox", "nox"):
+		return "test"
+	case has("build", "compile", "package", "publish", "tsc"):
+		return "build"
+	}
+
+	// Fall back to the job or stage name the command lives in
+	switch {
+	case strings.Contains(s.context, "test"):
+		return "test"
+	case strings.Contains(s.context, "build"):
+		return "build"
+	case strings.Contains(s.context, "install"), strings.Contains(s.context, "setup"):
+		return "install"
+	}
+	return ""
+}
+
+func (i *Import) note(format string, args ...interface{}) {
+	i.Untranslated = append(i.Untranslated, fmt.Sprintf(format, args...))
+}
+
+func stepName(s map[string]interface{}, context string, index int) string {
+	if name, ok := s["name"].(string); ok && name != "" {
+		return fmt.Sprintf("step %q", name)
+	}
+	if name, ok := s["displayName"].(string); ok && name != "" {
+		return fmt.Sprintf("step %q", name)
+	}
+	return fmt.Sprintf("%s step %d", context, index+1)
+}
+
+// scriptLines accepts either a single string or a list of strings
+func scriptLines(raw interface{}) []string {
+	switch value := raw.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		lines := make([]string, 0, len(value))
+		for _, item := range value {
+			if line, ok := item.(string); ok {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"automateLife/shell"
+	"encoding/json"
+	"fmt"
+)
+
+// commandValue reads a command written either as a string or as an argv
+// array. Arrays are quoted into a string that parses back to the same words.
+type commandValue string
+
+func (c *commandValue) UnmarshalJSON(data []byte) error {
+	var line string
+	if err := json.Unmarshal(data, &line); err == nil {
+		*c = commandValue(line)
+		return nil
+	}
+	var argv []string
+	if err := json.Unmarshal(data, &argv); err != nil {
+		return fmt.Errorf("a command must be a string or an array of strings")
+	}
+	*c = commandValue(shell.Join(argv))
+	return nil
+}
+
+// UnmarshalJSON accepts the commands as strings or argv arrays
+func (b *BuildConfig) UnmarshalJSON(data []byte) error {
+	type plain BuildConfig
+	var raw struct {
+		plain
+		InstallCommand commandValue `json:"install_command"`
+		BuildCommand   commandValue `json:"build_command"`
+		TestCommand    commandValue `json:"test_command"`
+	}
+	// Keep what is already set for keys the JSON leaves out
+	raw.plain = plain(*b)
+	raw.InstallCommand = commandValue(b.InstallCommand)
+	raw.BuildCommand = commandValue(b.BuildCommand)
+	raw.TestCommand = commandValue(b.TestCommand)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*b = BuildConfig(raw.plain)
+	b.InstallCommand = string(raw.InstallCommand)
+	b.BuildCommand = string(raw.BuildCommand)
+	b.TestCommand = string(raw.TestCommand)
+	return nil
+}
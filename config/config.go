@@ -1,20 +1,24 @@
 package config
 
 import (
+	"automateLife/redact"
 	"automateLife/utils"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
 const DefaultConfigFileName = "ConfigFile.json"
 
 type Config struct {
-	Git         GitConfig         `json:"git"`
-	Project     ProjectConfig     `json:"project"`
-	Build       BuildConfig       `json:"build"`
-	Azure       AzureConfig       `json:"azure"`
-	Environment EnvironmentConfig `json:"environment"`
+	Git         GitConfig          `json:"git"`
+	Project     ProjectConfig      `json:"project"`
+	Build       BuildConfig        `json:"build"`
+	Logs        LogsConfig         `json:"logs"`
+	Azure       AzureConfig        `json:"azure"`
+	Environment EnvironmentConfig  `json:"environment"`
+	Workspace   []RepositoryConfig `json:"workspace,omitempty"` // Further repositories sharing the git auth
 }
 
 type GitConfig struct {
@@ -24,8 +28,28 @@ type GitConfig struct {
 	UserName   string `json:"username"`
 	Password   string `json:"password"`
 	Branch     string `json:"branch"`
+	Ref        string `json:"ref"` // Branch, tag or commit SHA to build, overrides branch
 	Token      string `json:"token"`
 	SSHKeyPath string `json:"ssh_key_path"`
+	CloneDir   string `json:"clone_dir"` // Relative to the config file, defaults to the repository name
+
+	// SSH options
+	SSHAgent            bool     `json:"ssh_agent"`             // Use keys loaded in ssh-agent instead of ssh_key_path
+	SSHPassphrase       string   `json:"ssh_passphrase"`        // Passphrase of an encrypted key, usually a $VARIABLE reference
+	SSHHostFingerprints []string `json:"ssh_host_fingerprints"` // Pinned SHA256 host key fingerprints of the git server
+
+	// Clone tuning for large repositories
+	Depth             int      `json:"depth"`              // 0 clones the full history
+	SingleBranch      bool     `json:"single_branch"`      // Only fetch the configured branch
+	SparsePaths       []string `json:"sparse_paths"`       // Cone-mode sparse checkout directories
+	RecurseSubmodules bool     `json:"recurse_submodules"` // Clone and update submodules with the same credentials
+	LFS               string   `json:"lfs"`                // "" (git default), "pull" or "skip"
+	Cache             bool     `json:"cache"`              // Clone through the shared mirror cache
+
+	// Signature verification of the checked out commit or pinned tag
+	RequireSigned  bool   `json:"require_signed"`
+	AllowedSigners string `json:"allowed_signers"` // SSH allowed signers file
+	GPGKeyring     string `json:"gpg_keyring"`     // GnuPG home directory or exported public keys
 }
 
 type ProjectConfig struct {
@@ -35,11 +59,30 @@ type ProjectConfig struct {
 }
 
 type BuildConfig struct {
-	Language       string `json:"language"` //go, dotnet, python
-	InstallCommand string `json:"install_command"`
-	BuildCommand   string `json:"build_command"`
-	TestCommand    string `json:"test_command"`
-	OutputDir      string `json:"output_dir"`
+	Language       string         `json:"language"` //go, dotnet, python
+	InstallCommand string         `json:"install_command"`
+	BuildCommand   string         `json:"build_command"`
+	TestCommand    string         `json:"test_command"`
+	OutputDir      string         `json:"output_dir"`
+	Shell          bool           `json:"shell"` // Run the commands with /bin/sh -c instead of splitting them into words
+	Timeouts       TimeoutsConfig `json:"timeouts"`
+}
+
+// TimeoutsConfig limits how long the pipeline may run, as durations such as
+// "10m" or "1h30m". Empty means no limit.
+type TimeoutsConfig struct {
+	Install     string `json:"install"`
+	Build       string `json:"build"`
+	Test        string `json:"test"`
+	Pipeline    string `json:"pipeline"`     // All steps together
+	GracePeriod string `json:"grace_period"` // Between asking a step to stop and killing it, 10s when empty
+}
+
+// LogsConfig controls the step output saved under .automatelife/runs
+type LogsConfig struct {
+	Timestamps bool `json:"timestamps"` // Start every line with the time it was written
+	Streams    bool `json:"streams"`    // Tag every line with the stream it came from
+	Keep       int  `json:"keep"`       // Runs to keep, 20 when 0
 }
 
 type AzureConfig struct {
@@ -65,18 +108,45 @@ func DefaultConfigTemplate() string {
     "provider": "github",
     "repo_url": "",
     "branch": "main",
+    "ref": "",
     "auth_type": "token",
     "username": "",
     "password": "",
     "token": "",
-    "ssh_key_path": ""
+    "ssh_key_path": "",
+    "ssh_agent": false,
+    "ssh_passphrase": "",
+    "ssh_host_fingerprints": [],
+    "clone_dir": "",
+    "depth": 0,
+    "single_branch": false,
+    "sparse_paths": [],
+    "recurse_submodules": false,
+    "lfs": "",
+    "cache": false,
+    "require_signed": false,
+    "allowed_signers": "",
+    "gpg_keyring": ""
   },
   "build": {
     "language": "go",
     "install_command": "",
     "build_command": "",
     "test_command": "",
-    "output_dir": "./bin"
+    "output_dir": "./bin",
+    "shell": false,
+    "timeouts": {
+      "install": "",
+      "build": "",
+      "test": "",
+      "pipeline": "",
+      "grace_period": ""
+    }
+  },
+  "logs": {
+    "timestamps": false,
+    "streams": false,
+    "keep": 20
   },
   "azure": {
     "subscription_id": "",
@@ -94,6 +164,23 @@ func DefaultConfigTemplate() string {
 }
 
 func Load(fileName string) (*Config, error) {
+	config, err := Read(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expand all paths in the config
+	config.ExpandPaths()
+
+	// Credentials must never appear in output, whichever command prints it
+	redact.Add(config.Git.Token, config.Git.Password, config.Git.SSHPassphrase)
+
+	return config, nil
+}
+
+// Read decodes the config file as written, without expanding variables,
+// so it can be edited and saved back without baking in expanded values
+func Read(fileName string) (*Config, error) {
 	file, err := os.Open(fileName)
 	if err != nil {
 		return nil, fmt.Errorf("config file not found: %w", err)
@@ -106,12 +193,22 @@ func Load(fileName string) (*Config, error) {
 		return nil, fmt.Errorf("failed to decode config: %w", err)
 	}
 
-	// Expand all paths in the config
-	config.ExpandPaths()
-
 	return &config, nil
 }
 
+// Expanded returns a copy of the config with all variables expanded,
+// leaving the receiver untouched
+func (c *Config) Expanded() *Config {
+	expanded := *c
+	expanded.Environment.Variables = make(map[string]string, len(c.Environment.Variables))
+	for key, value := range c.Environment.Variables {
+		expanded.Environment.Variables[key] = value
+	}
+	expanded.Workspace = append([]RepositoryConfig(nil), c.Workspace...)
+	expanded.ExpandPaths()
+	return &expanded
+}
+
 // ExpandPaths expands all environment variables in all string fields of the config
 // Users can use $VAR, ${VAR}, or ~ in any field
 func (c *Config) ExpandPaths() {
@@ -122,19 +219,23 @@ func (c *Config) ExpandPaths() {
 	c.Git.UserName = utils.ExpandEnvVars(c.Git.UserName)
 	c.Git.Password = utils.ExpandEnvVars(c.Git.Password)
 	c.Git.Branch = utils.ExpandEnvVars(c.Git.Branch)
+	c.Git.Ref = utils.ExpandEnvVars(c.Git.Ref)
 	c.Git.Token = utils.ExpandEnvVars(c.Git.Token)
 	c.Git.SSHKeyPath = utils.ExpandEnvVars(c.Git.SSHKeyPath)
+	c.Git.SSHPassphrase = utils.ExpandEnvVars(c.Git.SSHPassphrase)
+	c.Git.CloneDir = utils.ExpandEnvVars(c.Git.CloneDir)
+	c.Git.LFS = utils.ExpandEnvVars(c.Git.LFS)
+	c.Git.AllowedSigners = utils.ExpandEnvVars(c.Git.AllowedSigners)
+	c.Git.GPGKeyring = utils.ExpandEnvVars(c.Git.GPGKeyring)
 
 	// Expand Project fields
 	c.Project.Name = utils.ExpandEnvVars(c.Project.Name)
 	c.Project.Type = utils.ExpandEnvVars(c.Project.Type)
 	c.Project.Description = utils.ExpandEnvVars(c.Project.Description)
 
-	// Expand Build fields
+	// Expand Build fields. The commands are left alone: shell.Parse or
+	// /bin/sh expand them when they run, respecting their quotes.
 	c.Build.Language = utils.ExpandEnvVars(c.Build.Language)
-	c.Build.InstallCommand = utils.ExpandEnvVars(c.Build.InstallCommand)
-	c.Build.BuildCommand = utils.ExpandEnvVars(c.Build.BuildCommand)
-	c.Build.TestCommand = utils.ExpandEnvVars(c.Build.TestCommand)
 	c.Build.OutputDir = utils.ExpandEnvVars(c.Build.OutputDir)
 
 	// Expand Azure fields
@@ -148,10 +249,38 @@ func (c *Config) ExpandPaths() {
 	for key, value := range c.Environment.Variables {
 		c.Environment.Variables[key] = utils.ExpandEnvVars(value)
 	}
+
+	for i := range c.Workspace {
+		c.Workspace[i].expandPaths()
+	}
+}
+
+// Find looks for fileName in the current directory and its parents, so
+// commands also work from inside the cloned repository. It returns
+// fileName unchanged when no config file is found.
+func Find(fileName string) string {
+	if filepath.IsAbs(fileName) {
+		return fileName
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return fileName
+	}
+	for {
+		candidate := filepath.Join(dir, fileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fileName
+		}
+		dir = parent
+	}
 }
 
 func Create(fileName string, content string) error {
-	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_EXCL|os.O_RDWR, fileModeFor([]byte(content)))
 	if err != nil {
 		if os.IsExist(err) {
 			return fmt.Errorf("config file already exists")
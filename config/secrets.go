@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	// DefaultFileMode is used for config files without credentials
+	DefaultFileMode os.FileMode = 0644
+	// SecretFileMode restricts config files holding credentials to their owner
+	SecretFileMode os.FileMode = 0600
+)
+
+// A value that only references an environment variable is not a secret itself
+var variableReference = regexp.MustCompile(`^\$(\{[A-Za-z_][A-Za-z0-9_]*\}|[A-Za-z_][A-Za-z0-9_]*)$`)
+
+// HasSecrets reports whether the config holds a literal token, password or passphrase
+func (c *Config) HasSecrets() bool {
+	return isLiteralSecret(c.Git.Token) || isLiteralSecret(c.Git.Password) || isLiteralSecret(c.Git.SSHPassphrase)
+}
+
+func isLiteralSecret(value string) bool {
+	return value != "" && !variableReference.MatchString(value)
+}
+
+// fileModeFor picks the permissions for a config file with the given content
+func fileModeFor(content []byte) os.FileMode {
+	var cfg Config
+	if err := json.Unmarshal(content, &cfg); err == nil && cfg.HasSecrets() {
+		return SecretFileMode
+	}
+	return DefaultFileMode
+}
+
+// Save writes the config to fileName, tightening the file permissions
+// to owner-only whenever it holds credentials. The file is replaced in one
+// step, so the credentials are never readable with the old permissions.
+func Save(fileName string, cfg *Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	mode := fileModeFor(data)
+	if info, err := os.Stat(fileName); err == nil && mode != SecretFileMode {
+		// Without credentials the permissions the file already has are kept
+		mode = info.Mode().Perm()
+	}
+
+	// CreateTemp makes the file owner-only until it is complete
+	tmp, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set config file permissions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), fileName); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// IsWorldReadable reports whether any user on the machine can read the file
+func IsWorldReadable(fileName string) (bool, error) {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return false, err
+	}
+	return info.Mode().Perm()&0004 != 0, nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"context"
+	"time"
+)
+
+// Step returns the time limit of step ("install", "build", "test" or
+// "pipeline"), zero when it has none
+func (t TimeoutsConfig) Step(step string) time.Duration {
+	switch step {
+	case "install":
+		return parseTimeout(t.Install)
+	case "build":
+		return parseTimeout(t.Build)
+	case "test":
+		return parseTimeout(t.Test)
+	case "pipeline":
+		return parseTimeout(t.Pipeline)
+	}
+	return 0
+}
+
+// Grace returns how long a stopped step gets to exit, zero for the default
+func (t TimeoutsConfig) Grace() time.Duration {
+	return parseTimeout(t.GracePeriod)
+}
+
+func parseTimeout(value string) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Context returns a context ending when the pipeline runs out of time
+func (t TimeoutsConfig) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	if limit := t.Step("pipeline"); limit > 0 {
+		return context.WithTimeout(parent, limit)
+	}
+	return context.WithCancel(parent)
+}
@@ -1,40 +1,178 @@
 package config
 
 import (
+	"automateLife/provider"
+	"automateLife/shell"
 	"automateLife/utils"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
+// FieldError is a validation failure tied to a single config field,
+// identified by its JSON path (e.g. "git.token")
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
 func (c *Config) Validate() error {
-	if c.Git.RepoUrl == "" {
-		return fmt.Errorf("git.repo_url is required")
+	if errs := c.ValidateAll(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ValidateAll runs every check and returns all failures instead of stopping at the first one
+func (c *Config) ValidateAll() []FieldError {
+	var errs []FieldError
+	add := func(field string, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// A workspace may leave the git section to the shared auth settings
+	if c.Git.RepoUrl == "" && len(c.Workspace) == 0 {
+		add("git.repo_url", "git.repo_url is required")
+	}
+	if p, err := provider.Lookup(c.Git.Provider); err != nil {
+		add("git.provider", "%s", err.Error())
+	} else if p != nil && c.Git.RepoUrl != "" {
+		if _, err := p.Parse(c.Git.RepoUrl); err != nil {
+			add("git.repo_url", "%s", err.Error())
+		}
 	}
 	if c.Project.Type == "" {
-		return fmt.Errorf("project.type is required")
+		add("project.type", "project.type is required")
 	}
 
 	switch c.Git.AuthType {
 	case "token":
 		if c.Git.Token == "" {
-			return fmt.Errorf("git.token is required when auth_type is 'token'")
+			add("git.token", "git.token is required when auth_type is 'token'")
 		}
 	case "basic":
-		if c.Git.UserName == "" || c.Git.Password == "" {
-			return fmt.Errorf("git.username and git.password are required when auth_type is 'basic'")
+		if c.Git.UserName == "" {
+			add("git.username", "git.username is missing, git.username and git.password are required when auth_type is 'basic'")
+		}
+		if c.Git.Password == "" {
+			add("git.password", "git.password is missing, git.username and git.password are required when auth_type is 'basic'")
 		}
 	case "ssh":
+		for _, fingerprint := range c.Git.SSHHostFingerprints {
+			if !strings.HasPrefix(fingerprint, "SHA256:") {
+				add("git.ssh_host_fingerprints", "git.ssh_host_fingerprints entries must be SHA256 fingerprints such as SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU, got %q", fingerprint)
+				break
+			}
+		}
 		if c.Git.SSHKeyPath == "" {
-			return fmt.Errorf("git.ssh_key_path is required when auth_type is 'ssh'")
+			if !c.Git.SSHAgent {
+				add("git.ssh_key_path", "git.ssh_key_path is required when auth_type is 'ssh' (or enable git.ssh_agent)")
+			}
+			break
 		}
 		// Expand path in case it wasn't expanded yet
 		expandedPath := utils.ExpandEnvVars(c.Git.SSHKeyPath)
 		if _, err := os.Stat(expandedPath); os.IsNotExist(err) {
-			return fmt.Errorf("SSH key not found at: %s (expanded from: %s)", expandedPath, c.Git.SSHKeyPath)
+			add("git.ssh_key_path", "SSH key not found at: %s (expanded from: %s)", expandedPath, c.Git.SSHKeyPath)
 		}
 	default:
-		return fmt.Errorf("git.auth_type must be 'token', 'basic', or 'ssh'")
+		add("git.auth_type", "git.auth_type must be 'token', 'basic', or 'ssh'")
 	}
 
-	return nil
+	if ref := c.Git.Ref; ref != "" && (strings.HasPrefix(ref, "-") || strings.ContainsAny(ref, " \t\n")) {
+		add("git.ref", "git.ref must be a branch, tag or commit SHA, got %q", ref)
+	}
+
+	if c.Git.Depth < 0 {
+		add("git.depth", "git.depth must be 0 (full history) or a positive number of commits")
+	}
+	for _, path := range c.Git.SparsePaths {
+		cleaned := filepath.ToSlash(filepath.Clean(path))
+		if strings.TrimSpace(path) == "" || filepath.IsAbs(path) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			add("git.sparse_paths", "git.sparse_paths entries must be directories inside the repository, got %q", path)
+			break
+		}
+	}
+	switch c.Git.LFS {
+	case "", "pull", "skip":
+	default:
+		add("git.lfs", "git.lfs must be empty, 'pull' or 'skip'")
+	}
+
+	if c.Git.RequireSigned && c.Git.AllowedSigners == "" && c.Git.GPGKeyring == "" {
+		add("git.allowed_signers", "git.require_signed needs the trusted keys in git.allowed_signers (SSH) or git.gpg_keyring (GPG)")
+	}
+	for _, key := range [][2]string{{"git.allowed_signers", c.Git.AllowedSigners}, {"git.gpg_keyring", c.Git.GPGKeyring}} {
+		if key[1] == "" {
+			continue
+		}
+		expandedPath := utils.ExpandEnvVars(key[1])
+		if _, err := os.Stat(expandedPath); os.IsNotExist(err) {
+			add(key[0], "%s not found at: %s (expanded from: %s)", key[0], expandedPath, key[1])
+		}
+	}
+
+	validateBuild("build", &c.Build, add)
+
+	if c.Logs.Keep < 0 {
+		add("logs.keep", "logs.keep must be a positive number of runs, or 0 for the default")
+	}
+
+	p, _ := provider.Lookup(c.Git.Provider)
+	names := map[string]bool{}
+	for i := range c.Workspace {
+		repo := &c.Workspace[i]
+		field := fmt.Sprintf("workspace[%d]", i)
+		if repo.RepoUrl == "" {
+			add(field+".repo_url", "%s.repo_url is required", field)
+			continue
+		}
+		if p != nil {
+			if _, err := p.Parse(repo.RepoUrl); err != nil {
+				add(field+".repo_url", "%s", err.Error())
+			}
+		}
+		if ref := repo.Ref; strings.HasPrefix(ref, "-") || strings.ContainsAny(ref, " \t\n") {
+			add(field+".ref", "%s.ref must be a branch, tag or commit SHA, got %q", field, ref)
+		}
+		build := c.RepoBuild(repo)
+		validateBuild(field+".build", &build, add)
+		name := repo.RepoName()
+		if names[name] {
+			add(field+".name", "workspace repository names must be unique, %q is used twice (set name to tell them apart)", name)
+		}
+		names[name] = true
+	}
+
+	return errs
+}
+
+// validateBuild checks the timeouts of build and that its commands can be
+// run: without build.shell they must split into plain commands
+func validateBuild(section string, build *BuildConfig, add func(field string, format string, args ...interface{})) {
+	for _, timeout := range [][2]string{{"install", build.Timeouts.Install}, {"build", build.Timeouts.Build}, {"test", build.Timeouts.Test}, {"pipeline", build.Timeouts.Pipeline}, {"grace_period", build.Timeouts.GracePeriod}} {
+		if timeout[1] == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(timeout[1]); err != nil || d <= 0 {
+			add(section+".timeouts."+timeout[0], "%s.timeouts.%s must be a duration such as 90s, 10m or 1h30m, got %q", section, timeout[0], timeout[1])
+		}
+	}
+	if build.Shell {
+		return
+	}
+	for _, command := range [][2]string{{"install_command", build.InstallCommand}, {"build_command", build.BuildCommand}, {"test_command", build.TestCommand}} {
+		if _, err := shell.Parse(command[1]); errors.Is(err, shell.ErrNeedsShell) {
+			add(section+"."+command[0], "%s.%s %v, set %s.shell to true to run it with /bin/sh", section, command[0], err, section)
+		} else if err != nil {
+			add(section+"."+command[0], "%s.%s cannot be parsed: %v", section, command[0], err)
+		}
+	}
 }
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"automateLife/provider"
+	"automateLife/utils"
+)
+
+// RepositoryConfig is one repository of a multi-repository workspace.
+// Authentication, provider and clone tuning come from the git section;
+// empty build fields inherit the values of the build section.
+type RepositoryConfig struct {
+	Name     string      `json:"name"`     // Defaults to the repository name from repo_url
+	RepoUrl  string      `json:"repo_url"` // Required
+	Branch   string      `json:"branch"`   // Empty uses the remote's default branch
+	Ref      string      `json:"ref"`      // Branch, tag or commit SHA, overrides branch
+	CloneDir string      `json:"clone_dir"`
+	Build    BuildConfig `json:"build"`
+}
+
+// RepoName returns the name the repository is reported under
+func (r *RepositoryConfig) RepoName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if parsed, err := provider.ParseURL(r.RepoUrl); err == nil {
+		return parsed.Name
+	}
+	return r.RepoUrl
+}
+
+// RepoGit returns the git settings for a workspace repository: the shared
+// git section with the repository's own URL, branch, ref and clone dir
+func (c *Config) RepoGit(r *RepositoryConfig) GitConfig {
+	git := c.Git
+	git.RepoUrl = r.RepoUrl
+	git.Branch = r.Branch
+	git.Ref = r.Ref
+	git.CloneDir = r.CloneDir
+	return git
+}
+
+// RepoBuild returns the build settings for a workspace repository
+func (c *Config) RepoBuild(r *RepositoryConfig) BuildConfig {
+	build := r.Build
+	inherit := func(value *string, shared string) {
+		if *value == "" {
+			*value = shared
+		}
+	}
+	inherit(&build.Language, c.Build.Language)
+	inherit(&build.InstallCommand, c.Build.InstallCommand)
+	inherit(&build.BuildCommand, c.Build.BuildCommand)
+	inherit(&build.TestCommand, c.Build.TestCommand)
+	inherit(&build.OutputDir, c.Build.OutputDir)
+	inherit(&build.Timeouts.Install, c.Build.Timeouts.Install)
+	inherit(&build.Timeouts.Build, c.Build.Timeouts.Build)
+	inherit(&build.Timeouts.Test, c.Build.Timeouts.Test)
+	inherit(&build.Timeouts.Pipeline, c.Build.Timeouts.Pipeline)
+	inherit(&build.Timeouts.GracePeriod, c.Build.Timeouts.GracePeriod)
+	build.Shell = build.Shell || c.Build.Shell
+	return build
+}
+
+func (r *RepositoryConfig) expandPaths() {
+	r.Name = utils.ExpandEnvVars(r.Name)
+	r.RepoUrl = utils.ExpandEnvVars(r.RepoUrl)
+	r.Branch = utils.ExpandEnvVars(r.Branch)
+	r.Ref = utils.ExpandEnvVars(r.Ref)
+	r.CloneDir = utils.ExpandEnvVars(r.CloneDir)
+	r.Build.Language = utils.ExpandEnvVars(r.Build.Language)
+	r.Build.OutputDir = utils.ExpandEnvVars(r.Build.OutputDir)
+}
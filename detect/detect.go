@@ -0,0 +1,269 @@
+package detect
+
+import (
+	"automateLife/config"
+	"automateLife/provider"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Result holds the settings inferred from an existing checkout
+type Result struct {
+	Provider       string
+	RepoUrl        string
+	Branch         string
+	Language       string
+	InstallCommand string
+	BuildCommand   string
+	TestCommand    string
+	DeploymentType string
+	Markers        []string // Files that drove the detection
+}
+
+// Detect inspects dir and infers git, build and deployment settings from it
+func Detect(dir string) (*Result, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot inspect %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	result := &Result{}
+	detectGit(dir, result)
+	detectLanguage(dir, result)
+	detectDeployment(dir, result)
+
+	return result, nil
+}
+
+// Apply copies every detected (non-empty) value into cfg
+func (r *Result) Apply(cfg *config.Config) {
+	if r.Provider != "" {
+		cfg.Git.Provider = r.Provider
+	}
+	if r.RepoUrl != "" {
+		cfg.Git.RepoUrl = r.RepoUrl
+	}
+	if r.Branch != "" {
+		cfg.Git.Branch = r.Branch
+	}
+	if r.Language != "" {
+		cfg.Build.Language = r.Language
+		cfg.Build.InstallCommand = r.InstallCommand
+		cfg.Build.BuildCommand = r.BuildCommand
+		cfg.Build.TestCommand = r.TestCommand
+	}
+	if r.DeploymentType != "" {
+		cfg.Azure.DeploymentType = r.DeploymentType
+	}
+}
+
+// ProviderFromURL guesses the git provider from the host part of a remote URL
+func ProviderFromURL(repoUrl string) string {
+	if p := provider.FromURL(repoUrl); p != nil {
+		return p.Name
+	}
+	return ""
+}
+
+func detectGit(dir string, result *Result) {
+	remote, err := gitOutput(dir, "remote", "get-url", "origin")
+	if err != nil || remote == "" {
+		return
+	}
+	result.RepoUrl = remote
+	result.Provider = ProviderFromURL(remote)
+
+	// Prefer the remote's default branch, fall back to the checked out one
+	if ref, err := gitOutput(dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil && ref != "" {
+		result.Branch = strings.TrimPrefix(ref, "origin/")
+	} else if branch, err := gitOutput(dir, "symbolic-ref", "--short", "HEAD"); err == nil && branch != "" {
+		result.Branch = branch
+	}
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// languageDetector recognises the projects of one language by their marker
+// files and fills in the commands that build them
+type languageDetector struct {
+	language string
+	detect   func(dir string, result *Result) bool
+}
+
+// languageDetectors are tried in order, the first one that matches wins
+var languageDetectors = []languageDetector{
+	{"go", detectGo},
+	{"nodejs", detectNode},
+	{"python", detectPython},
+	{"dotnet", detectDotnet},
+	{"rust", detectRust},
+	{"ruby", detectRuby},
+	{"java", detectJava},
+}
+
+// Languages lists the languages Detect recognises, for build.language
+func Languages() []string {
+	languages := make([]string, len(languageDetectors))
+	for i, d := range languageDetectors {
+		languages[i] = d.language
+	}
+	return languages
+}
+
+func detectLanguage(dir string, result *Result) {
+	for _, d := range languageDetectors {
+		if d.detect(dir, result) {
+			result.Language = d.language
+			return
+		}
+	}
+}
+
+func detectGo(dir string, result *Result) bool {
+	if !exists(dir, "go.mod") {
+		return false
+	}
+	result.Markers = append(result.Markers, "go.mod")
+	result.InstallCommand = "go mod download"
+	result.BuildCommand = "go build ./..."
+	result.TestCommand = "go test ./..."
+	return true
+}
+
+func detectNode(dir string, result *Result) bool {
+	if !exists(dir, "package.json") {
+		return false
+	}
+	result.Markers = append(result.Markers, "package.json")
+	result.InstallCommand = "npm install"
+	if exists(dir, "yarn.lock") {
+		result.InstallCommand = "yarn install"
+	} else if exists(dir, "pnpm-lock.yaml") {
+		result.InstallCommand = "pnpm install"
+	}
+	scripts := packageScripts(filepath.Join(dir, "package.json"))
+	if _, ok := scripts["build"]; ok {
+		result.BuildCommand = "npm run build"
+	}
+	result.TestCommand = "npm test"
+	return true
+}
+
+func detectPython(dir string, result *Result) bool {
+	if !exists(dir, "pyproject.toml") {
+		return false
+	}
+	result.Markers = append(result.Markers, "pyproject.toml")
+	if exists(dir, "poetry.lock") {
+		result.InstallCommand = "poetry install"
+		result.BuildCommand = "poetry build"
+		result.TestCommand = "poetry run pytest"
+	} else {
+		result.InstallCommand = "pip install -e ."
+		result.BuildCommand = "python -m build"
+		result.TestCommand = "pytest"
+	}
+	return true
+}
+
+func detectDotnet(dir string, result *Result) bool {
+	markers := append(glob(dir, "*.sln"), glob(dir, "*.csproj")...)
+	if len(markers) == 0 {
+		return false
+	}
+	result.Markers = append(result.Markers, markers...)
+	result.InstallCommand = "dotnet restore"
+	result.BuildCommand = "dotnet build --no-restore"
+	result.TestCommand = "dotnet test --no-build"
+	return true
+}
+
+func detectRust(dir string, result *Result) bool {
+	if !exists(dir, "Cargo.toml") {
+		return false
+	}
+	result.Markers = append(result.Markers, "Cargo.toml")
+	result.InstallCommand = "cargo fetch"
+	result.BuildCommand = "cargo build"
+	result.TestCommand = "cargo test"
+	return true
+}
+
+func detectRuby(dir string, result *Result) bool {
+	if !exists(dir, "Gemfile") {
+		return false
+	}
+	result.Markers = append(result.Markers, "Gemfile")
+	result.InstallCommand = "bundle install"
+	result.TestCommand = "bundle exec rspec"
+	if !exists(dir, "spec") && exists(dir, "Rakefile") {
+		result.TestCommand = "bundle exec rake test"
+	}
+	return true
+}
+
+func detectJava(dir string, result *Result) bool {
+	if !exists(dir, "pom.xml") {
+		return false
+	}
+	result.Markers = append(result.Markers, "pom.xml")
+	result.InstallCommand = "mvn dependency:resolve"
+	result.BuildCommand = "mvn package -DskipTests"
+	result.TestCommand = "mvn test"
+	return true
+}
+
+func detectDeployment(dir string, result *Result) {
+	// host.json is specific to Azure Functions, so check it before Dockerfile
+	switch {
+	case exists(dir, "host.json"):
+		result.Markers = append(result.Markers, "host.json")
+		result.DeploymentType = "function"
+	case exists(dir, "Dockerfile"):
+		result.Markers = append(result.Markers, "Dockerfile")
+		result.DeploymentType = "container"
+	}
+}
+
+// packageScripts returns the "scripts" section of a package.json file
+func packageScripts(path string) map[string]string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil
+	}
+	return pkg.Scripts
+}
+
+func exists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+func glob(dir, pattern string) []string {
+	matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+	names := make([]string, 0, len(matches))
+	for _, match := range matches {
+		names = append(names, filepath.Base(match))
+	}
+	return names
+}
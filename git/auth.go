@@ -2,19 +2,21 @@ package git
 
 import (
 	"automateLife/config"
-	"automateLife/utils"
+	"automateLife/provider"
 	"encoding/base64"
 	"fmt"
-	"os"
 	"strings"
 )
 
+// BuildAuthURL returns the URL to clone with. When git.provider is set, the
+// URL is checked against the provider and converted between its HTTPS and
+// SSH forms to match the auth type.
 func BuildAuthURL(config *config.GitConfig) (string, error) {
 	switch config.AuthType {
 	case "token", "basic":
 		return buildBasicTokenURL(config)
 	case "ssh":
-		return config.RepoUrl, nil
+		return buildSSHURL(config)
 	default:
 		return "", fmt.Errorf("unsupported auth type: %s . Use 'token', 'basic' or 'ssh'", config.AuthType)
 	}
@@ -24,18 +26,46 @@ func buildBasicTokenURL(config *config.GitConfig) (string, error) {
 	if len(config.RepoUrl) == 0 {
 		return "", fmt.Errorf("repo_url is empty")
 	}
-	if !strings.HasPrefix(config.RepoUrl, "http://") && !strings.HasPrefix(config.RepoUrl, "https://") {
+	isHTTP := strings.HasPrefix(config.RepoUrl, "http://") || strings.HasPrefix(config.RepoUrl, "https://")
+
+	repo, err := providerRepo(config)
+	if err != nil {
+		return "", err
+	}
+	if repo != nil && !isHTTP {
+		return repo.HTTPS(), nil
+	}
+	if !isHTTP {
 		return "", fmt.Errorf("repo_url must start with http:// or https:// for token/basic auth")
 	}
 	return config.RepoUrl, nil
 }
 
+func buildSSHURL(config *config.GitConfig) (string, error) {
+	repo, err := providerRepo(config)
+	if err != nil {
+		return "", err
+	}
+	if repo != nil && (strings.HasPrefix(config.RepoUrl, "http://") || strings.HasPrefix(config.RepoUrl, "https://")) {
+		return repo.SSH(), nil
+	}
+	return config.RepoUrl, nil
+}
+
+// providerRepo parses repo_url for the configured provider, or returns nil
+// when no provider is set
+func providerRepo(config *config.GitConfig) (*provider.Repo, error) {
+	p, err := provider.Lookup(config.Provider)
+	if err != nil || p == nil || config.RepoUrl == "" {
+		return nil, err
+	}
+	return p.Parse(config.RepoUrl)
+}
+
 func GetAuthHeader(config *config.GitConfig) (string, error) {
 	switch config.AuthType {
-	case "token":
-		return buildTokenAuthHeader(config)
-	case "basic":
-		return buildBasicAuthHeader(config)
+	case "token", "basic":
+		return buildAuthHeader(config)
 	case "ssh":
 		// SSH doesn't use HTTP headers
 		return "", nil
@@ -44,52 +74,63 @@ func GetAuthHeader(config *config.GitConfig) (string, error) {
 	}
 }
 
-func buildTokenAuthHeader(config *config.GitConfig) (string, error) {
-	if config.Token == "" {
-		return "", fmt.Errorf("token is required when auth_type is 'token'")
+// Credentials returns the HTTP username and password for token or basic auth
+func Credentials(config *config.GitConfig) (string, string, error) {
+	switch config.AuthType {
+	case "token":
+		if config.Token == "" {
+			return "", "", fmt.Errorf("token is required when auth_type is 'token'")
+		}
+		p, err := provider.Lookup(config.Provider)
+		if err != nil {
+			return "", "", err
+		}
+		if p == nil {
+			// Without a provider the token is sent as the username, which most servers accept
+			return config.Token, "", nil
+		}
+		return p.TokenUser, config.Token, nil
+	case "basic":
+		if config.UserName == "" || config.Password == "" {
+			return "", "", fmt.Errorf("username and password must not be empty when auth_type is 'basic'")
+		}
+		return config.UserName, config.Password, nil
+	default:
+		return "", "", fmt.Errorf("auth type %s has no HTTP credentials", config.AuthType)
 	}
-
-	credentials := fmt.Sprintf("%s:", config.Token)
-	encodedAuth := base64.StdEncoding.EncodeToString([]byte(credentials))
-
-	return fmt.Sprintf("http.extraheader=AUTHORIZATION: Basic %s", encodedAuth), nil
 }
 
-func buildBasicAuthHeader(config *config.GitConfig) (string, error) {
-	if config.UserName == "" || config.Password == "" {
-		return "", fmt.Errorf("username and password must not be empty when auth_type is 'basic'")
+// CredentialsFor returns the credentials for a git credential request, as
+// long as it is for the configured repository's protocol and host
+func CredentialsFor(config *config.GitConfig, protocol string, host string) (string, string, bool, error) {
+	if config.AuthType != "token" && config.AuthType != "basic" {
+		return "", "", false, nil
 	}
-
-	credentials := fmt.Sprintf("%s:%s", config.UserName, config.Password)
-	encodedAuth := base64.StdEncoding.EncodeToString([]byte(credentials))
-
-	return fmt.Sprintf("http.extraheader=AUTHORIZATION: Basic %s", encodedAuth), nil
-}
-
-func SetupSSH(keyPath string) error {
-	if keyPath == "" {
-		return fmt.Errorf("ssh_key_path must be provided when auth_type is 'ssh'")
+	repoUrl, err := BuildAuthURL(config)
+	if err != nil {
+		return "", "", false, err
 	}
-
-	// Expand environment variables and tilde in the key path
-	expandedPath := utils.ExpandEnvVars(keyPath)
-
-	if _, err := os.Stat(expandedPath); os.IsNotExist(err) {
-		return fmt.Errorf("SSH key not found at %s (expanded from: %s)", expandedPath, keyPath)
+	repo, err := ParseRepoURL(repoUrl)
+	if err != nil {
+		return "", "", false, err
+	}
+	if protocol != repo.Scheme || !strings.EqualFold(host, repo.Host) {
+		return "", "", false, nil
 	}
 
-	sshCommand := fmt.Sprintf("ssh -i %s -o StrictHostKeyChecking=no", expandedPath)
-	os.Setenv("GIT_SSH_COMMAND", sshCommand)
-
-	return nil
+	username, password, err := Credentials(config)
+	if err != nil {
+		return "", "", false, err
+	}
+	return username, password, true, nil
 }
 
-func GetProjectDirName(repoUrl string) string {
-
-	repoUrl = strings.TrimSuffix(repoUrl, ".git")
-	parts := strings.Split(repoUrl, "/")
-	if len(parts) > 0 {
-		return parts[len(parts)-1]
+func buildAuthHeader(config *config.GitConfig) (string, error) {
+	username, password, err := Credentials(config)
+	if err != nil {
+		return "", err
 	}
-	return ""
+	encodedAuth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+
+	return fmt.Sprintf("http.extraheader=AUTHORIZATION: Basic %s", encodedAuth), nil
 }
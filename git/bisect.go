@@ -0,0 +1,31 @@
+package git
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrBisectInconclusive is returned when skipped commits leave several
+// candidates for the first bad commit
+var ErrBisectInconclusive = errors.New("bisect could not narrow it down to one commit")
+
+var (
+	firstBadPattern = regexp.MustCompile(`(?m)^([0-9a-f]{40}) is the first bad commit`)
+	shaPattern      = regexp.MustCompile(`\b[0-9a-f]{40}\b`)
+)
+
+// lastLines returns the last n non-empty lines of output, where git bisect
+// run explains why it stopped
+func lastLines(output string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
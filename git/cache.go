@@ -0,0 +1,196 @@
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache keeps one bare mirror per remote, shared by every config on the
+// machine, so repeated clones only download objects that are new
+type Cache struct {
+	Dir string
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// CacheEntry describes one mirror in the cache
+type CacheEntry struct {
+	Path     string
+	URL      string // Remote the mirror follows, empty when it can't be read
+	Size     int64  // Bytes on disk
+	LastUsed time.Time
+}
+
+// DefaultCacheDir returns $XDG_CACHE_HOME/automatelife/repos, or the
+// platform's cache directory when XDG_CACHE_HOME is not set
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "automatelife", "repos"), nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not locate the cache directory: %w", err)
+	}
+	return filepath.Join(dir, "automatelife", "repos"), nil
+}
+
+// NewCache returns the cache stored in dir
+func NewCache(dir string) *Cache {
+	return &Cache{Dir: dir, locks: map[string]*sync.Mutex{}}
+}
+
+// MirrorPath returns where the mirror of url is kept. HTTPS and SSH URLs of
+// the same repository share a mirror.
+func (c *Cache) MirrorPath(url string) string {
+	key := url
+	name := "repo"
+	if parsed, err := ParseRepoURL(url); err == nil {
+		host, _ := splitHostPort(parsed.Host)
+		key = strings.ToLower(host + "/" + parsed.Path)
+		if parsed.Name != "" {
+			name = parsed.Name
+		}
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, fmt.Sprintf("%s-%s.git", name, hex.EncodeToString(sum[:6])))
+}
+
+// ReferenceFor prepares the mirror of url for a clone into dir and returns
+// its path. Existing clones don't need it, so nothing is fetched for them
+// and an empty path is returned.
+func (c *Cache) ReferenceFor(client Client, url string, dir string) (string, error) {
+	if !isEmptyOrMissing(dir) {
+		return "", nil
+	}
+	return c.Update(client, url)
+}
+
+// Update creates the mirror of url, or fetches into it when it exists, and
+// returns its path
+func (c *Cache) Update(client Client, url string) (string, error) {
+	path := c.MirrorPath(url)
+	lock := c.lock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := os.Stat(path); err == nil {
+		// Fetch from url rather than the mirror's origin, which may use the other protocol
+		if err := client.Fetch(path, FetchOptions{Remote: url, Refspecs: []string{"+refs/*:refs/*"}, Prune: true}); err != nil {
+			return "", fmt.Errorf("failed to update the cached mirror of %s: %w", url, err)
+		}
+	} else {
+		if err := os.MkdirAll(c.Dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create the cache directory: %w", err)
+		}
+		// Clone next to the final path so an interrupted clone never looks like a mirror
+		tmp := fmt.Sprintf("%s.tmp-%d", path, os.Getpid())
+		os.RemoveAll(tmp)
+		if err := client.Clone(CloneOptions{URL: url, Dir: tmp, Mirror: true}); err != nil {
+			os.RemoveAll(tmp)
+			return "", fmt.Errorf("failed to mirror %s into the cache: %w", url, err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			os.RemoveAll(tmp)
+			return "", fmt.Errorf("failed to add the mirror of %s to the cache: %w", url, err)
+		}
+	}
+
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return path, nil
+}
+
+func (c *Cache) lock(path string) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.locks == nil {
+		c.locks = map[string]*sync.Mutex{}
+	}
+	if c.locks[path] == nil {
+		c.locks[path] = &sync.Mutex{}
+	}
+	return c.locks[path]
+}
+
+// Entries lists the mirrors in the cache, least recently used first
+func (c *Cache) Entries(client Client) ([]CacheEntry, error) {
+	dirs, err := os.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the cache directory: %w", err)
+	}
+
+	var entries []CacheEntry
+	for _, dir := range dirs {
+		if !dir.IsDir() || !strings.HasSuffix(dir.Name(), ".git") {
+			continue
+		}
+		info, err := dir.Info()
+		if err != nil {
+			continue
+		}
+		entry := CacheEntry{Path: filepath.Join(c.Dir, dir.Name()), LastUsed: info.ModTime()}
+		entry.URL, _ = client.RemoteURL(entry.Path, "origin")
+		entry.Size = dirSize(entry.Path)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// Prune removes mirrors not used within maxAge, then the least recently
+// used ones until the cache fits in maxSize bytes. A zero limit is not
+// applied. It returns the removed mirrors.
+func (c *Cache) Prune(client Client, maxAge time.Duration, maxSize int64) ([]CacheEntry, error) {
+	entries, err := c.Entries(client)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	var removed []CacheEntry
+	for _, entry := range entries {
+		expired := maxAge > 0 && time.Since(entry.LastUsed) > maxAge
+		oversized := maxSize > 0 && total > maxSize
+		if !expired && !oversized {
+			continue
+		}
+		if err := os.RemoveAll(entry.Path); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+		}
+		total -= entry.Size
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
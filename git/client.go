@@ -0,0 +1,119 @@
+package git
+
+import (
+	"automateLife/config"
+	"time"
+)
+
+// Client runs git operations. ExecClient shells out to the git binary,
+// FakeClient keeps everything in memory for tests.
+type Client interface {
+	Clone(opts CloneOptions) error
+	Fetch(dir string, opts FetchOptions) error
+	Checkout(dir string, ref string) error
+	RevParse(dir string, rev string) (string, error)
+	LsRemote(url string, patterns ...string) ([]RemoteRef, error)
+	Log(dir string, rev string, limit int) ([]Commit, error)
+	Worktree(dir string, path string, ref string) error
+	RemoveWorktree(dir string, path string) error
+	RemoteURL(dir string, remote string) (string, error)
+	CurrentBranch(dir string) (string, error)
+	IsDirty(dir string) (bool, error)
+	GitPath(dir string, name string) (string, error)
+	ChangedFiles(dir string, base string, rev string) ([]string, error)
+	FastForward(dir string, ref string) error
+	IsAncestor(dir string, ancestor string, rev string) (bool, error)
+	ResetHard(dir string, ref string) error
+	Merge(dir string, ref string, message string) error
+	VerifySignature(dir string, rev string, keys SigningKeys) (*Signature, error)
+	Bisect(dir string, good string, bad string, command []string) (string, error)
+	UpdateSubmodules(dir string, depth int) error
+	LFSPull(dir string) error
+	SparseCheckout(dir string, paths []string) error
+}
+
+// Tuning holds the optional clone settings for large repositories
+type Tuning struct {
+	Depth             int
+	SingleBranch      bool
+	SparsePaths       []string
+	RecurseSubmodules bool
+	LFS               string // "", "pull" or "skip"
+}
+
+// TuningFromConfig copies the clone tuning options out of the git settings
+func TuningFromConfig(cfg *config.GitConfig) Tuning {
+	return Tuning{
+		Depth:             cfg.Depth,
+		SingleBranch:      cfg.SingleBranch,
+		SparsePaths:       cfg.SparsePaths,
+		RecurseSubmodules: cfg.RecurseSubmodules,
+		LFS:               cfg.LFS,
+	}
+}
+
+// CloneOptions controls how a repository is cloned
+type CloneOptions struct {
+	URL    string
+	Dir    string // Destination directory, git picks one from the URL when empty
+	Branch string // Branch to check out, the remote default when empty
+	Mirror bool   // Bare mirror of every ref, as kept by the clone cache
+	// Local repository to copy existing objects from; the clone doesn't depend on it afterwards
+	Reference string
+	Tuning
+}
+
+// FetchOptions controls what is fetched into an existing clone
+type FetchOptions struct {
+	Remote   string   // Defaults to "origin"
+	Refspecs []string // Defaults to the remote's configured refspecs
+	Prune    bool
+	Depth    int // Keeps a shallow clone this many commits deep, 0 fetches the new history in full
+}
+
+// RemoteRef is a single line of `git ls-remote` output
+type RemoteRef struct {
+	Name   string // e.g. refs/heads/main or HEAD
+	SHA    string
+	Target string // For symbolic refs such as HEAD, the ref they point to
+}
+
+// Commit describes a single commit
+type Commit struct {
+	SHA     string
+	Author  string
+	Email   string
+	Date    time.Time
+	Subject string
+}
+
+// ShortSHA returns the abbreviated commit hash
+func (c Commit) ShortSHA() string {
+	if len(c.SHA) > 7 {
+		return c.SHA[:7]
+	}
+	return c.SHA
+}
+
+// Env returns the AUTOMATELIFE_COMMIT_* variables that describe the commit
+// to build and test steps. ref is the branch, tag or SHA that was asked for.
+func (c Commit) Env(ref string) map[string]string {
+	date := ""
+	if !c.Date.IsZero() {
+		date = c.Date.Format(time.RFC3339)
+	}
+	return map[string]string{
+		"AUTOMATELIFE_COMMIT_SHA":       c.SHA,
+		"AUTOMATELIFE_COMMIT_SHORT_SHA": c.ShortSHA(),
+		"AUTOMATELIFE_COMMIT_REF":       ref,
+		"AUTOMATELIFE_COMMIT_AUTHOR":    c.Author,
+		"AUTOMATELIFE_COMMIT_EMAIL":     c.Email,
+		"AUTOMATELIFE_COMMIT_DATE":      date,
+		"AUTOMATELIFE_COMMIT_SUBJECT":   c.Subject,
+	}
+}
+
+var (
+	_ Client = (*ExecClient)(nil)
+	_ Client = (*FakeClient)(nil)
+)
@@ -0,0 +1,188 @@
+package git
+
+import (
+	"automateLife/config"
+	"automateLife/provider"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Categories of git failures, matched with errors.Is against the errors
+// returned by ExecClient
+var (
+	ErrAuthFailed      = errors.New("authentication failed")
+	ErrRepoNotFound    = errors.New("repository not found")
+	ErrBranchNotFound  = errors.New("branch or ref not found")
+	ErrHostUnreachable = errors.New("git host unreachable")
+	ErrTLS             = errors.New("TLS certificate verification failed")
+	ErrHostKey         = errors.New("SSH host key verification failed")
+	ErrDiskFull        = errors.New("no space left on device")
+)
+
+// Substrings of git, ssh and curl messages for each category, checked in
+// order. Authentication comes first since ssh reports a denied key as
+// "Could not read from remote repository" as well.
+var failurePatterns = []struct {
+	err      error
+	patterns []string
+}{
+	{ErrHostKey, []string{"host key verification failed", "remote host identification has changed", "host key is known for", "no matching host key"}},
+	{ErrAuthFailed, []string{"authentication failed", "could not read username", "could not read password", "permission denied (publickey", "http basic: access denied", "invalid username or password", "returned error: 401", "returned error: 403", "terminal prompts disabled", "incorrect passphrase"}},
+	{ErrRepoNotFound, []string{"repository not found", "repository '", "does not appear to be a git repository", "returned error: 404", "tf401019", "project not found", "not found: repository"}},
+	{ErrBranchNotFound, []string{"not found in upstream", "couldn't find remote ref", "did not match any file(s) known to git", "unknown revision", "not our ref"}},
+	{ErrTLS, []string{"ssl certificate problem", "server certificate verification failed", "certificate verify failed", "unable to get local issuer certificate", "gnutls_handshake() failed", "ssl_error"}},
+	{ErrDiskFull, []string{"no space left on device", "disk quota exceeded"}},
+	{ErrHostUnreachable, []string{"could not resolve host", "could not resolve hostname", "connection refused", "connection timed out", "operation timed out", "network is unreachable", "no route to host", "failed to connect to", "connection reset", "early eof", "rpc failed", "the remote end hung up unexpectedly", "unexpected disconnect", "returned error: 502", "returned error: 503", "returned error: 504"}},
+}
+
+// Failures worth retrying: the network or server hiccupped, nothing the
+// user needs to fix. DNS failures are left out, they are usually typos.
+var transientPatterns = []string{
+	"connection timed out", "operation timed out", "connection reset", "early eof", "rpc failed",
+	"the remote end hung up unexpectedly", "unexpected disconnect",
+	"returned error: 502", "returned error: 503", "returned error: 504",
+}
+
+// classify returns the failure category of git's stderr, or nil when it
+// matches none
+func classify(stderr string) error {
+	lower := strings.ToLower(stderr)
+	for _, category := range failurePatterns {
+		for _, pattern := range category.patterns {
+			if strings.Contains(lower, pattern) {
+				return category.err
+			}
+		}
+	}
+	return nil
+}
+
+// Is lets errors.Is match a CommandError against the failure categories
+func (e *CommandError) Is(target error) bool {
+	category := classify(e.Stderr)
+	return category != nil && category == target
+}
+
+// IsTransient reports whether err is a network failure that may succeed on
+// retry. A dropped connection that comes with a rejected login, a missing
+// repository or a missing branch is not: retrying only repeats the failure.
+func IsTransient(err error) bool {
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	switch classify(cmdErr.Stderr) {
+	case ErrAuthFailed, ErrRepoNotFound, ErrBranchNotFound, ErrHostKey:
+		return false
+	}
+	lower := strings.ToLower(cmdErr.Stderr)
+	for _, pattern := range transientPatterns {
+		if strings.Contains(lower, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remediation returns troubleshooting steps for err, tailored to the
+// configured provider and auth type. It returns nil for unknown failures.
+func Remediation(err error, cfg *config.GitConfig) []string {
+	host := ""
+	if repoUrl, urlErr := BuildAuthURL(cfg); urlErr == nil {
+		if parsed, parseErr := ParseRepoURL(repoUrl); parseErr == nil {
+			host = parsed.Host
+		}
+	}
+	p, _ := provider.Lookup(cfg.Provider)
+
+	switch {
+	case errors.Is(err, ErrHostKey):
+		tips := []string{
+			fmt.Sprintf("Compare the host key fingerprint of %s with the one your provider publishes", hostOrDefault(host)),
+			"Pin the published fingerprint in git.ssh_host_fingerprints",
+		}
+		return append(tips, fmt.Sprintf("If the key changed legitimately, remove the stale entry with: ssh-keygen -R %s", host))
+	case errors.Is(err, ErrAuthFailed):
+		return authTips(cfg, p, host)
+	case errors.Is(err, ErrRepoNotFound):
+		tips := []string{fmt.Sprintf("Check that repo_url (%s) is spelled correctly", cfg.RepoUrl)}
+		tips = append(tips, "Private repositories are reported as not found when the credentials lack access to them:")
+		return append(tips, authTips(cfg, p, host)...)
+	case errors.Is(err, ErrBranchNotFound):
+		ref := cfg.Ref
+		if ref == "" {
+			ref = cfg.Branch
+		}
+		return []string{
+			fmt.Sprintf("%q does not exist on the remote, check git.branch and git.ref", ref),
+			fmt.Sprintf("List the remote branches with: git ls-remote --heads %s", cfg.RepoUrl),
+		}
+	case errors.Is(err, ErrTLS):
+		return []string{
+			fmt.Sprintf("The certificate presented by %s could not be verified", hostOrDefault(host)),
+			"Behind a corporate proxy, point GIT_SSL_CAINFO at your organisation's CA bundle",
+			"Check that the system clock is correct",
+		}
+	case errors.Is(err, ErrDiskFull):
+		return []string{
+			"Free up disk space, or set git.clone_dir to a location with more room",
+			"Reduce the checkout with git.depth or git.sparse_paths",
+		}
+	case errors.Is(err, ErrHostUnreachable):
+		tips := []string{
+			fmt.Sprintf("Check your network connection and that %s resolves", hostOrDefault(host)),
+			"Behind a proxy, set HTTPS_PROXY (or http.proxy in your git config)",
+		}
+		if cfg.AuthType == "ssh" {
+			tips = append(tips, "Check that outgoing SSH (port 22) is not blocked by a firewall")
+		}
+		return tips
+	}
+	return nil
+}
+
+func authTips(cfg *config.GitConfig, p *provider.Provider, host string) []string {
+	switch cfg.AuthType {
+	case "ssh":
+		tips := []string{fmt.Sprintf("Check that your public key is added to your account on %s", hostOrDefault(host))}
+		if cfg.SSHAgent && cfg.SSHKeyPath == "" {
+			tips = append(tips, "List the keys loaded in ssh-agent with: ssh-add -l")
+		} else {
+			tips = append(tips, fmt.Sprintf("Check that git.ssh_key_path (%s) is the matching private key", cfg.SSHKeyPath))
+		}
+		if host != "" {
+			tips = append(tips, fmt.Sprintf("Test the connection with: ssh -T git@%s", host))
+		}
+		return tips
+	case "basic":
+		return []string{
+			"Check git.username and git.password",
+			"Many providers no longer accept account passwords over HTTPS, use auth_type 'token' with an access token instead",
+		}
+	}
+
+	scope := "read access to the repository"
+	switch p {
+	case provider.GitHub:
+		scope = "the 'repo' scope (classic tokens) or Contents: Read access (fine-grained tokens)"
+	case provider.GitLab:
+		scope = "the read_repository scope"
+	case provider.Bitbucket:
+		scope = "Repositories: Read permission"
+	case provider.AzureDevOps:
+		scope = "the Code (Read) scope in the repository's organisation"
+	}
+	return []string{
+		fmt.Sprintf("Verify your token has %s", scope),
+		"Check if the token has expired or was revoked",
+		"If git.provider is empty, set it so the token is sent with the username your provider expects",
+	}
+}
+
+func hostOrDefault(host string) string {
+	if host == "" {
+		return "the git host"
+	}
+	return host
+}
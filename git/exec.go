@@ -0,0 +1,667 @@
+package git
+
+import (
+	"automateLife/config"
+	"automateLife/redact"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExecClient implements Client by running the git binary. It owns the
+// authentication setup so callers never deal with headers or SSH commands.
+type ExecClient struct {
+	Stdout io.Writer // Progress output of remote operations, discarded when nil
+	Stderr io.Writer
+
+	// Remote operations that fail with a transient network error are retried
+	// up to Retries times, waiting RetryDelay, then twice as long each time
+	Retries    int
+	RetryDelay time.Duration
+
+	authConfig [][2]string // git config entries with credentials, passed through the environment
+	env        []string    // Extra environment for every git process
+	remoteEnv  []string    // Extra environment for git processes that talk to a remote
+
+	// SSH host verification, done once before the first remote operation
+	sshHost          string
+	sshPort          string
+	hostFingerprints []string
+	trustOnce        sync.Once
+	trustErr         error
+}
+
+// NewExecClient creates a client authenticated with the given git settings.
+// A nil config creates an unauthenticated client.
+func NewExecClient(cfg *config.GitConfig) (*ExecClient, error) {
+	client := &ExecClient{
+		Retries:    3,
+		RetryDelay: 2 * time.Second,
+		env: []string{
+			"GIT_TERMINAL_PROMPT=0",
+			"GCM_INTERACTIVE=never",
+			"GIT_ASKPASS=echo",
+		},
+	}
+	if cfg == nil {
+		return client, nil
+	}
+	if cfg.LFS == "skip" {
+		// Every checkout leaves LFS files as pointers, not only the first clone
+		client.env = append(client.env, "GIT_LFS_SKIP_SMUDGE=1")
+	}
+
+	switch cfg.AuthType {
+	case "ssh":
+		env, remoteEnv, err := sshEnv(cfg)
+		if err != nil {
+			return nil, err
+		}
+		client.env = append(client.env, env...)
+		client.remoteEnv = remoteEnv
+
+		repoUrl, err := BuildAuthURL(cfg)
+		if err != nil {
+			repoUrl = cfg.RepoUrl
+		}
+		if parsed, err := ParseRepoURL(repoUrl); err == nil && (parsed.Scheme == "ssh" || parsed.Scheme == "scp") {
+			client.sshHost, client.sshPort = splitHostPort(parsed.Host)
+			client.hostFingerprints = cfg.SSHHostFingerprints
+		}
+	default:
+		authHeader, err := GetAuthHeader(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if authHeader != "" {
+			repoUrl, err := BuildAuthURL(cfg)
+			if err != nil {
+				repoUrl = cfg.RepoUrl
+			}
+			key, value, _ := strings.Cut(scopeToHost(authHeader, repoUrl), "=")
+			client.authConfig = [][2]string{{key, value}}
+			redact.Add(value, strings.TrimPrefix(value, "AUTHORIZATION: Basic "))
+		}
+	}
+
+	return client, nil
+}
+
+func (c *ExecClient) Clone(opts CloneOptions) error {
+	args := []string{"clone"}
+	if opts.Mirror {
+		args = append(args, "--mirror")
+	}
+	if opts.Branch != "" {
+		args = append(args, "-b", opts.Branch)
+	}
+	if opts.Reference != "" {
+		args = append(args, "--reference-if-able", opts.Reference, "--dissociate")
+	}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	if len(opts.SparsePaths) > 0 {
+		// Only top-level files are checked out until the cone is set below
+		args = append(args, "--sparse")
+	}
+	if opts.RecurseSubmodules {
+		args = append(args, "--recurse-submodules")
+		if opts.Depth > 0 {
+			args = append(args, "--shallow-submodules")
+		}
+	}
+	args = append(args, "--", opts.URL)
+	if opts.Dir != "" {
+		args = append(args, opts.Dir)
+	}
+
+	var env []string
+	if opts.LFS == "skip" {
+		env = append(env, "GIT_LFS_SKIP_SMUDGE=1")
+	}
+	if opts.LFS == "pull" {
+		if err := c.requireLFS(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.runRemote("", env, args...); err != nil {
+		return err
+	}
+
+	dir := opts.Dir
+	if dir == "" {
+		dir = GetProjectDirName(opts.URL)
+	}
+	if len(opts.SparsePaths) > 0 {
+		if err := c.SparseCheckout(dir, opts.SparsePaths); err != nil {
+			return err
+		}
+	}
+	if opts.LFS == "pull" {
+		return c.LFSPull(dir)
+	}
+	return nil
+}
+
+func (c *ExecClient) Fetch(dir string, opts FetchOptions) error {
+	remote := opts.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	args := []string{"fetch"}
+	if opts.Prune {
+		args = append(args, "--prune")
+	}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	args = append(args, remote)
+	args = append(args, opts.Refspecs...)
+	return c.runRemote(dir, nil, args...)
+}
+
+func (c *ExecClient) Checkout(dir string, ref string) error {
+	// Single-branch clones don't guess remote branches, so create the local branch explicitly
+	if _, err := c.output(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+ref); err != nil {
+		if _, err := c.output(dir, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+ref); err == nil {
+			_, err := c.output(dir, "checkout", "--quiet", "--no-track", "-b", ref, "origin/"+ref)
+			return err
+		}
+	}
+	_, err := c.output(dir, "checkout", "--quiet", ref)
+	return err
+}
+
+func (c *ExecClient) RevParse(dir string, rev string) (string, error) {
+	return c.output(dir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+}
+
+func (c *ExecClient) LsRemote(url string, patterns ...string) ([]RemoteRef, error) {
+	args := append([]string{"ls-remote", "--symref", url}, patterns...)
+	out, err := c.run("", true, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseLsRemote(out), nil
+}
+
+func (c *ExecClient) Log(dir string, rev string, limit int) ([]Commit, error) {
+	args := []string{"log", "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%s%x1e"}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("-n%d", limit))
+	}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	out, err := c.output(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseLog(out), nil
+}
+
+func (c *ExecClient) Worktree(dir string, path string, ref string) error {
+	// Forget worktrees whose directory was deleted, so path can be used again
+	if _, err := c.output(dir, "worktree", "prune"); err != nil {
+		return err
+	}
+	_, err := c.output(dir, "worktree", "add", "--detach", path, ref)
+	return err
+}
+
+func (c *ExecClient) RemoveWorktree(dir string, path string) error {
+	_, err := c.output(dir, "worktree", "remove", "--force", path)
+	return err
+}
+
+// GitPath resolves a path inside the repository's git directory such as
+// "hooks", honouring settings that move it like core.hooksPath
+func (c *ExecClient) GitPath(dir string, name string) (string, error) {
+	path, err := c.output(dir, "rev-parse", "--git-path", name)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
+
+// ChangedFiles lists the files rev changes since it forked from base. With
+// an empty rev it lists the changes staged on top of base instead.
+func (c *ExecClient) ChangedFiles(dir string, base string, rev string) ([]string, error) {
+	args := []string{"diff", "--name-only", "-z", "--no-renames"}
+	if rev == "" {
+		args = append(args, "--cached", base)
+	} else {
+		args = append(args, base+"..."+rev)
+	}
+	out, err := c.output(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(out, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func (c *ExecClient) RemoteURL(dir string, remote string) (string, error) {
+	return c.output(dir, "remote", "get-url", remote)
+}
+
+// CurrentBranch returns the checked out branch, or an empty string when HEAD is detached
+func (c *ExecClient) CurrentBranch(dir string) (string, error) {
+	if _, err := c.output(dir, "rev-parse", "--git-dir"); err != nil {
+		return "", err
+	}
+	branch, err := c.output(dir, "symbolic-ref", "--short", "--quiet", "HEAD")
+	if err != nil {
+		return "", nil
+	}
+	return branch, nil
+}
+
+// IsDirty reports uncommitted changes to tracked files
+func (c *ExecClient) IsDirty(dir string) (bool, error) {
+	out, err := c.output(dir, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+func (c *ExecClient) FastForward(dir string, ref string) error {
+	_, err := c.output(dir, "merge", "--ff-only", "--quiet", ref)
+	return err
+}
+
+// IsAncestor reports whether ancestor is in the history of rev
+func (c *ExecClient) IsAncestor(dir string, ancestor string, rev string) (bool, error) {
+	_, err := c.output(dir, "merge-base", "--is-ancestor", ancestor, rev)
+	if err == nil {
+		return true, nil
+	}
+	// merge-base exits 1 when it is not
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, err
+}
+
+func (c *ExecClient) ResetHard(dir string, ref string) error {
+	_, err := c.output(dir, "reset", "--hard", "--quiet", ref)
+	return err
+}
+
+// Merge creates a merge commit of ref on top of HEAD. The commit never
+// leaves the clone, so it is made under a fixed identity instead of
+// requiring one in the user's git config. Conflicts abort the merge.
+func (c *ExecClient) Merge(dir string, ref string, message string) error {
+	_, err := c.output(dir, "-c", "user.name=AutomateLife", "-c", "user.email=automatelife@localhost",
+		"merge", "--no-ff", "--no-edit", "--quiet", "-m", message, ref)
+	if err == nil {
+		return nil
+	}
+	conflicts, _ := c.output(dir, "diff", "--name-only", "--diff-filter=U")
+	c.output(dir, "merge", "--abort")
+	if conflicts != "" {
+		return fmt.Errorf("%w in %s", ErrMergeConflict, strings.Join(strings.Fields(conflicts), ", "))
+	}
+	return err
+}
+
+// VerifySignature checks the signature of the commit or annotated tag rev
+// against keys only, ignoring any signers and keyrings in the user's config.
+// A missing or untrusted signature is reported in the result, not as an error.
+func (c *ExecClient) VerifySignature(dir string, rev string, keys SigningKeys) (*Signature, error) {
+	object, err := c.output(dir, "cat-file", "-t", rev)
+	if err != nil {
+		return nil, err
+	}
+	verb := "verify-commit"
+	switch object {
+	case "commit":
+	case "tag":
+		verb = "verify-tag"
+	default:
+		return nil, fmt.Errorf("%s is a %s, not a commit or tag", rev, object)
+	}
+
+	home, cleanup, err := gpgHome(keys.GPGKeyring)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	cmd := c.command(dir, false, "-c", "gpg.ssh.allowedSignersFile="+keys.AllowedSigners, verb, "--raw", rev)
+	cmd.Env = append(cmd.Env, "GNUPGHOME="+home)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	runErr := cmd.Run()
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return nil, fmt.Errorf("git %s failed: %w", verb, runErr)
+	}
+
+	sig := parseSignature(out.String())
+	sig.Object = object
+	sig.Verified = runErr == nil && sig.Signed
+	return sig, nil
+}
+
+// Bisect runs git bisect run between good and bad and returns the first bad
+// commit. command decides each commit by its exit code: 0 good, 125 skip,
+// anything else below 128 bad. Its output goes to Stdout and Stderr. The
+// original checkout is restored afterwards.
+func (c *ExecClient) Bisect(dir string, good string, bad string, command []string) (string, error) {
+	defer c.output(dir, "bisect", "reset")
+	if _, err := c.output(dir, "bisect", "start", bad, good); err != nil {
+		return "", err
+	}
+
+	args := append([]string{"bisect", "run"}, command...)
+	cmd := c.command(dir, false, args...)
+	// The command is usually automateLife itself, which mustn't start in askpass mode
+	cmd.Env = withoutVars(cmd.Env, askpassVar, passphraseVar)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if c.Stdout != nil {
+		stdout := redact.NewWriter(c.Stdout)
+		defer stdout.Flush()
+		cmd.Stdout = io.MultiWriter(stdout, &out)
+	}
+	if c.Stderr != nil {
+		stderr := redact.NewWriter(c.Stderr)
+		defer stderr.Flush()
+		cmd.Stderr = io.MultiWriter(stderr, &out)
+	}
+	runErr := cmd.Run()
+
+	if match := firstBadPattern.FindStringSubmatch(out.String()); match != nil {
+		return match[1], nil
+	}
+	if _, candidates, ok := strings.Cut(out.String(), "could be any of:"); ok {
+		return "", fmt.Errorf("%w, the first bad commit is one of: %s", ErrBisectInconclusive, strings.Join(shaPattern.FindAllString(candidates, -1), ", "))
+	}
+	if runErr != nil {
+		return "", &CommandError{Args: args, Stderr: lastLines(out.String(), 5), Err: runErr}
+	}
+	return "", fmt.Errorf("git bisect run finished without naming the first bad commit")
+}
+
+// UpdateSubmodules checks out the commits recorded for every submodule,
+// using the same credentials as the parent repository
+func (c *ExecClient) UpdateSubmodules(dir string, depth int) error {
+	args := []string{"submodule", "update", "--init", "--recursive"}
+	if depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(depth))
+	}
+	return c.runRemote(dir, nil, args...)
+}
+
+// LFSPull downloads the LFS objects for the checked out commit
+func (c *ExecClient) LFSPull(dir string) error {
+	if err := c.requireLFS(); err != nil {
+		return err
+	}
+	return c.runRemote(dir, nil, "lfs", "pull")
+}
+
+// SparseCheckout limits the working tree to the cone-mode directories in
+// paths, or checks out every file again when paths is empty
+func (c *ExecClient) SparseCheckout(dir string, paths []string) error {
+	if len(paths) == 0 {
+		if sparse, _ := c.output(dir, "config", "--bool", "core.sparseCheckout"); sparse != "true" {
+			return nil
+		}
+		_, err := c.output(dir, "sparse-checkout", "disable")
+		return err
+	}
+	_, err := c.output(dir, append([]string{"sparse-checkout", "set", "--cone", "--"}, paths...)...)
+	return err
+}
+
+func (c *ExecClient) requireLFS() error {
+	if _, err := c.output("", "lfs", "version"); err != nil {
+		return fmt.Errorf("git.lfs is 'pull' but git-lfs is not installed, install it or set git.lfs to 'skip'")
+	}
+	return nil
+}
+
+// runRemote runs a git command that talks to a remote, streaming its output
+func (c *ExecClient) runRemote(dir string, env []string, args ...string) error {
+	if err := c.trustHost(); err != nil {
+		return err
+	}
+	return c.retry(args, func() error {
+		return c.runRemoteOnce(dir, env, args...)
+	})
+}
+
+func (c *ExecClient) runRemoteOnce(dir string, env []string, args ...string) error {
+	cmd := c.command(dir, true, args...)
+	cmd.Env = append(cmd.Env, env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if c.Stdout != nil {
+		stdout := redact.NewWriter(c.Stdout)
+		defer stdout.Flush()
+		cmd.Stdout = stdout
+	}
+	if c.Stderr != nil {
+		progress := redact.NewWriter(c.Stderr)
+		defer progress.Flush()
+		cmd.Stderr = io.MultiWriter(progress, &stderr)
+	}
+
+	if err := cmd.Run(); err != nil {
+		return &CommandError{Args: args, Stderr: stderr.String(), Err: err}
+	}
+	return nil
+}
+
+// output runs a local git command and returns its trimmed stdout
+func (c *ExecClient) output(dir string, args ...string) (string, error) {
+	return c.run(dir, false, args...)
+}
+
+func (c *ExecClient) run(dir string, remote bool, args ...string) (string, error) {
+	if !remote {
+		return c.runOnce(dir, false, args...)
+	}
+	if err := c.trustHost(); err != nil {
+		return "", err
+	}
+	var out string
+	err := c.retry(args, func() error {
+		var err error
+		out, err = c.runOnce(dir, true, args...)
+		return err
+	})
+	return out, err
+}
+
+func (c *ExecClient) runOnce(dir string, remote bool, args ...string) (string, error) {
+	cmd := c.command(dir, remote, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", &CommandError{Args: args, Stderr: stderr.String(), Err: err}
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// retry runs fn again with exponential backoff while it fails with a transient error
+func (c *ExecClient) retry(args []string, fn func() error) error {
+	delay := c.RetryDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > c.Retries || !IsTransient(err) {
+			return err
+		}
+		if c.Stderr != nil {
+			fmt.Fprintf(c.Stderr, "git %s failed with a network error, retrying in %s (%d/%d)\n", args[0], delay, attempt, c.Retries)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// trustHost makes sure the SSH host key can be verified before git connects
+func (c *ExecClient) trustHost() error {
+	if c.sshHost == "" {
+		return nil
+	}
+	c.trustOnce.Do(func() {
+		c.trustErr = EnsureKnownHost(c.sshHost, c.sshPort, c.hostFingerprints)
+	})
+	return c.trustErr
+}
+
+func splitHostPort(host string) (string, string) {
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+		return host[:i], host[i+1:]
+	}
+	return host, ""
+}
+
+// command builds the git process, adding credentials only when it talks to a remote
+func (c *ExecClient) command(dir string, remote bool, args ...string) *exec.Cmd {
+	var fullArgs []string
+	if dir != "" {
+		fullArgs = append(fullArgs, "-C", dir)
+	}
+	cmd := exec.Command("git", append(fullArgs, args...)...)
+	cmd.Env = append(os.Environ(), c.env...)
+	if remote {
+		// Credentials go through the environment so they never show up in the process list
+		cmd.Env = append(cmd.Env, c.remoteEnv...)
+		cmd.Env = append(cmd.Env, configEnv(cmd.Env, c.authConfig)...)
+	}
+	return cmd
+}
+
+// CommandError is returned when a git process exits unsuccessfully
+type CommandError struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	return redact.String(e.message())
+}
+
+func (e *CommandError) message() string {
+	name := "git"
+	for _, arg := range e.Args {
+		if !strings.HasPrefix(arg, "-") {
+			name = "git " + arg
+			break
+		}
+	}
+	if message := strings.TrimSpace(e.Stderr); message != "" {
+		return fmt.Sprintf("%s failed: %v: %s", name, e.Err, message)
+	}
+	return fmt.Sprintf("%s failed: %v", name, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// configEnv returns GIT_CONFIG_COUNT variables adding entries after any
+// the environment already defines
+func configEnv(env []string, entries [][2]string) []string {
+	if len(entries) == 0 {
+		return nil
+	}
+	count := 0
+	for _, variable := range env {
+		if value, ok := strings.CutPrefix(variable, "GIT_CONFIG_COUNT="); ok {
+			count, _ = strconv.Atoi(value)
+		}
+	}
+
+	var vars []string
+	for i, entry := range entries {
+		vars = append(vars,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", count+i, entry[0]),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", count+i, entry[1]),
+		)
+	}
+	return append(vars, fmt.Sprintf("GIT_CONFIG_COUNT=%d", count+len(entries)))
+}
+
+// scopeToHost limits an http.extraheader setting to the repository's host,
+// so submodules on the same host get the credentials and other hosts do not
+func scopeToHost(setting string, repoUrl string) string {
+	parsed, err := ParseRepoURL(repoUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return setting
+	}
+	value := strings.TrimPrefix(setting, "http.extraheader=")
+	return fmt.Sprintf("http.%s://%s/.extraheader=%s", parsed.Scheme, parsed.Host, value)
+}
+
+func parseLsRemote(out string) []RemoteRef {
+	var refs []RemoteRef
+	targets := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 2 {
+			continue
+		}
+		// Symbolic refs are reported as "ref: refs/heads/main\tHEAD"
+		if strings.HasPrefix(fields[0], "ref: ") {
+			targets[fields[1]] = strings.TrimPrefix(fields[0], "ref: ")
+			continue
+		}
+		refs = append(refs, RemoteRef{SHA: fields[0], Name: fields[1]})
+	}
+	for i := range refs {
+		refs[i].Target = targets[refs[i].Name]
+	}
+	return refs
+}
+
+func parseLog(out string) []Commit {
+	var commits []Commit
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		if len(fields) != 5 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[3])
+		commits = append(commits, Commit{
+			SHA:     fields[0],
+			Author:  fields[1],
+			Email:   fields[2],
+			Date:    date,
+			Subject: fields[4],
+		})
+	}
+	return commits
+}
@@ -0,0 +1,517 @@
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FakeRepo is an in-memory remote used by FakeClient
+type FakeRepo struct {
+	DefaultBranch string
+	Refs          map[string]string // Full ref name (refs/heads/main) to commit SHA
+	Commits       map[string]Commit
+}
+
+// FakeClone is the state of a directory cloned by FakeClient
+type FakeClone struct {
+	URL       string
+	Head      string // Checked out commit SHA
+	Branch    string
+	Fetched   map[string]string // Remote refs as of the last clone or fetch
+	Worktrees map[string]string // Worktree path to commit SHA
+	Dirty     bool              // Simulates uncommitted changes
+}
+
+// FakeClient implements Client in memory so handlers can be tested
+// without touching the network or the filesystem
+type FakeClient struct {
+	mu      sync.Mutex
+	Remotes map[string]*FakeRepo  // Keyed by URL
+	Clones  map[string]*FakeClone // Keyed by directory
+	Calls   []string              // Every operation in the order it was made
+	Errors  map[string]error      // Operation name (e.g. "Clone") to error to return
+	// Signatures by commit SHA, commits without one are unsigned
+	Signatures map[string]*Signature
+}
+
+// NewFakeClient creates an empty fake
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		Remotes:    map[string]*FakeRepo{},
+		Clones:     map[string]*FakeClone{},
+		Errors:     map[string]error{},
+		Signatures: map[string]*Signature{},
+	}
+}
+
+// AddCommit records a commit on branch in the remote at url, creating the remote if needed
+func (f *FakeClient) AddCommit(url string, branch string, commit Commit) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	repo, ok := f.Remotes[url]
+	if !ok {
+		repo = &FakeRepo{DefaultBranch: branch, Refs: map[string]string{}, Commits: map[string]Commit{}}
+		f.Remotes[url] = repo
+	}
+	repo.Commits[commit.SHA] = commit
+	repo.Refs["refs/heads/"+branch] = commit.SHA
+}
+
+// AddTag points tag at an existing commit in the remote at url
+func (f *FakeClient) AddTag(url string, tag string, sha string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if repo, ok := f.Remotes[url]; ok {
+		repo.Refs["refs/tags/"+tag] = sha
+	}
+}
+
+func (f *FakeClient) record(op string, args ...string) error {
+	f.Calls = append(f.Calls, strings.TrimSpace(op+" "+strings.Join(args, " ")))
+	return f.Errors[op]
+}
+
+func (f *FakeClient) Clone(opts CloneOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Clone", opts.URL, opts.Dir); err != nil {
+		return err
+	}
+
+	repo, ok := f.Remotes[opts.URL]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRepoNotFound, opts.URL)
+	}
+	dir := opts.Dir
+	if dir == "" {
+		dir = GetProjectDirName(opts.URL)
+	}
+	if _, exists := f.Clones[dir]; exists {
+		return fmt.Errorf("destination path '%s' already exists", dir)
+	}
+
+	branch := opts.Branch
+	if branch == "" {
+		branch = repo.DefaultBranch
+	}
+	sha, ok := repo.Refs["refs/heads/"+branch]
+	if !ok {
+		// Like git clone -b, a tag is checked out detached
+		if sha, ok = repo.Refs["refs/tags/"+branch]; !ok {
+			return fmt.Errorf("%w: %s", ErrBranchNotFound, branch)
+		}
+		branch = ""
+	}
+
+	f.Clones[dir] = &FakeClone{
+		URL:       opts.URL,
+		Head:      sha,
+		Branch:    branch,
+		Fetched:   copyRefs(repo.Refs),
+		Worktrees: map[string]string{},
+	}
+	return nil
+}
+
+func (f *FakeClient) Fetch(dir string, opts FetchOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Fetch", dir); err != nil {
+		return err
+	}
+
+	clone, repo, err := f.lookup(dir)
+	if err != nil {
+		return err
+	}
+	clone.Fetched = copyRefs(repo.Refs)
+	return nil
+}
+
+func (f *FakeClient) Checkout(dir string, ref string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Checkout", dir, ref); err != nil {
+		return err
+	}
+
+	clone, repo, err := f.lookup(dir)
+	if err != nil {
+		return err
+	}
+	sha, err := resolve(clone, repo, ref)
+	if err != nil {
+		return err
+	}
+	clone.Head = sha
+	clone.Branch = ""
+	if _, ok := clone.Fetched["refs/heads/"+ref]; ok {
+		clone.Branch = ref
+	}
+	return nil
+}
+
+func (f *FakeClient) RevParse(dir string, rev string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("RevParse", dir, rev); err != nil {
+		return "", err
+	}
+
+	clone, repo, err := f.lookup(dir)
+	if err != nil {
+		return "", err
+	}
+	return resolve(clone, repo, rev)
+}
+
+func (f *FakeClient) LsRemote(url string, patterns ...string) ([]RemoteRef, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("LsRemote", append([]string{url}, patterns...)...); err != nil {
+		return nil, err
+	}
+
+	repo, ok := f.Remotes[url]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRepoNotFound, url)
+	}
+
+	var refs []RemoteRef
+	if sha, ok := repo.Refs["refs/heads/"+repo.DefaultBranch]; ok {
+		refs = append(refs, RemoteRef{Name: "HEAD", SHA: sha, Target: "refs/heads/" + repo.DefaultBranch})
+	}
+	names := make([]string, 0, len(repo.Refs))
+	for name := range repo.Refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		refs = append(refs, RemoteRef{Name: name, SHA: repo.Refs[name]})
+	}
+
+	if len(patterns) == 0 {
+		return refs, nil
+	}
+	var matched []RemoteRef
+	for _, ref := range refs {
+		for _, pattern := range patterns {
+			if ref.Name == pattern || strings.HasSuffix(ref.Name, "/"+pattern) {
+				matched = append(matched, ref)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// Log returns the single commit rev resolves to, the fake has no parent links
+func (f *FakeClient) Log(dir string, rev string, limit int) ([]Commit, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Log", dir, rev); err != nil {
+		return nil, err
+	}
+
+	clone, repo, err := f.lookup(dir)
+	if err != nil {
+		return nil, err
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	sha, err := resolve(clone, repo, rev)
+	if err != nil {
+		return nil, err
+	}
+	return []Commit{repo.Commits[sha]}, nil
+}
+
+func (f *FakeClient) Worktree(dir string, path string, ref string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Worktree", dir, path, ref); err != nil {
+		return err
+	}
+
+	clone, repo, err := f.lookup(dir)
+	if err != nil {
+		return err
+	}
+	sha, err := resolve(clone, repo, ref)
+	if err != nil {
+		return err
+	}
+	clone.Worktrees[path] = sha
+	return nil
+}
+
+func (f *FakeClient) RemoveWorktree(dir string, path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("RemoveWorktree", dir, path); err != nil {
+		return err
+	}
+
+	clone, _, err := f.lookup(dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := clone.Worktrees[path]; !ok {
+		return fmt.Errorf("'%s' is not a working tree", path)
+	}
+	delete(clone.Worktrees, path)
+	return nil
+}
+
+func (f *FakeClient) GitPath(dir string, name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("GitPath", dir, name); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ".git", name), nil
+}
+
+// ChangedFiles reports no changes, the fake keeps no files
+func (f *FakeClient) ChangedFiles(dir string, base string, rev string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("ChangedFiles", dir, base, rev); err != nil {
+		return nil, err
+	}
+	if _, _, err := f.lookup(dir); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (f *FakeClient) RemoteURL(dir string, remote string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("RemoteURL", dir, remote); err != nil {
+		return "", err
+	}
+
+	clone, ok := f.Clones[dir]
+	if !ok {
+		return "", fmt.Errorf("not a git repository: %s", dir)
+	}
+	return clone.URL, nil
+}
+
+func (f *FakeClient) CurrentBranch(dir string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("CurrentBranch", dir); err != nil {
+		return "", err
+	}
+
+	clone, ok := f.Clones[dir]
+	if !ok {
+		return "", fmt.Errorf("not a git repository: %s", dir)
+	}
+	return clone.Branch, nil
+}
+
+func (f *FakeClient) IsDirty(dir string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("IsDirty", dir); err != nil {
+		return false, err
+	}
+
+	clone, ok := f.Clones[dir]
+	if !ok {
+		return false, fmt.Errorf("not a git repository: %s", dir)
+	}
+	return clone.Dirty, nil
+}
+
+// FastForward moves HEAD to ref. The fake has no history, so divergence
+// is simulated by setting Errors["FastForward"].
+func (f *FakeClient) FastForward(dir string, ref string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("FastForward", dir, ref); err != nil {
+		return err
+	}
+	return f.moveHead(dir, ref)
+}
+
+// IsAncestor resolves both revisions. The fake has no history, so a commit
+// is only an ancestor of itself.
+func (f *FakeClient) IsAncestor(dir string, ancestor string, rev string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("IsAncestor", dir, ancestor, rev); err != nil {
+		return false, err
+	}
+
+	clone, repo, err := f.lookup(dir)
+	if err != nil {
+		return false, err
+	}
+	a, err := resolve(clone, repo, ancestor)
+	if err != nil {
+		return false, err
+	}
+	b, err := resolve(clone, repo, rev)
+	if err != nil {
+		return false, err
+	}
+	return a == b, nil
+}
+
+func (f *FakeClient) ResetHard(dir string, ref string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("ResetHard", dir, ref); err != nil {
+		return err
+	}
+	if err := f.moveHead(dir, ref); err != nil {
+		return err
+	}
+	f.Clones[dir].Dirty = false
+	return nil
+}
+
+// Merge moves HEAD to a new commit standing for the merge of ref. Conflicts
+// are simulated by setting Errors["Merge"].
+func (f *FakeClient) Merge(dir string, ref string, message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Merge", dir, ref); err != nil {
+		return err
+	}
+
+	clone, repo, err := f.lookup(dir)
+	if err != nil {
+		return err
+	}
+	sha, err := resolve(clone, repo, ref)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(clone.Head + sha))
+	merge := hex.EncodeToString(sum[:20])
+	repo.Commits[merge] = Commit{SHA: merge, Author: "AutomateLife", Subject: message}
+	clone.Head = merge
+	return nil
+}
+
+func (f *FakeClient) VerifySignature(dir string, rev string, keys SigningKeys) (*Signature, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("VerifySignature", dir, rev); err != nil {
+		return nil, err
+	}
+
+	clone, repo, err := f.lookup(dir)
+	if err != nil {
+		return nil, err
+	}
+	sha, err := resolve(clone, repo, strings.TrimSuffix(rev, "^{commit}"))
+	if err != nil {
+		return nil, err
+	}
+	if sig, ok := f.Signatures[sha]; ok {
+		return sig, nil
+	}
+	return &Signature{Object: "commit"}, nil
+}
+
+// Bisect returns bad itself, the fake has no history between good and bad
+func (f *FakeClient) Bisect(dir string, good string, bad string, command []string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("Bisect", dir, good, bad); err != nil {
+		return "", err
+	}
+
+	clone, repo, err := f.lookup(dir)
+	if err != nil {
+		return "", err
+	}
+	if _, err := resolve(clone, repo, good); err != nil {
+		return "", err
+	}
+	return resolve(clone, repo, bad)
+}
+
+func (f *FakeClient) UpdateSubmodules(dir string, depth int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record("UpdateSubmodules", dir)
+}
+
+func (f *FakeClient) LFSPull(dir string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record("LFSPull", dir)
+}
+
+func (f *FakeClient) SparseCheckout(dir string, paths []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.record("SparseCheckout", append([]string{dir}, paths...)...)
+}
+
+func (f *FakeClient) moveHead(dir string, ref string) error {
+	clone, repo, err := f.lookup(dir)
+	if err != nil {
+		return err
+	}
+	sha, err := resolve(clone, repo, ref)
+	if err != nil {
+		return err
+	}
+	clone.Head = sha
+	return nil
+}
+
+func (f *FakeClient) lookup(dir string) (*FakeClone, *FakeRepo, error) {
+	clone, ok := f.Clones[dir]
+	if !ok {
+		return nil, nil, fmt.Errorf("not a git repository: %s", dir)
+	}
+	repo, ok := f.Remotes[clone.URL]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrRepoNotFound, clone.URL)
+	}
+	return clone, repo, nil
+}
+
+// resolve maps HEAD, branch names, origin/<branch>, full ref names and
+// (abbreviated) SHAs to a commit SHA known to the clone
+func resolve(clone *FakeClone, repo *FakeRepo, rev string) (string, error) {
+	if rev == "HEAD" {
+		return clone.Head, nil
+	}
+	rev = strings.TrimPrefix(rev, "origin/")
+	for _, name := range []string{rev, "refs/heads/" + rev, "refs/tags/" + rev} {
+		if sha, ok := clone.Fetched[name]; ok {
+			return sha, nil
+		}
+	}
+	for sha := range repo.Commits {
+		if len(rev) >= 4 && strings.HasPrefix(sha, rev) {
+			return sha, nil
+		}
+	}
+	return "", fmt.Errorf("unknown revision %s", rev)
+}
+
+func copyRefs(refs map[string]string) map[string]string {
+	copied := make(map[string]string, len(refs))
+	for name, sha := range refs {
+		copied[name] = sha
+	}
+	return copied
+}
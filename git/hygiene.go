@@ -0,0 +1,81 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// IsIgnored reports whether path is ignored by git. inRepo is false when
+// path does not live inside a git working tree, in which case ignored is meaningless.
+func IsIgnored(path string) (ignored bool, inRepo bool, err error) {
+	dir := filepath.Dir(path)
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		return false, false, nil
+	}
+
+	cmd := exec.Command("git", "-C", dir, "check-ignore", "-q", filepath.Base(path))
+	err = cmd.Run()
+	if err == nil {
+		return true, true, nil
+	}
+
+	// check-ignore exits 1 when the path is not ignored
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, true, nil
+	}
+	return false, true, fmt.Errorf("git check-ignore failed: %w", err)
+}
+
+// CommitsTouching lists the commits in repoDir (across all refs) that
+// added or changed a file called name anywhere in the tree
+func CommitsTouching(repoDir string, name string) ([]string, error) {
+	cmd := exec.Command("git", "-C", repoDir, "log", "--all", "--format=%h %s", "--", ":(glob)**/"+name)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed in %s: %w", repoDir, err)
+	}
+
+	var commits []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits, nil
+}
+
+// AddToGitignore appends an entry for path to the .gitignore next to it
+func AddToGitignore(path string) error {
+	gitignore := filepath.Join(filepath.Dir(path), ".gitignore")
+	entry := "/" + filepath.Base(path)
+
+	existing, err := os.ReadFile(gitignore)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", gitignore, err)
+	}
+	for _, line := range strings.Split(string(existing), "\n") {
+		if strings.TrimSpace(line) == entry {
+			return nil
+		}
+	}
+
+	file, err := os.OpenFile(gitignore, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", gitignore, err)
+	}
+	defer file.Close()
+
+	prefix := ""
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		prefix = "\n"
+	}
+	if _, err := file.WriteString(prefix + entry + "\n"); err != nil {
+		return fmt.Errorf("failed to update %s: %w", gitignore, err)
+	}
+	return nil
+}
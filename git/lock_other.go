@@ -0,0 +1,8 @@
+//go:build !unix
+
+package git
+
+// lockFile does nothing, only this process is kept from racing here
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"automateLife/config"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// SigningKeys are the keys a signature has to be made with to be trusted.
+// Signatures of the other kind are never trusted.
+type SigningKeys struct {
+	AllowedSigners string // SSH allowed signers file, as read by ssh-keygen -Y verify
+	GPGKeyring     string // GnuPG home directory or exported public keys
+}
+
+// SigningKeysFromConfig copies the trusted keys out of the git settings
+func SigningKeysFromConfig(cfg *config.GitConfig) SigningKeys {
+	return SigningKeys{AllowedSigners: cfg.AllowedSigners, GPGKeyring: cfg.GPGKeyring}
+}
+
+// Signature is the outcome of verifying a commit or tag
+type Signature struct {
+	Object   string // "commit" or "tag"
+	Signed   bool
+	Verified bool   // Signed with one of the trusted keys
+	Signer   string // SSH principal or GPG user ID, when known
+	Key      string // Key fingerprint or ID, when known
+	Output   string // What gpg or ssh-keygen reported
+}
+
+// VerifyRevision verifies the signature of rev. A tag without a signature
+// of its own is trusted through the commit it points to.
+func VerifyRevision(client Client, dir string, rev string, keys SigningKeys) (*Signature, error) {
+	sig, err := client.VerifySignature(dir, rev, keys)
+	if err != nil {
+		return nil, err
+	}
+	if sig.Object == "tag" && !sig.Signed {
+		return client.VerifySignature(dir, rev+"^{commit}", keys)
+	}
+	return sig, nil
+}
+
+var (
+	sshSignaturePattern = regexp.MustCompile(`signature (?:for (\S+) )?with (\S+ key \S+)`)
+	gpgStatusPattern    = regexp.MustCompile(`^\[GNUPG:\] (GOODSIG|BADSIG|EXPSIG|EXPKEYSIG|REVKEYSIG|ERRSIG|NO_PUBKEY|VALIDSIG) (\S+) ?(.*)$`)
+)
+
+// parseSignature reads the --raw output of git verify-commit and verify-tag:
+// GnuPG status lines, or ssh-keygen's messages for SSH signatures
+func parseSignature(output string) *Signature {
+	output = strings.TrimSpace(output)
+	// verify-tag says so for unsigned tags, verify-commit stays silent
+	sig := &Signature{Output: output, Signed: output != "" && !strings.Contains(output, "no signature found")}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if match := gpgStatusPattern.FindStringSubmatch(line); match != nil {
+			switch match[1] {
+			case "VALIDSIG":
+				sig.Key = match[2]
+			case "ERRSIG", "NO_PUBKEY":
+				if sig.Key == "" {
+					sig.Key = match[2]
+				}
+			default:
+				if sig.Key == "" {
+					sig.Key = match[2]
+				}
+				sig.Signer = match[3]
+			}
+			continue
+		}
+		if match := sshSignaturePattern.FindStringSubmatch(line); match != nil {
+			if match[1] != "" {
+				sig.Signer = match[1]
+			}
+			sig.Key = match[2]
+		}
+	}
+	return sig
+}
+
+// gpgHome returns a GnuPG home directory holding only the trusted keys, and
+// a function removing it when it was made for this check. Without a keyring
+// the directory is empty, so the user's own keyring is never consulted.
+func gpgHome(keyring string) (string, func(), error) {
+	if info, err := os.Stat(keyring); err == nil && info.IsDir() {
+		return keyring, func() {}, nil
+	}
+	home, err := os.MkdirTemp("", "automatelife-gnupg-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create a GnuPG home directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(home) }
+	if keyring == "" {
+		return home, cleanup, nil
+	}
+	if out, err := exec.Command("gpg", "--batch", "--quiet", "--homedir", home, "--import", keyring).CombinedOutput(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to import the GPG keyring %s: %v: %s", keyring, err, strings.TrimSpace(string(out)))
+	}
+	return home, cleanup, nil
+}
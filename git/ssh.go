@@ -0,0 +1,335 @@
+package git
+
+import (
+	"automateLife/config"
+	"automateLife/utils"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	askpassVar    = "AUTOMATELIFE_ASKPASS"
+	passphraseVar = "AUTOMATELIFE_SSH_PASSPHRASE"
+)
+
+// SSHCommand returns the GIT_SSH_COMMAND for the given settings. Host keys
+// are always verified, against the managed known_hosts file and the user's own.
+func SSHCommand(cfg *config.GitConfig) (string, error) {
+	knownHosts, err := KnownHostsFile()
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{
+		"ssh",
+		"-o", "StrictHostKeyChecking=yes",
+		"-o", shellQuote(fmt.Sprintf(`UserKnownHostsFile="%s" "~/.ssh/known_hosts"`, knownHosts)),
+	}
+
+	switch {
+	case cfg.SSHKeyPath != "":
+		keyPath, err := sshKeyPath(cfg.SSHKeyPath)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, "-i", shellQuote(keyPath), "-o", "IdentitiesOnly=yes")
+	case cfg.SSHAgent:
+		if os.Getenv("SSH_AUTH_SOCK") == "" {
+			return "", fmt.Errorf("ssh_agent is enabled but SSH_AUTH_SOCK is not set, start ssh-agent and add your key with ssh-add")
+		}
+	default:
+		return "", fmt.Errorf("ssh_key_path must be provided when auth_type is 'ssh' unless ssh_agent is enabled")
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+func sshKeyPath(keyPath string) (string, error) {
+	// Expand environment variables and tilde in the key path
+	expandedPath := utils.ExpandEnvVars(keyPath)
+
+	if _, err := os.Stat(expandedPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("SSH key not found at %s (expanded from: %s)", expandedPath, keyPath)
+	}
+	return expandedPath, nil
+}
+
+// sshEnv returns the environment the ssh child processes need, and the
+// part of it that only git processes talking to a remote get: the
+// passphrase mustn't reach local commands such as git bisect run.
+func sshEnv(cfg *config.GitConfig) ([]string, []string, error) {
+	sshCommand, err := SSHCommand(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	env := []string{"GIT_SSH_COMMAND=" + sshCommand}
+	var remoteEnv []string
+
+	if cfg.SSHPassphrase != "" {
+		// ssh reads passphrases from the terminal, so hand it over through
+		// this binary running in askpass mode instead
+		self, err := os.Executable()
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not locate automateLife for ssh passphrase prompts: %w", err)
+		}
+		remoteEnv = append(remoteEnv,
+			"SSH_ASKPASS="+self,
+			"SSH_ASKPASS_REQUIRE=force",
+			askpassVar+"=1",
+			passphraseVar+"="+cfg.SSHPassphrase,
+		)
+	}
+	return env, remoteEnv, nil
+}
+
+// withoutVars returns env without the given variables
+func withoutVars(env []string, names ...string) []string {
+	var kept []string
+	for _, variable := range env {
+		name, _, _ := strings.Cut(variable, "=")
+		if !slices.Contains(names, name) {
+			kept = append(kept, variable)
+		}
+	}
+	return kept
+}
+
+// Askpass answers ssh's passphrase prompt when the binary was started as
+// SSH_ASKPASS by an ExecClient. It reports whether it handled the
+// invocation and the exit code to use.
+func Askpass(args []string) (int, bool) {
+	if os.Getenv(askpassVar) != "1" {
+		return 0, false
+	}
+	// Only passphrases are answered, never host key confirmations
+	if len(args) == 0 || !strings.Contains(strings.ToLower(args[0]), "passphrase") {
+		return 1, true
+	}
+	fmt.Println(os.Getenv(passphraseVar))
+	return 0, true
+}
+
+// KeyNeedsPassphrase reports whether the private key at keyPath is encrypted
+func KeyNeedsPassphrase(keyPath string) (bool, error) {
+	data, err := os.ReadFile(utils.ExpandEnvVars(keyPath))
+	if err != nil {
+		return false, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return false, nil
+	}
+	switch {
+	case block.Type == "ENCRYPTED PRIVATE KEY", block.Headers["Proc-Type"] == "4,ENCRYPTED":
+		return true, nil
+	case block.Type == "OPENSSH PRIVATE KEY":
+		// openssh-key-v1\0, then the cipher name as a length-prefixed string
+		const magic = "openssh-key-v1\x00"
+		body := block.Bytes
+		if !bytes.HasPrefix(body, []byte(magic)) || len(body) < len(magic)+4 {
+			return false, nil
+		}
+		body = body[len(magic):]
+		length := binary.BigEndian.Uint32(body)
+		if int(length) > len(body)-4 {
+			return false, nil
+		}
+		return string(body[4:4+length]) != "none", nil
+	}
+	return false, nil
+}
+
+// KnownHostsFile returns the known_hosts file AutomateLife adds pinned host keys to
+func KnownHostsFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not locate the config directory for known_hosts: %w", err)
+	}
+	return filepath.Join(dir, "automatelife", "known_hosts"), nil
+}
+
+// HostKeyFingerprint returns the SHA256 fingerprint of a known_hosts or
+// ssh-keyscan line, in the format ssh-keygen -l prints
+func HostKeyFingerprint(line string) (string, error) {
+	fields := strings.Fields(line)
+	for i := 0; i+1 < len(fields); i++ {
+		if !isKeyType(fields[i]) {
+			continue
+		}
+		blob, err := base64.StdEncoding.DecodeString(fields[i+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid host key: %w", err)
+		}
+		sum := sha256.Sum256(blob)
+		return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]), nil
+	}
+	return "", fmt.Errorf("no host key found in %q", line)
+}
+
+func isKeyType(field string) bool {
+	return strings.HasPrefix(field, "ssh-") || strings.HasPrefix(field, "ecdsa-") || strings.HasPrefix(field, "sk-")
+}
+
+// PinnedHostKeys returns the lines whose key matches one of fingerprints
+func PinnedHostKeys(lines []string, fingerprints []string) []string {
+	var pinned []string
+	for _, line := range lines {
+		fingerprint, err := HostKeyFingerprint(line)
+		if err != nil {
+			continue
+		}
+		for _, want := range fingerprints {
+			if strings.TrimRight(want, "=") == fingerprint {
+				pinned = append(pinned, line)
+				break
+			}
+		}
+	}
+	return pinned
+}
+
+// knownHostsMu serialises updates of the managed known_hosts file within
+// this process, a lock file next to it keeps other processes out
+var knownHostsMu sync.Mutex
+
+// EnsureKnownHost makes sure ssh can verify host. With pinned fingerprints
+// the host's keys are scanned and the matching ones added to the managed
+// known_hosts file; without them the host must already be known. It is
+// safe to call for the same host from several goroutines or processes.
+func EnsureKnownHost(host string, port string, fingerprints []string) error {
+	knownHosts, err := KnownHostsFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(knownHosts), 0700); err != nil {
+		return fmt.Errorf("failed to create known_hosts directory: %w", err)
+	}
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+	unlock, err := lockFile(knownHosts + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	name := host
+	if port != "" && port != "22" {
+		name = fmt.Sprintf("[%s]:%s", host, port)
+	}
+
+	managed := lookupKnownHost(name, knownHosts)
+	if len(fingerprints) == 0 {
+		home, _ := os.UserHomeDir()
+		for _, file := range []string{knownHosts, filepath.Join(home, ".ssh", "known_hosts"), "/etc/ssh/ssh_known_hosts"} {
+			if len(lookupKnownHost(name, file)) > 0 {
+				return nil
+			}
+		}
+		scanned, _ := keyscan(host, port)
+		return fmt.Errorf("%w: host key of %s is not known, verify it and add its fingerprint to git.ssh_host_fingerprints%s", ErrHostKey, host, offeredKeys(scanned))
+	}
+
+	if len(PinnedHostKeys(managed, fingerprints)) > 0 {
+		return nil
+	}
+
+	scanned, err := keyscan(host, port)
+	if err != nil {
+		return err
+	}
+	trusted := PinnedHostKeys(scanned, fingerprints)
+	if len(trusted) == 0 {
+		return fmt.Errorf("%w: host key of %s does not match git.ssh_host_fingerprints%s", ErrHostKey, host, offeredKeys(scanned))
+	}
+
+	// Drop keys that are no longer pinned before adding the current ones
+	if len(managed) > 0 {
+		exec.Command("ssh-keygen", "-R", name, "-f", knownHosts).Run()
+	}
+	return appendKnownHosts(knownHosts, trusted)
+}
+
+// lookupKnownHost returns the entries for name in a known_hosts file
+func lookupKnownHost(name string, file string) []string {
+	if _, err := os.Stat(file); err != nil {
+		return nil
+	}
+	out, err := exec.Command("ssh-keygen", "-F", name, "-f", file).Output()
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func keyscan(host string, port string) ([]string, error) {
+	args := []string{"-T", "10"}
+	if port != "" {
+		args = append(args, "-p", port)
+	}
+	out, err := exec.Command("ssh-keyscan", append(args, host)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ssh-keyscan %s failed: %w", host, err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("ssh-keyscan returned no host keys for %s", host)
+	}
+	return lines, nil
+}
+
+// offeredKeys lists the fingerprints the server presents, to help the user
+// compare them with the ones their provider publishes
+func offeredKeys(scanned []string) string {
+	if len(scanned) == 0 {
+		return ""
+	}
+	var fingerprints []string
+	for _, line := range scanned {
+		if fingerprint, err := HostKeyFingerprint(line); err == nil {
+			fingerprints = append(fingerprints, fingerprint)
+		}
+	}
+	return " (server offers " + strings.Join(fingerprints, ", ") + ")"
+}
+
+func appendKnownHosts(file string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("failed to create known_hosts directory: %w", err)
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open known_hosts: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// shellQuote quotes s for the shell git runs GIT_SSH_COMMAND with
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,434 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SyncOptions controls CloneOrUpdate
+type SyncOptions struct {
+	URL    string
+	Dir    string
+	Branch string // Defaults to the remote default on clone and the current branch on update
+	Ref    string // Branch, tag or commit SHA; tags and SHAs are checked out detached. Overrides Branch.
+	Force  bool   // Hard-reset to the remote branch when it has diverged
+	// Local repository, usually a cache mirror, to copy objects from when cloning
+	Reference string
+	// Pull request to check out on top of the synced branch. Overrides Ref.
+	PullRequest *PullRequest
+	Tuning
+}
+
+// PullRequest is a pull or merge request fetched from the ref the provider
+// publishes it under
+type PullRequest struct {
+	Number int
+	Ref    string // e.g. refs/pull/123/head
+	Merge  bool   // Merge Ref into the target branch locally and check out the result
+}
+
+// SyncResult reports what CloneOrUpdate did
+type SyncResult struct {
+	Cloned    bool
+	Branch    string // Empty when a tag or commit is checked out
+	Ref       string // The tag or commit SHA that was requested, empty for branches
+	OldCommit string // Empty when the repository was freshly cloned
+	NewCommit string
+	Commit    Commit // Details of NewCommit
+}
+
+// Updated reports whether an existing clone moved to a different commit
+func (r *SyncResult) Updated() bool {
+	return !r.Cloned && r.OldCommit != r.NewCommit
+}
+
+var (
+	// ErrDirtyWorkTree is returned when an existing clone has uncommitted changes
+	ErrDirtyWorkTree = errors.New("working tree has uncommitted changes")
+	// ErrRemoteMismatch is returned when the directory is a clone of a different remote
+	ErrRemoteMismatch = errors.New("directory is a clone of a different remote")
+	// ErrDiverged is returned when the local branch has commits the remote branch lacks
+	ErrDiverged = errors.New("local branch has diverged from the remote")
+	// ErrMergeConflict is returned when Merge stops on conflicting changes
+	ErrMergeConflict = errors.New("merge conflict")
+)
+
+// CloneOrUpdate clones the repository into opts.Dir, or when a clone of the
+// same remote is already there, fetches and fast-forwards it instead
+func CloneOrUpdate(client Client, opts SyncOptions) (*SyncResult, error) {
+	if opts.PullRequest != nil {
+		return syncPullRequest(client, opts)
+	}
+
+	pin, err := resolveRef(client, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	existingURL, err := originOf(client, opts.Dir)
+	if err != nil {
+		if !isEmptyOrMissing(opts.Dir) {
+			return nil, fmt.Errorf("%s already exists and is not a git clone, remove it or choose another clone directory", opts.Dir)
+		}
+		if pin != nil {
+			return clonePinned(client, opts, pin)
+		}
+		return clone(client, opts)
+	}
+
+	if !SameRemote(existingURL, opts.URL) {
+		return nil, fmt.Errorf("%w: %s points to %s, expected %s", ErrRemoteMismatch, opts.Dir, existingURL, opts.URL)
+	}
+
+	dirty, err := client.IsDirty(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, fmt.Errorf("%w in %s, commit or stash them first", ErrDirtyWorkTree, opts.Dir)
+	}
+
+	// The paths may have changed since the clone, widening or narrowing the cone
+	if err := client.SparseCheckout(opts.Dir, opts.SparsePaths); err != nil {
+		return nil, err
+	}
+
+	if pin != nil {
+		oldCommit, err := client.RevParse(opts.Dir, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		result, err := checkoutPinned(client, opts, pin)
+		if err != nil {
+			return nil, err
+		}
+		result.OldCommit = oldCommit
+		return result, nil
+	}
+
+	result := &SyncResult{Branch: opts.Branch}
+	if result.OldCommit, err = client.RevParse(opts.Dir, "HEAD"); err != nil {
+		return nil, err
+	}
+
+	current, err := client.CurrentBranch(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if result.Branch == "" {
+		result.Branch = current
+	}
+	if result.Branch == "" {
+		// Detached by a pull request or pinned ref checked out before
+		if result.Branch, err = defaultBranch(client, opts.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	upstream := "origin/" + result.Branch
+	// A shallow fetch cuts the history between HEAD and the new upstream, so
+	// remember the old upstream to tell whether HEAD has commits of its own
+	var fetched string
+	if opts.Depth > 0 {
+		fetched, _ = client.RevParse(opts.Dir, upstream)
+	}
+
+	// Single-branch clones fetch the branch by name, which also lets them switch branches
+	refspec := fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", result.Branch, result.Branch)
+	if !opts.SingleBranch {
+		refspec = "+refs/heads/*:refs/remotes/origin/*"
+	}
+	if err := client.Fetch(opts.Dir, FetchOptions{Prune: true, Refspecs: []string{refspec}, Depth: opts.Depth}); err != nil {
+		return nil, err
+	}
+
+	if current != result.Branch {
+		if err := client.Checkout(opts.Dir, result.Branch); err != nil {
+			return nil, err
+		}
+	}
+
+	if ffErr := client.FastForward(opts.Dir, upstream); ffErr != nil {
+		// HEAD still behind upstream means the merge itself failed, e.g. on an untracked file in the way
+		if behind, err := client.IsAncestor(opts.Dir, "HEAD", upstream); err != nil || behind {
+			return nil, fmt.Errorf("failed to fast-forward %s to %s: %w", result.Branch, upstream, ffErr)
+		}
+		if !opts.Force && !atUpstream(client, opts.Dir, fetched) {
+			return nil, fmt.Errorf("%w: %s cannot be fast-forwarded to %s, rerun with --force to reset it", ErrDiverged, result.Branch, upstream)
+		}
+		if err := client.ResetHard(opts.Dir, upstream); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := updateExtras(client, opts); err != nil {
+		return nil, err
+	}
+	if err := recordHead(client, opts.Dir, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// atUpstream reports whether HEAD is still the upstream commit fetched
+// before, so moving it to the new upstream loses no local commits
+func atUpstream(client Client, dir string, fetched string) bool {
+	if fetched == "" {
+		return false
+	}
+	head, err := client.RevParse(dir, "HEAD")
+	return err == nil && head == fetched
+}
+
+// syncPullRequest brings the target branch up to date, then fetches the
+// pull request and checks it out detached, merged into the target branch
+// when asked to
+func syncPullRequest(client Client, opts SyncOptions) (*SyncResult, error) {
+	pr := opts.PullRequest
+	opts.PullRequest = nil
+	opts.Ref = ""
+	if opts.Branch == "" {
+		// The previous checkout may be a detached pull request, so don't rely on the current branch
+		branch, err := defaultBranch(client, opts.URL)
+		if err != nil {
+			return nil, err
+		}
+		opts.Branch = branch
+	}
+
+	base, err := CloneOrUpdate(client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	refspec := fmt.Sprintf("+%s:%s", pr.Ref, pr.Ref)
+	if err := client.Fetch(opts.Dir, FetchOptions{Refspecs: []string{refspec}}); err != nil {
+		return nil, fmt.Errorf("pull request #%d: %w", pr.Number, err)
+	}
+	if pr.Merge {
+		if err := client.Checkout(opts.Dir, "origin/"+opts.Branch); err != nil {
+			return nil, err
+		}
+		message := fmt.Sprintf("Merge pull request #%d into %s", pr.Number, opts.Branch)
+		if err := client.Merge(opts.Dir, pr.Ref, message); err != nil {
+			return nil, fmt.Errorf("pull request #%d does not merge cleanly into %s: %w", pr.Number, opts.Branch, err)
+		}
+	} else if err := client.Checkout(opts.Dir, pr.Ref); err != nil {
+		return nil, err
+	}
+	if err := updateExtras(client, opts); err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{Cloned: base.Cloned, Ref: pr.Ref, OldCommit: base.OldCommit}
+	if err := recordHead(client, opts.Dir, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// defaultBranch asks the remote which branch its HEAD points to
+func defaultBranch(client Client, url string) (string, error) {
+	refs, err := client.LsRemote(url, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	for _, ref := range refs {
+		if ref.Name == "HEAD" && strings.HasPrefix(ref.Target, "refs/heads/") {
+			return strings.TrimPrefix(ref.Target, "refs/heads/"), nil
+		}
+	}
+	return "", fmt.Errorf("could not determine the default branch of %s, set git.branch", url)
+}
+
+func clone(client Client, opts SyncOptions) (*SyncResult, error) {
+	if err := client.Clone(CloneOptions{URL: opts.URL, Dir: opts.Dir, Branch: opts.Branch, Reference: opts.Reference, Tuning: opts.Tuning}); err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{Cloned: true, Branch: opts.Branch}
+	if err := recordHead(client, opts.Dir, result); err != nil {
+		return nil, err
+	}
+	if result.Branch == "" {
+		result.Branch, _ = client.CurrentBranch(opts.Dir)
+	}
+	return result, nil
+}
+
+// pinnedRef is a tag or commit SHA that is checked out on a detached HEAD
+type pinnedRef struct {
+	Name string // As written in the config
+	Tag  bool
+}
+
+// resolveRef decides whether opts.Ref names a remote branch, in which case
+// it becomes opts.Branch, or a tag or commit to pin the checkout to
+func resolveRef(client Client, opts *SyncOptions) (*pinnedRef, error) {
+	if opts.Ref == "" {
+		return nil, nil
+	}
+
+	refs, err := client.LsRemote(opts.URL, "refs/heads/"+opts.Ref, "refs/tags/"+opts.Ref)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		if ref.Name == "refs/heads/"+opts.Ref {
+			opts.Branch = opts.Ref
+			return nil, nil
+		}
+	}
+	for _, ref := range refs {
+		if ref.Name == "refs/tags/"+opts.Ref {
+			return &pinnedRef{Name: opts.Ref, Tag: true}, nil
+		}
+	}
+	if !isHexSHA(opts.Ref) {
+		return nil, fmt.Errorf("%w: %s is not a branch or tag on %s, nor a commit SHA", ErrBranchNotFound, opts.Ref, opts.URL)
+	}
+	return &pinnedRef{Name: opts.Ref}, nil
+}
+
+func clonePinned(client Client, opts SyncOptions, pin *pinnedRef) (*SyncResult, error) {
+	// Tags can be cloned directly, commits need the default branch first
+	branch := ""
+	if pin.Tag {
+		branch = pin.Name
+	}
+	if err := client.Clone(CloneOptions{URL: opts.URL, Dir: opts.Dir, Branch: branch, Reference: opts.Reference, Tuning: opts.Tuning}); err != nil {
+		return nil, err
+	}
+
+	result, err := checkoutPinned(client, opts, pin)
+	if err != nil {
+		return nil, err
+	}
+	result.Cloned = true
+	return result, nil
+}
+
+// checkoutPinned fetches the tag or commit when it is not already present
+// and checks it out detached
+func checkoutPinned(client Client, opts SyncOptions, pin *pinnedRef) (*SyncResult, error) {
+	target := pin.Name
+	if pin.Tag {
+		target = "refs/tags/" + pin.Name
+		refspec := fmt.Sprintf("+%s:%s", target, target)
+		if err := client.Fetch(opts.Dir, FetchOptions{Refspecs: []string{refspec}}); err != nil {
+			return nil, err
+		}
+	} else if _, err := client.RevParse(opts.Dir, pin.Name); err != nil {
+		if err := fetchCommit(client, opts.Dir, pin.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	sha, err := client.RevParse(opts.Dir, target)
+	if err != nil {
+		return nil, fmt.Errorf("ref %s was not found in %s: %w", pin.Name, opts.URL, err)
+	}
+	if err := client.Checkout(opts.Dir, sha); err != nil {
+		return nil, err
+	}
+	if err := updateExtras(client, opts); err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{Ref: pin.Name}
+	if err := recordHead(client, opts.Dir, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fetchCommit brings a commit into the clone. Full SHAs are asked for
+// directly, abbreviated ones can only be found by fetching every branch and tag.
+func fetchCommit(client Client, dir string, sha string) error {
+	if len(sha) == 40 {
+		if err := client.Fetch(dir, FetchOptions{Refspecs: []string{sha}}); err == nil {
+			return nil
+		}
+	}
+	return client.Fetch(dir, FetchOptions{Refspecs: []string{
+		"+refs/heads/*:refs/remotes/origin/*",
+		"+refs/tags/*:refs/tags/*",
+	}})
+}
+
+// updateExtras brings submodules and LFS objects in line with the checkout
+func updateExtras(client Client, opts SyncOptions) error {
+	if opts.RecurseSubmodules {
+		if err := client.UpdateSubmodules(opts.Dir, opts.Depth); err != nil {
+			return err
+		}
+	}
+	if opts.LFS == "pull" {
+		if err := client.LFSPull(opts.Dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordHead fills in NewCommit and Commit from the checked out HEAD
+func recordHead(client Client, dir string, result *SyncResult) error {
+	commits, err := client.Log(dir, "HEAD", 1)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("%s has no commits", dir)
+	}
+	result.Commit = commits[0]
+	result.NewCommit = commits[0].SHA
+	return nil
+}
+
+func isHexSHA(ref string) bool {
+	if len(ref) < 4 || len(ref) > 40 {
+		return false
+	}
+	for _, r := range ref {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// SameRemote compares two remote URLs ignoring case, credentials, a
+// trailing slash and a .git suffix
+func SameRemote(a string, b string) bool {
+	normalize := func(url string) string {
+		if parsed, err := ParseRepoURL(url); err == nil {
+			return strings.ToLower(parsed.Host + "/" + parsed.Path)
+		}
+		url = strings.TrimSuffix(strings.TrimSpace(url), "/")
+		url = strings.TrimSuffix(url, ".git")
+		return strings.ToLower(url)
+	}
+	return normalize(a) == normalize(b)
+}
+
+// originOf returns the origin URL of the clone in dir. A directory on disk
+// without its own .git is never a clone, even when nested in another repository.
+func originOf(client Client, dir string) (string, error) {
+	if _, err := os.Stat(dir); err == nil {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+			return "", fmt.Errorf("%s is not a git clone", dir)
+		}
+	}
+	return client.RemoteURL(dir, "origin")
+}
+
+func isEmptyOrMissing(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return true
+	}
+	return err == nil && len(entries) == 0
+}
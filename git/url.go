@@ -0,0 +1,22 @@
+package git
+
+import "automateLife/provider"
+
+// RepoURL is a repository URL broken into its parts
+type RepoURL = provider.URL
+
+// ParseRepoURL parses HTTPS, SSH, scp-style (git@host:org/repo.git),
+// git://, file:// URLs and plain local paths, including Azure DevOps
+// "_git" and "v3" forms
+func ParseRepoURL(raw string) (*RepoURL, error) {
+	return provider.ParseURL(raw)
+}
+
+// GetProjectDirName returns the directory name git would clone repoUrl into
+func GetProjectDirName(repoUrl string) string {
+	parsed, err := ParseRepoURL(repoUrl)
+	if err != nil {
+		return ""
+	}
+	return parsed.Name
+}
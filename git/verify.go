@@ -0,0 +1,75 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RemoteCheck is what VerifyRemote found on the remote
+type RemoteCheck struct {
+	DefaultBranch string // Branch the remote HEAD points to, empty for empty repositories
+	Ref           string // Branch, tag or commit a clone would check out
+	Kind          string // "branch", "tag" or "commit"
+	SHA           string // Commit Ref points to, empty for commit SHAs that are not a branch or tag tip
+}
+
+// VerifyRemote lists the remote with the client's credentials to confirm
+// the repository is reachable and readable, and that ref (or branch when
+// ref is empty) exists. An empty branch stands for the default branch.
+func VerifyRemote(client Client, url string, branch string, ref string) (*RemoteCheck, error) {
+	name := ref
+	if name == "" {
+		name = branch
+	}
+
+	patterns := []string{"HEAD"}
+	if name != "" {
+		patterns = append(patterns, "refs/heads/"+name, "refs/tags/"+name, "refs/tags/"+name+"^{}")
+	}
+	refs, err := client.LsRemote(url, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &RemoteCheck{}
+	shas := map[string]string{}
+	for _, r := range refs {
+		if r.Name == "HEAD" && r.Target != "" {
+			check.DefaultBranch = strings.TrimPrefix(r.Target, "refs/heads/")
+		}
+		shas[r.Name] = r.SHA
+	}
+
+	if name == "" {
+		if check.DefaultBranch == "" {
+			return nil, fmt.Errorf("%w: %s has no default branch, it may be empty; set git.branch", ErrBranchNotFound, url)
+		}
+		check.Ref, check.Kind, check.SHA = check.DefaultBranch, "branch", shas["HEAD"]
+		return check, nil
+	}
+	check.Ref = name
+
+	switch {
+	case shas["refs/heads/"+name] != "":
+		check.Kind, check.SHA = "branch", shas["refs/heads/"+name]
+	case ref != "" && shas["refs/tags/"+name] != "":
+		// Annotated tags are listed with the commit they point to as well
+		check.Kind, check.SHA = "tag", shas["refs/tags/"+name]
+		if peeled := shas["refs/tags/"+name+"^{}"]; peeled != "" {
+			check.SHA = peeled
+		}
+	case ref != "" && isHexSHA(ref):
+		// Servers don't advertise arbitrary commits, so this is only checked when cloning
+		check.Kind = "commit"
+		for _, r := range refs {
+			if r.SHA == ref {
+				check.SHA = ref
+			}
+		}
+	case ref != "":
+		return nil, fmt.Errorf("%w: %s is not a branch or tag on %s, nor a commit SHA", ErrBranchNotFound, ref, url)
+	default:
+		return nil, fmt.Errorf("%w: branch %s does not exist on %s", ErrBranchNotFound, name, url)
+	}
+	return check, nil
+}
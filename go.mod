@@ -2,8 +2,12 @@ module automateLife
 
 go 1.25.3
 
+require (
+	github.com/manifoldco/promptui v0.9.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
 require (
 	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
-	github.com/manifoldco/promptui v0.9.0 // indirect
 	golang.org/x/sys v0.0.0-20181122145206-62eef0e2fa9b // indirect
 )
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"automateLife/state"
+	"automateLife/ui"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Exit codes git bisect run understands
+const (
+	bisectGood  = 0
+	bisectBad   = 1
+	bisectSkip  = 125
+	bisectAbort = 128
+)
+
+func HandleBisect(fileName string, args []string) {
+	flags := flag.NewFlagSet("bisect", flag.ContinueOnError)
+	goodFlag := flags.String("good", "", "a commit, tag or branch where the tests pass")
+	badFlag := flags.String("bad", "HEAD", "a commit, tag or branch where the tests fail")
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+	if *goodFlag == "" {
+		fmt.Println("Usage: automateLife bisect --good <ref> [--bad <ref>]")
+		return
+	}
+
+	cfg, err := config.Load(fileName)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to load config: %v", err))
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		ui.Error(fmt.Sprintf("Configuration validation failed: %v", err))
+		return
+	}
+	cloneDir, err := state.CloneDir(fileName, &cfg.Git)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	if _, err := os.Stat(cloneDir); os.IsNotExist(err) {
+		ui.Error(fmt.Sprintf("Project directory '%s' not found. Run 'automateLife start' first.", cloneDir))
+		return
+	}
+
+	client, err := git.NewExecClient(&cfg.Git)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	client.Stdout = os.Stdout
+	client.Stderr = os.Stderr
+
+	// Bisecting checks out other commits, which would carry local changes along
+	if dirty, err := client.IsDirty(cloneDir); err != nil {
+		ui.Error(err.Error())
+		return
+	} else if dirty {
+		ui.Error(fmt.Sprintf("%v in %s, commit or stash them first", git.ErrDirtyWorkTree, cloneDir))
+		return
+	}
+	for _, ref := range []string{*goodFlag, *badFlag} {
+		if _, err := client.RevParse(cloneDir, ref); err != nil {
+			ui.Error(fmt.Sprintf("%s is not a commit in %s, run 'automateLife start' to fetch it or use origin/<branch>", ref, cloneDir))
+			return
+		}
+	}
+
+	// Each step runs this binary again, so it installs and tests exactly like 'test'
+	executable, err := os.Executable()
+	if err != nil {
+		ui.Error(fmt.Sprintf("Could not locate the automateLife binary: %v", err))
+		return
+	}
+	configPath, err := filepath.Abs(fileName)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+
+	ui.Info(fmt.Sprintf("Bisecting %s between %s (good) and %s (bad)", cloneDir, *goodFlag, *badFlag))
+	sha, err := client.Bisect(cloneDir, *goodFlag, *badFlag, []string{executable, "bisect-step", configPath})
+	if err != nil {
+		ui.Error(fmt.Sprintf("Bisect failed: %v", err))
+		return
+	}
+
+	commits, err := client.Log(cloneDir, sha, 1)
+	if err != nil || len(commits) == 0 {
+		ui.Success(fmt.Sprintf("First bad commit: %s", sha))
+		return
+	}
+	commit := commits[0]
+	fmt.Println()
+	ui.Success(fmt.Sprintf("First bad commit: %s", commit.SHA))
+	fmt.Printf("  Author:  %s <%s>\n", commit.Author, commit.Email)
+	fmt.Printf("  Date:    %s\n", commit.Date.Format("2006-01-02 15:04"))
+	fmt.Printf("  Subject: %s\n", commit.Subject)
+}
+
+// HandleBisectStep runs the install, build and test steps on the commit git
+// bisect checked out in the working directory and returns the exit code
+// telling git bisect run whether it is good, bad or has to be skipped
+func HandleBisectStep(fileName string) int {
+	cfg, err := config.Load(fileName)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to load config: %v", err))
+		return bisectAbort
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		ui.Error(err.Error())
+		return bisectAbort
+	}
+
+	for key, value := range cfg.Environment.Variables {
+		os.Setenv(key, value)
+	}
+	sha := ""
+	if client, err := git.NewExecClient(nil); err == nil {
+		if commits, err := client.Log(dir, "HEAD", 1); err == nil && len(commits) > 0 {
+			commit := commits[0]
+			sha = commit.SHA
+			fmt.Printf("\n%s%s=== Bisect: testing %s %s ===%s\n\n", ui.Bold, ui.Blue, commit.ShortSHA(), commit.Subject, ui.Reset)
+			for key, value := range commit.Env(commit.SHA) {
+				os.Setenv(key, value)
+			}
+		}
+	}
+
+	run := startRun(fileName, cfg, "bisect", sha)
+	result := runSteps(cfg, dir, run)
+	defer finishRun(run, result.String())
+
+	switch result {
+	case stepsPassed:
+		return bisectGood
+	case stepsFailed:
+		return bisectBad
+	case stepsInterrupted:
+		return bisectAbort
+	}
+	ui.Warning("Skipping this commit, its tests could not be run")
+	return bisectSkip
+}
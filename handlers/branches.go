@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"automateLife/state"
+	"automateLife/ui"
+	"automateLife/workspace"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// testBranches runs install, build and test for every branch at once, each in its
+// own worktree of the clone, and compares the results
+func testBranches(fileName string, branches []string, jobs int) {
+	cfg, err := config.Load(fileName)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to load config: %v", err))
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		ui.Error(fmt.Sprintf("Configuration validation failed: %v", err))
+		return
+	}
+	cloneDir, err := state.CloneDir(fileName, &cfg.Git)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	if _, err := os.Stat(cloneDir); os.IsNotExist(err) {
+		ui.Error(fmt.Sprintf("Project directory '%s' not found. Run 'automateLife start' first.", cloneDir))
+		return
+	}
+
+	if err := unlockSSHKey(&cfg.Git); err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	client, err := git.NewExecClient(&cfg.Git)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+
+	ui.Info(fmt.Sprintf("Fetching %s", strings.Join(branches, ", ")))
+	commits := make([]string, len(branches))
+	revs := make([]string, len(branches)) // What each commit was found as
+	for i, branch := range branches {
+		refspec := fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branch, branch)
+		if err := client.Fetch(cloneDir, git.FetchOptions{Refspecs: []string{refspec}}); err != nil {
+			ui.Warning(fmt.Sprintf("Could not fetch %s, testing the clone's copy: %v", branch, err))
+		}
+		// Prefer the remote branch, then anything else git understands such as a tag
+		for _, rev := range []string{"origin/" + branch, branch} {
+			if commits[i], err = client.RevParse(cloneDir, rev); err == nil {
+				revs[i] = rev
+				break
+			}
+		}
+		if commits[i] == "" {
+			ui.Error(fmt.Sprintf("%s is not a branch, tag or commit in %s", branch, cloneDir))
+			return
+		}
+	}
+
+	root := filepath.Join(state.Dir(fileName), "worktrees")
+	repos := make([]workspace.Repo, 0, len(branches))
+	defer func() {
+		for _, repo := range repos {
+			if err := client.RemoveWorktree(cloneDir, repo.Dir); err != nil {
+				ui.Warning(fmt.Sprintf("Could not remove the worktree of %s: %v", repo.Name, err))
+			}
+		}
+		os.Remove(root)
+	}()
+	used := map[string]bool{}
+	var tested []string // Commits of repos
+	var refused []int   // Branches whose commit is not signed by a trusted key
+	for i, branch := range branches {
+		if cfg.Git.RequireSigned && !verifySigned(&cfg.Git, client, cloneDir, branchSignedRevision(client, cloneDir, revs[i])) {
+			refused = append(refused, i)
+			continue
+		}
+
+		name := strings.NewReplacer("/", "-", "\\", "-", ":", "-").Replace(branch)
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d", name, n)
+		}
+		used[name] = true
+
+		path := filepath.Join(root, name)
+		if _, err := os.Stat(path); err == nil {
+			// Left behind by an interrupted run
+			client.RemoveWorktree(cloneDir, path)
+			os.RemoveAll(path)
+		}
+		if err := client.Worktree(cloneDir, path, commits[i]); err != nil {
+			ui.Error(fmt.Sprintf("Could not create a worktree for %s: %v", branch, err))
+			return
+		}
+		// The worktree is detached, the branch is what its commit is reported as
+		gitCfg := cfg.Git
+		gitCfg.Ref = branch
+		repos = append(repos, workspace.Repo{Name: branch, Dir: path, Git: gitCfg, Build: cfg.Build})
+		tested = append(tested, commits[i])
+	}
+	if len(repos) == 0 {
+		ui.Error("None of the branches is signed by a trusted key, nothing to test")
+		return
+	}
+
+	for key, value := range cfg.Environment.Variables {
+		os.Setenv(key, value)
+	}
+
+	ui.Info(fmt.Sprintf("Testing %d branches, %d at a time", len(repos), min(jobs, len(repos))))
+	run := startRun(fileName, cfg, "test --branches", "")
+	statuses, err := workspace.Pipeline(repos, workspace.Stages, jobs, os.Stdout, run)
+	if err != nil {
+		finishRun(run, stepsSkipped.String())
+		ui.Error(err.Error())
+		return
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "\nBRANCH\tCOMMIT\tRESULT\tTIME")
+	failed := len(refused)
+	for i, status := range statuses {
+		result := "passed"
+		if status.Err != nil {
+			result = fmt.Sprintf("failed (%s)", status.Stage)
+			failed++
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", status.Repo.Name, shortSHA(tested[i]), result, status.Duration.Round(time.Millisecond))
+	}
+	for _, i := range refused {
+		fmt.Fprintf(table, "%s\t%s\t%s\t\n", branches[i], shortSHA(commits[i]), "refused (signature)")
+	}
+	table.Flush()
+
+	if failed > 0 {
+		ui.Error(fmt.Sprintf("Tests failed on %d of %d branches", failed, len(branches)))
+		finishRun(run, stepsFailed.String())
+		return
+	}
+	ui.Success(fmt.Sprintf("Tests passed on all %d branches", len(statuses)))
+	finishRun(run, stepsPassed.String())
+}
+
+// branchSignedRevision picks what to verify for a branch found as rev: a
+// tag named on the command line is verified rather than its commit
+func branchSignedRevision(client git.Client, dir string, rev string) string {
+	if strings.HasPrefix(rev, "origin/") {
+		return rev
+	}
+	if _, err := client.RevParse(dir, "refs/tags/"+rev); err == nil {
+		return "refs/tags/" + rev
+	}
+	return rev
+}
+
+// splitBranches parses the comma separated --branches list, dropping
+// duplicates
+func splitBranches(value string) []string {
+	seen := map[string]bool{}
+	var branches []string
+	for _, branch := range splitList(value) {
+		if !seen[branch] {
+			seen[branch] = true
+			branches = append(branches, branch)
+		}
+	}
+	return branches
+}
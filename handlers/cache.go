@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"automateLife/git"
+	"automateLife/ui"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func HandleCache(args []string) {
+	if len(args) > 0 && args[0] == "prune" {
+		HandleCachePrune(args[1:])
+		return
+	}
+	if len(args) > 0 {
+		fmt.Println("Usage: automateLife cache [prune [--max-age 30d] [--max-size 5GB]]")
+		return
+	}
+
+	cache, client, ok := openCache()
+	if !ok {
+		return
+	}
+	entries, err := cache.Entries(client)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		ui.Info(fmt.Sprintf("The clone cache at %s is empty", cache.Dir))
+		return
+	}
+
+	var total int64
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "REPOSITORY\tSIZE\tLAST USED")
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		total += entry.Size
+		url := entry.URL
+		if url == "" {
+			url = entry.Path
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", url, formatSize(entry.Size), entry.LastUsed.Format("2006-01-02 15:04"))
+	}
+	table.Flush()
+	ui.Info(fmt.Sprintf("%d mirrors, %s in %s", len(entries), formatSize(total), cache.Dir))
+}
+
+func HandleCachePrune(args []string) {
+	flags := flag.NewFlagSet("cache prune", flag.ContinueOnError)
+	maxAgeFlag := flags.String("max-age", "30d", "remove mirrors not used for this long (e.g. 30d, 12h), 0 to keep all")
+	maxSizeFlag := flags.String("max-size", "0", "then remove the least recently used mirrors until the cache fits (e.g. 5GB), 0 for no limit")
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+
+	maxAge, err := parseAge(*maxAgeFlag)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	maxSize, err := parseSize(*maxSizeFlag)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+
+	cache, client, ok := openCache()
+	if !ok {
+		return
+	}
+	removed, err := cache.Prune(client, maxAge, maxSize)
+	var freed int64
+	for _, entry := range removed {
+		freed += entry.Size
+		fmt.Printf("  Removed %s (%s, last used %s)\n", entry.Path, formatSize(entry.Size), entry.LastUsed.Format("2006-01-02"))
+	}
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	ui.Success(fmt.Sprintf("Pruned %d mirrors, freed %s", len(removed), formatSize(freed)))
+}
+
+func openCache() (*git.Cache, git.Client, bool) {
+	dir, err := git.DefaultCacheDir()
+	if err != nil {
+		ui.Error(err.Error())
+		return nil, nil, false
+	}
+	client, err := git.NewExecClient(nil)
+	if err != nil {
+		ui.Error(err.Error())
+		return nil, nil, false
+	}
+	return git.NewCache(dir), client, true
+}
+
+// cachedReference brings the cached mirror of repoUrl up to date when a new
+// clone is about to be made into dir and returns its path. The cache only
+// saves time, so problems with it are warnings.
+func cachedReference(client git.Client, repoUrl string, dir string) string {
+	cacheDir, err := git.DefaultCacheDir()
+	if err != nil {
+		ui.Warning(fmt.Sprintf("Cloning without the cache: %v", err))
+		return ""
+	}
+	path, err := git.NewCache(cacheDir).ReferenceFor(client, repoUrl, dir)
+	if err != nil {
+		ui.Warning(fmt.Sprintf("Cloning without the cache: %v", err))
+		return ""
+	}
+	if path != "" {
+		ui.Info(fmt.Sprintf("Cloning from the cached mirror %s", path))
+	}
+	return path
+}
+
+// parseAge understands Go durations plus a d suffix for days
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q, use e.g. 30d or 12h", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q, use e.g. 30d or 12h", value)
+	}
+	return age, nil
+}
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+}
+
+// parseSize understands byte counts with an optional KB, MB, GB or TB suffix
+func parseSize(value string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if number, ok := strings.CutSuffix(upper, unit.suffix); ok {
+			upper, multiplier = strings.TrimSpace(number), unit.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(upper, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, use e.g. 500MB or 5GB", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+func formatSize(size int64) string {
+	for _, unit := range sizeUnits {
+		if size >= unit.bytes && unit.bytes > 1 {
+			return fmt.Sprintf("%.1f %s", float64(size)/float64(unit.bytes), unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
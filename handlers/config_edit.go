@@ -0,0 +1,484 @@
+package handlers
+
+import (
+	"automateLife/config"
+	"automateLife/detect"
+	"automateLife/ui"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+// editableField describes one config value the editor can prompt for.
+// Key matches the field name reported by config.ValidateAll.
+type editableField struct {
+	Key     string
+	Section string
+	Label   string
+	Options []string
+	Secret  bool
+	Get     func(cfg *config.Config) string
+	Set     func(cfg *config.Config, value string)
+	Visible func(cfg *config.Config) bool
+}
+
+var configSections = []string{"Project", "Git", "Clone", "Build", "Logs", "Azure"}
+
+func authTypeIs(authType string) func(cfg *config.Config) bool {
+	return func(cfg *config.Config) bool {
+		return cfg.Git.AuthType == authType
+	}
+}
+
+var editableFields = []editableField{
+	{
+		Key: "project.name", Section: "Project", Label: "Project Name",
+		Get: func(c *config.Config) string { return c.Project.Name },
+		Set: func(c *config.Config, v string) { c.Project.Name = v },
+	},
+	{
+		Key: "project.type", Section: "Project", Label: "Project Type",
+		Options: []string{"backend", "frontend", "fullstack", "cli", "library"},
+		Get:     func(c *config.Config) string { return c.Project.Type },
+		Set:     func(c *config.Config, v string) { c.Project.Type = v },
+	},
+	{
+		Key: "project.description", Section: "Project", Label: "Project Description",
+		Get: func(c *config.Config) string { return c.Project.Description },
+		Set: func(c *config.Config, v string) { c.Project.Description = v },
+	},
+	{
+		Key: "git.provider", Section: "Git", Label: "Git Provider",
+		Options: []string{"github", "gitlab", "bitbucket", "azure-devops"},
+		Get:     func(c *config.Config) string { return c.Git.Provider },
+		Set:     func(c *config.Config, v string) { c.Git.Provider = v },
+	},
+	{
+		Key: "git.repo_url", Section: "Git", Label: "Git Repository URL",
+		Get: func(c *config.Config) string { return c.Git.RepoUrl },
+		Set: func(c *config.Config, v string) { c.Git.RepoUrl = v },
+	},
+	{
+		Key: "git.branch", Section: "Git", Label: "Git Branch",
+		Get: func(c *config.Config) string { return c.Git.Branch },
+		Set: func(c *config.Config, v string) { c.Git.Branch = v },
+	},
+	{
+		Key: "git.ref", Section: "Git", Label: "Git Ref (branch, tag or commit SHA, overrides branch)",
+		Get: func(c *config.Config) string { return c.Git.Ref },
+		Set: func(c *config.Config, v string) { c.Git.Ref = v },
+	},
+	{
+		Key: "git.auth_type", Section: "Git", Label: "Git Authentication Type",
+		Options: []string{"token", "basic", "ssh"},
+		Get:     func(c *config.Config) string { return c.Git.AuthType },
+		Set:     func(c *config.Config, v string) { c.Git.AuthType = v },
+	},
+	{
+		Key: "git.token", Section: "Git", Label: "Git Token", Secret: true,
+		Get:     func(c *config.Config) string { return c.Git.Token },
+		Set:     func(c *config.Config, v string) { c.Git.Token = v },
+		Visible: authTypeIs("token"),
+	},
+	{
+		Key: "git.username", Section: "Git", Label: "Git Username",
+		Get:     func(c *config.Config) string { return c.Git.UserName },
+		Set:     func(c *config.Config, v string) { c.Git.UserName = v },
+		Visible: authTypeIs("basic"),
+	},
+	{
+		Key: "git.password", Section: "Git", Label: "Git Password", Secret: true,
+		Get:     func(c *config.Config) string { return c.Git.Password },
+		Set:     func(c *config.Config, v string) { c.Git.Password = v },
+		Visible: authTypeIs("basic"),
+	},
+	{
+		Key: "git.ssh_key_path", Section: "Git", Label: "SSH Key Path",
+		Get:     func(c *config.Config) string { return c.Git.SSHKeyPath },
+		Set:     func(c *config.Config, v string) { c.Git.SSHKeyPath = v },
+		Visible: authTypeIs("ssh"),
+	},
+	{
+		Key: "git.ssh_agent", Section: "Git", Label: "Use ssh-agent",
+		Options: []string{"false", "true"},
+		Get:     func(c *config.Config) string { return strconv.FormatBool(c.Git.SSHAgent) },
+		Set:     func(c *config.Config, v string) { c.Git.SSHAgent = v == "true" },
+		Visible: authTypeIs("ssh"),
+	},
+	{
+		Key: "git.ssh_passphrase", Section: "Git", Label: "SSH Key Passphrase (e.g. $SSH_KEY_PASSPHRASE, empty to be asked)", Secret: true,
+		Get:     func(c *config.Config) string { return c.Git.SSHPassphrase },
+		Set:     func(c *config.Config, v string) { c.Git.SSHPassphrase = v },
+		Visible: authTypeIs("ssh"),
+	},
+	{
+		Key: "git.ssh_host_fingerprints", Section: "Git", Label: "Pinned SSH Host Fingerprints (comma separated SHA256:...)",
+		Get:     func(c *config.Config) string { return strings.Join(c.Git.SSHHostFingerprints, ",") },
+		Set:     func(c *config.Config, v string) { c.Git.SSHHostFingerprints = splitList(v) },
+		Visible: authTypeIs("ssh"),
+	},
+	{
+		Key: "git.clone_dir", Section: "Git", Label: "Clone Directory (empty for the repository name)",
+		Get: func(c *config.Config) string { return c.Git.CloneDir },
+		Set: func(c *config.Config, v string) { c.Git.CloneDir = v },
+	},
+	{
+		Key: "git.require_signed", Section: "Git", Label: "Require Signed Commits or Tags",
+		Options: []string{"false", "true"},
+		Get:     func(c *config.Config) string { return strconv.FormatBool(c.Git.RequireSigned) },
+		Set:     func(c *config.Config, v string) { c.Git.RequireSigned = v == "true" },
+	},
+	{
+		Key: "git.allowed_signers", Section: "Git", Label: "SSH Allowed Signers File",
+		Get:     func(c *config.Config) string { return c.Git.AllowedSigners },
+		Set:     func(c *config.Config, v string) { c.Git.AllowedSigners = v },
+		Visible: func(c *config.Config) bool { return c.Git.RequireSigned },
+	},
+	{
+		Key: "git.gpg_keyring", Section: "Git", Label: "GPG Keyring (GnuPG home or exported public keys)",
+		Get:     func(c *config.Config) string { return c.Git.GPGKeyring },
+		Set:     func(c *config.Config, v string) { c.Git.GPGKeyring = v },
+		Visible: func(c *config.Config) bool { return c.Git.RequireSigned },
+	},
+	{
+		Key: "git.depth", Section: "Clone", Label: "Clone Depth (0 for full history)",
+		Get: func(c *config.Config) string { return strconv.Itoa(c.Git.Depth) },
+		Set: func(c *config.Config, v string) {
+			// Anything that is not a number is kept as -1 so the validator reopens it
+			depth, err := strconv.Atoi(v)
+			if err != nil {
+				depth = -1
+			}
+			c.Git.Depth = depth
+		},
+	},
+	{
+		Key: "git.single_branch", Section: "Clone", Label: "Single Branch",
+		Options: []string{"false", "true"},
+		Get:     func(c *config.Config) string { return strconv.FormatBool(c.Git.SingleBranch) },
+		Set:     func(c *config.Config, v string) { c.Git.SingleBranch = v == "true" },
+	},
+	{
+		Key: "git.sparse_paths", Section: "Clone", Label: "Sparse Checkout Paths (comma separated, empty for all)",
+		Get: func(c *config.Config) string { return strings.Join(c.Git.SparsePaths, ",") },
+		Set: func(c *config.Config, v string) { c.Git.SparsePaths = splitList(v) },
+	},
+	{
+		Key: "git.recurse_submodules", Section: "Clone", Label: "Recurse Submodules",
+		Options: []string{"false", "true"},
+		Get:     func(c *config.Config) string { return strconv.FormatBool(c.Git.RecurseSubmodules) },
+		Set:     func(c *config.Config, v string) { c.Git.RecurseSubmodules = v == "true" },
+	},
+	{
+		Key: "git.lfs", Section: "Clone", Label: "Git LFS (empty, pull or skip)",
+		Get: func(c *config.Config) string { return c.Git.LFS },
+		Set: func(c *config.Config, v string) { c.Git.LFS = v },
+	},
+	{
+		Key: "git.cache", Section: "Clone", Label: "Use the Shared Clone Cache",
+		Options: []string{"false", "true"},
+		Get:     func(c *config.Config) string { return strconv.FormatBool(c.Git.Cache) },
+		Set:     func(c *config.Config, v string) { c.Git.Cache = v == "true" },
+	},
+	{
+		Key: "build.language", Section: "Build", Label: "Project Language",
+		Options: detect.Languages(),
+		Get:     func(c *config.Config) string { return c.Build.Language },
+		Set:     func(c *config.Config, v string) { c.Build.Language = v },
+	},
+	{
+		Key: "build.install_command", Section: "Build", Label: "Install Command",
+		Get: func(c *config.Config) string { return c.Build.InstallCommand },
+		Set: func(c *config.Config, v string) { c.Build.InstallCommand = v },
+	},
+	{
+		Key: "build.build_command", Section: "Build", Label: "Build Command",
+		Get: func(c *config.Config) string { return c.Build.BuildCommand },
+		Set: func(c *config.Config, v string) { c.Build.BuildCommand = v },
+	},
+	{
+		Key: "build.test_command", Section: "Build", Label: "Test Command",
+		Get: func(c *config.Config) string { return c.Build.TestCommand },
+		Set: func(c *config.Config, v string) { c.Build.TestCommand = v },
+	},
+	{
+		Key: "build.shell", Section: "Build", Label: "Run Commands with /bin/sh",
+		Options: []string{"false", "true"},
+		Get:     func(c *config.Config) string { return strconv.FormatBool(c.Build.Shell) },
+		Set:     func(c *config.Config, v string) { c.Build.Shell = v == "true" },
+	},
+	{
+		Key: "build.timeouts.install", Section: "Build", Label: "Install Timeout (e.g. 10m, empty for none)",
+		Get: func(c *config.Config) string { return c.Build.Timeouts.Install },
+		Set: func(c *config.Config, v string) { c.Build.Timeouts.Install = v },
+	},
+	{
+		Key: "build.timeouts.build", Section: "Build", Label: "Build Timeout",
+		Get: func(c *config.Config) string { return c.Build.Timeouts.Build },
+		Set: func(c *config.Config, v string) { c.Build.Timeouts.Build = v },
+	},
+	{
+		Key: "build.timeouts.test", Section: "Build", Label: "Test Timeout",
+		Get: func(c *config.Config) string { return c.Build.Timeouts.Test },
+		Set: func(c *config.Config, v string) { c.Build.Timeouts.Test = v },
+	},
+	{
+		Key: "build.timeouts.pipeline", Section: "Build", Label: "Pipeline Timeout",
+		Get: func(c *config.Config) string { return c.Build.Timeouts.Pipeline },
+		Set: func(c *config.Config, v string) { c.Build.Timeouts.Pipeline = v },
+	},
+	{
+		Key: "build.timeouts.grace_period", Section: "Build", Label: "Grace Period before SIGKILL (default 10s)",
+		Get: func(c *config.Config) string { return c.Build.Timeouts.GracePeriod },
+		Set: func(c *config.Config, v string) { c.Build.Timeouts.GracePeriod = v },
+	},
+	{
+		Key: "build.output_dir", Section: "Build", Label: "Output Directory",
+		Get: func(c *config.Config) string { return c.Build.OutputDir },
+		Set: func(c *config.Config, v string) { c.Build.OutputDir = v },
+	},
+	{
+		Key: "logs.timestamps", Section: "Logs", Label: "Timestamp Every Log Line",
+		Options: []string{"false", "true"},
+		Get:     func(c *config.Config) string { return strconv.FormatBool(c.Logs.Timestamps) },
+		Set:     func(c *config.Config, v string) { c.Logs.Timestamps = v == "true" },
+	},
+	{
+		Key: "logs.streams", Section: "Logs", Label: "Tag Log Lines with out or err",
+		Options: []string{"false", "true"},
+		Get:     func(c *config.Config) string { return strconv.FormatBool(c.Logs.Streams) },
+		Set:     func(c *config.Config, v string) { c.Logs.Streams = v == "true" },
+	},
+	{
+		Key: "logs.keep", Section: "Logs", Label: "Runs to Keep (0 for 20)",
+		Get: func(c *config.Config) string { return strconv.Itoa(c.Logs.Keep) },
+		Set: func(c *config.Config, v string) {
+			// Anything that is not a number is kept as -1 so the validator reopens it
+			keep, err := strconv.Atoi(v)
+			if err != nil {
+				keep = -1
+			}
+			c.Logs.Keep = keep
+		},
+	},
+	{
+		Key: "azure.deployment_type", Section: "Azure", Label: "Azure Deployment Type",
+		Options: []string{"webapp", "container", "function"},
+		Get:     func(c *config.Config) string { return c.Azure.DeploymentType },
+		Set:     func(c *config.Config, v string) { c.Azure.DeploymentType = v },
+	},
+	{
+		Key: "azure.app_name", Section: "Azure", Label: "Azure App Name",
+		Get: func(c *config.Config) string { return c.Azure.AppName },
+		Set: func(c *config.Config, v string) { c.Azure.AppName = v },
+	},
+	{
+		Key: "azure.resource_group", Section: "Azure", Label: "Azure Resource Group",
+		Get: func(c *config.Config) string { return c.Azure.ResourceGroup },
+		Set: func(c *config.Config, v string) { c.Azure.ResourceGroup = v },
+	},
+	{
+		Key: "azure.subscription_id", Section: "Azure", Label: "Azure Subscription ID",
+		Get: func(c *config.Config) string { return c.Azure.SubscriptionID },
+		Set: func(c *config.Config, v string) { c.Azure.SubscriptionID = v },
+	},
+	{
+		Key: "azure.region", Section: "Azure", Label: "Azure Region",
+		Get: func(c *config.Config) string { return c.Azure.Region },
+		Set: func(c *config.Config, v string) { c.Azure.Region = v },
+	},
+}
+
+func HandleConfig(fileName string, args []string) {
+	if len(args) == 0 || args[0] != "edit" {
+		fmt.Println("Usage: automateLife config edit [section]")
+		fmt.Printf("Sections: %s\n", strings.ToLower(strings.Join(configSections, ", ")))
+		return
+	}
+	HandleConfigEdit(fileName, args[1:])
+}
+
+func HandleConfigEdit(fileName string, args []string) {
+	// Read without expanding so $VAR references survive the round trip
+	cfg, err := config.Read(fileName)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to load config: %v", err))
+		fmt.Println("Please run 'automateLife init' to create a config file")
+		return
+	}
+
+	// Jump straight to a section when one was given
+	if len(args) > 0 {
+		section, ok := findSection(args[0])
+		if !ok {
+			ui.Error(fmt.Sprintf("Unknown section %q, expected one of: %s", args[0], strings.ToLower(strings.Join(configSections, ", "))))
+			return
+		}
+		if err := editSection(cfg, section); err != nil {
+			reportEditAborted(err)
+			return
+		}
+	}
+
+	for {
+		menu := promptui.Select{
+			Label: "Select a section to edit",
+			Items: append(append([]string{}, configSections...), "Save and exit", "Quit without saving"),
+		}
+		_, choice, err := menu.Run()
+		if err != nil {
+			reportEditAborted(err)
+			return
+		}
+
+		switch choice {
+		case "Quit without saving":
+			fmt.Println("No changes were saved")
+			return
+		case "Save and exit":
+			if err := fixInvalidFields(cfg); err != nil {
+				reportEditAborted(err)
+				return
+			}
+			if err := config.Save(fileName, cfg); err != nil {
+				ui.Error(err.Error())
+				return
+			}
+			ui.Success(fileName + " saved and validated successfully")
+			return
+		default:
+			if err := editSection(cfg, choice); err != nil {
+				reportEditAborted(err)
+				return
+			}
+		}
+	}
+}
+
+// fixInvalidFields runs the full validator and reopens only the fields
+// it complains about, looping until the config passes
+func fixInvalidFields(cfg *config.Config) error {
+	for {
+		errs := cfg.Expanded().ValidateAll()
+		if len(errs) == 0 {
+			return nil
+		}
+
+		ui.Warning(fmt.Sprintf("The config has %d problem(s):", len(errs)))
+		for _, fieldErr := range errs {
+			fmt.Printf("  - %s\n", fieldErr.Message)
+		}
+
+		reopened := map[string]bool{}
+		for _, fieldErr := range errs {
+			if reopened[fieldErr.Field] {
+				continue
+			}
+			reopened[fieldErr.Field] = true
+
+			field, ok := findField(fieldErr.Field)
+			if !ok {
+				return fmt.Errorf("%s cannot be edited here, please fix it in the config file", fieldErr.Field)
+			}
+			if err := promptField(cfg, field); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func editSection(cfg *config.Config, section string) error {
+	fmt.Printf("\n%s%s%s settings%s\n", ui.Bold, ui.Blue, section, ui.Reset)
+	for _, field := range editableFields {
+		if field.Section != section {
+			continue
+		}
+		if field.Visible != nil && !field.Visible(cfg) {
+			continue
+		}
+		if err := promptField(cfg, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// promptField asks for a new value using the current one as the default
+func promptField(cfg *config.Config, field editableField) error {
+	current := field.Get(cfg)
+
+	if len(field.Options) > 0 {
+		cursor := 0
+		for i, option := range field.Options {
+			if option == current {
+				cursor = i
+			}
+		}
+		prompt := promptui.Select{
+			Label:     field.Label,
+			Items:     field.Options,
+			CursorPos: cursor,
+		}
+		_, value, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		field.Set(cfg, value)
+		return nil
+	}
+
+	prompt := promptui.Prompt{
+		Label:     field.Label,
+		Default:   current,
+		AllowEdit: true,
+	}
+	if field.Secret {
+		prompt.Mask = '*'
+	}
+	value, err := prompt.Run()
+	if err != nil {
+		return err
+	}
+	field.Set(cfg, strings.TrimSpace(value))
+	return nil
+}
+
+// splitList turns "a, b,,c" into [a b c]
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func findSection(name string) (string, bool) {
+	for _, section := range configSections {
+		if strings.EqualFold(section, name) {
+			return section, true
+		}
+	}
+	return "", false
+}
+
+func findField(key string) (editableField, bool) {
+	for _, field := range editableFields {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	return editableField{}, false
+}
+
+func reportEditAborted(err error) {
+	if errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF) {
+		fmt.Println("\nEditing aborted, no changes were saved")
+		return
+	}
+	ui.Error(fmt.Sprintf("Editing failed: %v", err))
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// HandleCredential implements the git credential helper protocol, so git
+// can be pointed at the config for credentials:
+//
+//	git config credential.helper '!automateLife credential'
+//
+// Only "get" is answered; "store" and "erase" are accepted and ignored.
+// Nothing but protocol output may go to stdout.
+func HandleCredential(fileName string, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: automateLife credential <get|store|erase>")
+		os.Exit(1)
+	}
+	if args[0] != "get" {
+		return
+	}
+
+	cfg, err := config.Load(fileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "automateLife credential: failed to load config: %v\n", err)
+		return
+	}
+	if err := writeCredential(&cfg.Git, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "automateLife credential: %v\n", err)
+	}
+}
+
+// writeCredential answers a credential request read from in when it is for
+// the configured repository's host. Other hosts get no answer, so git falls
+// back to its next helper.
+func writeCredential(cfg *config.GitConfig, in io.Reader, out io.Writer) error {
+	request := map[string]string{}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			request[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read request: %w", err)
+	}
+
+	username, password, ok, err := git.CredentialsFor(cfg, request["protocol"], request["host"])
+	if err != nil || !ok {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "username=%s\npassword=%s\n", username, password)
+	return err
+}
@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"automateLife/builder"
+	"automateLife/config"
+	"automateLife/git"
+	"automateLife/hooks"
+	"automateLife/state"
+	"automateLife/ui"
+	"automateLife/workspace"
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// zeroSHA stands for a ref that doesn't exist in git's pre-push input
+var zeroSHA = strings.Repeat("0", 40)
+
+func HandleHooks(fileName string, args []string) {
+	if len(args) == 0 {
+		printHooksUsage()
+		return
+	}
+	switch args[0] {
+	case "install":
+		HandleHooksInstall(fileName, args[1:])
+	case "uninstall":
+		HandleHooksUninstall(fileName, args[1:])
+	case "status":
+		HandleHooksStatus(fileName, args[1:])
+	default:
+		printHooksUsage()
+	}
+}
+
+func printHooksUsage() {
+	fmt.Println("Usage: automateLife hooks install [--hooks pre-commit,pre-push] [--stages test] [--changed]")
+	fmt.Println("       automateLife hooks uninstall [--hooks pre-commit,pre-push]")
+	fmt.Println("       automateLife hooks status")
+}
+
+// openHooksDir loads the config and locates the hooks directory of the clone
+func openHooksDir(fileName string) (string, bool) {
+	cfg, err := config.Load(fileName)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to load config: %v", err))
+		return "", false
+	}
+	cloneDir, err := state.CloneDir(fileName, &cfg.Git)
+	if err != nil {
+		ui.Error(err.Error())
+		return "", false
+	}
+	if _, err := os.Stat(cloneDir); os.IsNotExist(err) {
+		ui.Error(fmt.Sprintf("Project directory '%s' not found. Run 'automateLife start' first.", cloneDir))
+		return "", false
+	}
+	client, err := git.NewExecClient(nil)
+	if err != nil {
+		ui.Error(err.Error())
+		return "", false
+	}
+	dir, err := hooks.Dir(client, cloneDir)
+	if err != nil {
+		ui.Error(err.Error())
+		return "", false
+	}
+	return dir, true
+}
+
+// hookNames parses the --hooks list
+func hookNames(value string) ([]string, bool) {
+	names := splitList(value)
+	if len(names) == 0 {
+		printHooksUsage()
+		return nil, false
+	}
+	for _, name := range names {
+		if !hooks.Known(name) {
+			ui.Error(fmt.Sprintf("Unknown hook %q, expected one of: %s", name, strings.Join(hooks.Names, ", ")))
+			return nil, false
+		}
+	}
+	return names, true
+}
+
+func HandleHooksInstall(fileName string, args []string) {
+	flags := flag.NewFlagSet("hooks install", flag.ContinueOnError)
+	hooksFlag := flags.String("hooks", strings.Join(hooks.Names, ","), "comma separated hooks to install")
+	stagesFlag := flags.String("stages", "test", fmt.Sprintf("comma separated stages the hooks run (%s)", strings.Join(workspace.Stages, ", ")))
+	changedFlag := flags.Bool("changed", false, "only test the packages the commit or push changes")
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+	names, ok := hookNames(*hooksFlag)
+	if !ok {
+		return
+	}
+	stages := splitList(*stagesFlag)
+	if len(stages) == 0 {
+		printHooksUsage()
+		return
+	}
+	for _, stage := range stages {
+		if _, err := (&workspace.Repo{}).StageCommand(stage); err != nil {
+			ui.Error(err.Error())
+			return
+		}
+	}
+
+	// The hooks run this binary with this config, wherever git is run from
+	executable, err := os.Executable()
+	if err != nil {
+		ui.Error(fmt.Sprintf("Could not locate the automateLife binary: %v", err))
+		return
+	}
+	configPath, err := filepath.Abs(fileName)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	dir, ok := openHooksDir(fileName)
+	if !ok {
+		return
+	}
+
+	opts := hooks.Options{Executable: executable, ConfigFile: configPath, Stages: stages, Changed: *changedFlag}
+	for _, name := range names {
+		status, err := hooks.Install(dir, name, opts)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		if status.Chained {
+			ui.Success(fmt.Sprintf("Installed %s, running after the existing %s hook", status.Path, name))
+		} else {
+			ui.Success(fmt.Sprintf("Installed %s", status.Path))
+		}
+	}
+}
+
+func HandleHooksUninstall(fileName string, args []string) {
+	flags := flag.NewFlagSet("hooks uninstall", flag.ContinueOnError)
+	hooksFlag := flags.String("hooks", strings.Join(hooks.Names, ","), "comma separated hooks to remove")
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+	names, ok := hookNames(*hooksFlag)
+	if !ok {
+		return
+	}
+	dir, ok := openHooksDir(fileName)
+	if !ok {
+		return
+	}
+
+	for _, name := range names {
+		before, err := hooks.Inspect(dir, name)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		after, err := hooks.Uninstall(dir, name)
+		switch {
+		case err != nil && after != nil && after.Foreign:
+			ui.Warning(fmt.Sprintf("Leaving %s alone, it was not installed by automateLife", after.Path))
+		case err != nil:
+			ui.Error(err.Error())
+			return
+		case !before.Installed:
+			ui.Info(fmt.Sprintf("No %s hook installed", name))
+		case before.Chained:
+			ui.Success(fmt.Sprintf("Removed %s and restored the previous hook", before.Path))
+		default:
+			ui.Success(fmt.Sprintf("Removed %s", before.Path))
+		}
+	}
+}
+
+func HandleHooksStatus(fileName string, args []string) {
+	if len(args) > 0 {
+		printHooksUsage()
+		return
+	}
+	dir, ok := openHooksDir(fileName)
+	if !ok {
+		return
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "HOOK\tSTATE\tSTAGES\tCHANGED ONLY\tCHAINED")
+	for _, name := range hooks.Names {
+		status, err := hooks.Inspect(dir, name)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		installed, stages, changed, chained := "not installed", "-", "-", "-"
+		switch {
+		case status.Installed:
+			installed = "installed"
+			stages = strings.Join(status.Options.Stages, ",")
+			changed = yesNo(status.Options.Changed)
+			chained = yesNo(status.Chained)
+		case status.Foreign:
+			installed = "other hook"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", name, installed, stages, changed, chained)
+	}
+	table.Flush()
+	ui.Info(fmt.Sprintf("Hooks directory: %s", dir))
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// HandleHookRun runs the stages of an installed hook in the working tree
+// git runs it from and returns the hook's exit code. A non-zero code makes
+// git abort the commit or push.
+func HandleHookRun(args []string) int {
+	flags := flag.NewFlagSet("hook-run", flag.ContinueOnError)
+	configFlag := flags.String("config", config.DefaultConfigFileName, "config file")
+	stagesFlag := flags.String("stages", "test", "comma separated stages to run")
+	changedFlag := flags.Bool("changed", false, "only test the packages the commit or push changes")
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		return 1
+	}
+	name := flags.Arg(0)
+
+	cfg, err := config.Load(*configFlag)
+	if err != nil {
+		ui.Error(fmt.Sprintf("%s hook: failed to load config: %v", name, err))
+		return 1
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		ui.Error(err.Error())
+		return 1
+	}
+	for key, value := range cfg.Environment.Variables {
+		os.Setenv(key, value)
+	}
+
+	var changed []string
+	narrow := false
+	if *changedFlag {
+		changed, narrow = hookChanges(name, cfg, dir)
+		if narrow && changed == nil {
+			ui.Info(fmt.Sprintf("%s hook: nothing to check", name))
+			return 0
+		}
+	}
+
+	// git points these at the repository being committed to, which would
+	// confuse any git the stages run, such as in test fixtures
+	for _, key := range []string{"GIT_DIR", "GIT_INDEX_FILE", "GIT_WORK_TREE", "GIT_PREFIX"} {
+		os.Unsetenv(key)
+	}
+
+	repo := workspace.Repo{Name: name, Dir: dir, Git: cfg.Git, Build: cfg.Build}
+	commit := repo.CommitEnv()
+	for key, value := range commit {
+		os.Setenv(key, value)
+	}
+
+	ctx, cancel := cfg.Build.Timeouts.Context(context.Background())
+	defer cancel()
+	run := startRun(*configFlag, cfg, "hook "+name, commit["AUTOMATELIFE_COMMIT_SHA"])
+	for _, stage := range splitList(*stagesFlag) {
+		command, err := repo.StageCommand(stage)
+		if err != nil {
+			ui.Error(err.Error())
+			finishRun(run, stepsSkipped.String())
+			return 1
+		}
+		// Shell scripts are left alone, their words may mean more than they say
+		if stage == "test" && narrow && !cfg.Build.Shell {
+			narrowed, ok := builder.ChangedTestCommand(cfg.Build.Language, command, dir, changed)
+			if ok && narrowed == "" {
+				ui.Info(fmt.Sprintf("%s hook: no changed packages to test", name))
+				continue
+			}
+			command = narrowed
+		}
+		if command == "" {
+			continue
+		}
+
+		fmt.Printf("%s%s=== %s: %s ===%s\n", ui.Bold, ui.Blue, name, stage, ui.Reset)
+		ui.Info(fmt.Sprintf("Executing: %s", command))
+		if err := run.RunStep(ctx, newStep(&cfg.Build, stage, command), os.Stdout, os.Stderr); err != nil {
+			ui.Error(fmt.Sprintf("%s hook: %s failed: %v", name, stage, err))
+			fmt.Printf("Fix the problem, or bypass the hook once with git %s --no-verify\n", strings.TrimPrefix(name, "pre-"))
+			finishRun(run, stoppedSteps(err, stepsFailed).String())
+			return 1
+		}
+	}
+	finishRun(run, stepsPassed.String())
+	ui.Success(fmt.Sprintf("%s hook passed", name))
+	return 0
+}
+
+// hookChanges lists the files the commit or push being checked changes. It
+// reports false when they can't be told, and everything has to be tested.
+func hookChanges(name string, cfg *config.Config, dir string) ([]string, bool) {
+	client, err := git.NewExecClient(nil)
+	if err != nil {
+		return nil, false
+	}
+
+	switch name {
+	case "pre-commit":
+		files, err := client.ChangedFiles(dir, "HEAD", "")
+		if err != nil {
+			// The first commit has nothing to compare with
+			return nil, false
+		}
+		return files, true
+
+	case "pre-push":
+		// Every line is: <local ref> <local sha> <remote ref> <remote sha>
+		base := "origin/HEAD"
+		if cfg.Git.Branch != "" {
+			base = "origin/" + cfg.Git.Branch
+		}
+		var files []string
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) != 4 || fields[1] == zeroSHA {
+				continue // Deleting a ref needs no checks
+			}
+			from := fields[3]
+			if from == zeroSHA {
+				// A new branch, compare with where it forked from
+				from = base
+			}
+			changed, err := client.ChangedFiles(dir, from, fields[1])
+			if err != nil {
+				return nil, false
+			}
+			files = append(files, changed...)
+		}
+		return files, true
+	}
+	return nil, false
+}
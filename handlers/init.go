@@ -1,18 +1,28 @@
 package handlers
 
 import (
+	"automateLife/ci"
 	"automateLife/config"
+	"automateLife/detect"
 	"automateLife/ui"
 	"bufio"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/manifoldco/promptui"
 )
 
-func HandleInit(fileName string) {
+func HandleInit(fileName string, args []string) {
+	flags := flag.NewFlagSet("init", flag.ContinueOnError)
+	detectFlag := flags.Bool("detect", false, "detect settings from an existing checkout")
+	fromCIFlag := flags.String("from-ci", "", "import build settings from a CI definition file")
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+
 	content := config.DefaultConfigTemplate()
 
 	if err := config.Create(fileName, content); err != nil {
@@ -25,6 +35,28 @@ func HandleInit(fileName string) {
 	}
 
 	ui.Success(fileName + " created successfully")
+
+	if *detectFlag || *fromCIFlag != "" {
+		if *detectFlag {
+			dir := "."
+			if flags.NArg() > 0 {
+				dir = flags.Arg(0)
+			}
+			if err := populateConfigFromCheckout(fileName, dir); err != nil {
+				ui.Error(fmt.Sprintf("Failed to detect settings: %v", err))
+				return
+			}
+		}
+		if *fromCIFlag != "" {
+			if err := populateConfigFromCI(fileName, *fromCIFlag); err != nil {
+				ui.Error(fmt.Sprintf("Failed to import CI settings: %v", err))
+				return
+			}
+		}
+		fmt.Println("Fill in your credentials, then run 'automateLife verify'")
+		return
+	}
+
 	fmt.Println("Do you wish to populate the config file? y/n")
 
 	reader := bufio.NewReader(os.Stdin)
@@ -51,7 +83,7 @@ func HandleInit(fileName string) {
 
 		if startInput == "y" {
 			fmt.Print("\nStarting repository clone...\n\n")
-			HandleStart(fileName)
+			HandleStart(fileName, nil)
 		} else {
 			fmt.Println("You can run 'automateLife start' later to begin cloning the repository")
 		}
@@ -82,7 +114,7 @@ func populateConfigInteractively(fileName string) error {
 	// 2. Select Authentication Type
 	authPrompt := promptui.Select{
 		Label: "Select Git Authentication Type",
-		Items: []string{"token", "password", "ssh"},
+		Items: []string{"token", "basic", "ssh"},
 	}
 	_, authType, err := authPrompt.Run()
 	if err != nil {
@@ -93,7 +125,7 @@ func populateConfigInteractively(fileName string) error {
 	// 3. Select Language
 	langPrompt := promptui.Select{
 		Label: "Select Project Language",
-		Items: []string{"go", "dotnet", "python", "nodejs", "java"},
+		Items: detect.Languages(),
 	}
 	_, language, err := langPrompt.Run()
 	if err != nil {
@@ -194,7 +226,7 @@ func populateConfigInteractively(fileName string) error {
 		cfg.Git.UserName = ""
 		cfg.Git.SSHKeyPath = ""
 
-	case "password":
+	case "basic":
 		usernamePrompt := promptui.Prompt{
 			Label: "Git Username",
 			Validate: func(input string) error {
@@ -304,19 +336,96 @@ func populateConfigInteractively(fileName string) error {
 		cfg.Azure.Region = strings.TrimSpace(azureRegion)
 	}
 
+	// Re-prompt for anything the validator rejects before saving
+	if err := fixInvalidFields(cfg); err != nil {
+		return err
+	}
+
 	// Save the updated config
-	return saveConfig(fileName, cfg)
+	return config.Save(fileName, cfg)
+}
+
+func populateConfigFromCheckout(fileName string, dir string) error {
+	cfg, err := config.Load(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ui.Info(fmt.Sprintf("Inspecting %s", dir))
+	result, err := detect.Detect(dir)
+	if err != nil {
+		return err
+	}
+	result.Apply(cfg)
+
+	if err := config.Save(fileName, cfg); err != nil {
+		return err
+	}
+
+	printDetected("Provider", result.Provider)
+	printDetected("Repository URL", result.RepoUrl)
+	printDetected("Branch", result.Branch)
+	printDetected("Language", result.Language)
+	printDetected("Install command", result.InstallCommand)
+	printDetected("Build command", result.BuildCommand)
+	printDetected("Test command", result.TestCommand)
+	printDetected("Deployment type", result.DeploymentType)
+	if len(result.Markers) > 0 {
+		ui.Info(fmt.Sprintf("Detected from: %s", strings.Join(result.Markers, ", ")))
+	}
+
+	ui.Success("Config file populated from " + dir)
+	return nil
 }
 
-func saveConfig(fileName string, cfg *config.Config) error {
-	data, err := json.MarshalIndent(cfg, "", "  ")
+func populateConfigFromCI(fileName string, ciFile string) error {
+	cfg, err := config.Load(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	imported, err := ci.ImportFile(ciFile)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
+	imported.Apply(cfg)
 
-	if err := os.WriteFile(fileName, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	if err := config.Save(fileName, cfg); err != nil {
+		return err
 	}
 
+	ui.Info(fmt.Sprintf("Imported %s definition from %s", imported.Format, ciFile))
+	printDetected("Install command", imported.InstallCommand)
+	printDetected("Build command", imported.BuildCommand)
+	printDetected("Test command", imported.TestCommand)
+	for _, key := range sortedVariableNames(imported.Variables) {
+		printDetected("Variable "+key, imported.Variables[key])
+	}
+
+	if len(imported.Untranslated) > 0 {
+		ui.Warning(fmt.Sprintf("%d item(s) could not be translated:", len(imported.Untranslated)))
+		for _, note := range imported.Untranslated {
+			fmt.Printf("  - %s\n", note)
+		}
+	}
+
+	ui.Success("Config file populated from " + ciFile)
 	return nil
 }
+
+func sortedVariableNames(vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func printDetected(label string, value string) {
+	if value == "" {
+		fmt.Printf("  %s: %s(not detected)%s\n", label, ui.Yellow, ui.Reset)
+		return
+	}
+	fmt.Printf("  %s: %s%s%s\n", label, ui.Bold, value, ui.Reset)
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"automateLife/config"
+	"automateLife/runlog"
+	"automateLife/ui"
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/tabwriter"
+	"time"
+)
+
+// HandleLogs lists the logged runs, the steps of one run, or shows the
+// log of a step
+func HandleLogs(fileName string, args []string) {
+	flags := flag.NewFlagSet("logs", flag.ContinueOnError)
+	tailFlag := flags.Int("tail", 0, "only show the last n lines")
+	grepFlag := flags.String("grep", "", "only show lines matching this regular expression")
+
+	// Flags may follow the run and step
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(positional) > 2 || *tailFlag < 0 {
+		printLogsUsage()
+		return
+	}
+
+	var pattern *regexp.Regexp
+	if *grepFlag != "" {
+		var err error
+		if pattern, err = regexp.Compile(*grepFlag); err != nil {
+			ui.Error(fmt.Sprintf("Invalid --grep pattern: %v", err))
+			return
+		}
+	}
+
+	root := runlog.Root(fileName)
+	if len(positional) == 0 {
+		switch {
+		case pattern != nil:
+			grepRuns(root, pattern, *tailFlag)
+		case *tailFlag > 0:
+			showRun(root, "last", *tailFlag, nil)
+		default:
+			listRuns(root)
+		}
+		return
+	}
+	if len(positional) == 1 {
+		showRun(root, positional[0], *tailFlag, pattern)
+		return
+	}
+
+	run, ok := findRun(root, positional[0])
+	if !ok {
+		return
+	}
+	step, err := run.FindStep(positional[1])
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	lines, err := logLines(run.LogPath(step), pattern, *tailFlag)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	for _, line := range lines {
+		fmt.Println(line.text)
+	}
+}
+
+func printLogsUsage() {
+	fmt.Println("Usage: automateLife logs [run [step]] [--tail N] [--grep PATTERN]")
+	fmt.Println("       run is a run ID, the start of one, or last")
+}
+
+func findRun(root string, ref string) (*runlog.Run, bool) {
+	run, err := runlog.Find(root, ref)
+	if errors.Is(err, runlog.ErrNoRuns) {
+		ui.Info("No runs logged yet, test, watch, bisect, hooks and workspace run save them")
+		return nil, false
+	}
+	if err != nil {
+		ui.Error(err.Error())
+		return nil, false
+	}
+	return run, true
+}
+
+func listRuns(root string) {
+	runs, err := runlog.List(root)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	if len(runs) == 0 {
+		ui.Info("No runs logged yet, test, watch, bisect, hooks and workspace run save them")
+		return
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "RUN\tSTARTED\tTITLE\tCOMMIT\tRESULT\tTIME\tSTEPS")
+	for _, run := range runs {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n", run.ID, run.Started.Format("2006-01-02 15:04:05"), run.Title, orDash(shortSHA(run.Revision)), runResult(run.Result), run.Duration.Round(time.Millisecond), len(run.Steps))
+	}
+	table.Flush()
+	fmt.Println("\nShow the steps of a run with: automateLife logs <run>")
+}
+
+// showRun lists the steps of a run, or shows the lines of all of its logs
+// that pass the tail and pattern filters
+func showRun(root string, ref string, tail int, pattern *regexp.Regexp) {
+	run, ok := findRun(root, ref)
+	if !ok {
+		return
+	}
+
+	if tail > 0 || pattern != nil {
+		for _, step := range run.Steps {
+			lines, err := logLines(run.LogPath(step), pattern, tail)
+			if err != nil {
+				ui.Warning(err.Error())
+				continue
+			}
+			if len(lines) == 0 && pattern != nil {
+				continue
+			}
+			fmt.Printf("%s%s=== %s (%s) ===%s\n", ui.Bold, ui.Blue, step.Name, runResult(step.Result), ui.Reset)
+			for _, line := range lines {
+				fmt.Println(line.text)
+			}
+		}
+		return
+	}
+
+	fmt.Printf("%sRun %s:%s %s", ui.Bold, run.ID, ui.Reset, run.Title)
+	if run.Revision != "" {
+		fmt.Printf(" of %s", shortSHA(run.Revision))
+	}
+	fmt.Printf(", %s, started %s\n\n", runResult(run.Result), run.Started.Format("2006-01-02 15:04:05"))
+	if len(run.Steps) == 0 {
+		ui.Info("The run logged no steps")
+		return
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "STEP\tRESULT\tTIME\tLOG")
+	for _, step := range run.Steps {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", step.Name, runResult(step.Result), step.Duration.Round(time.Millisecond), run.LogPath(step))
+	}
+	table.Flush()
+	fmt.Println()
+	for _, step := range run.Steps {
+		if step.Error != "" {
+			fmt.Printf("%s: %s\n", step.Name, step.Error)
+		}
+	}
+	fmt.Printf("Show a log with: automateLife logs %s <step> [--tail N] [--grep PATTERN]\n", run.ID)
+}
+
+// grepRuns prints the lines matching pattern in every log kept
+func grepRuns(root string, pattern *regexp.Regexp, tail int) {
+	runs, err := runlog.List(root)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	matched := false
+	for _, run := range runs {
+		for _, step := range run.Steps {
+			lines, err := logLines(run.LogPath(step), pattern, tail)
+			if err != nil {
+				continue
+			}
+			for _, line := range lines {
+				fmt.Printf("%s %s:%d: %s\n", run.ID, step.Name, line.number, line.text)
+				matched = true
+			}
+		}
+	}
+	if !matched {
+		ui.Info(fmt.Sprintf("No line of the %d runs kept matches %s", len(runs), pattern))
+	}
+}
+
+type logLine struct {
+	number int
+	text   string
+}
+
+// logLines reads the lines of the log at path matching pattern, all of
+// them when it is nil, keeping only the last tail unless tail is 0
+func logLines(path string, pattern *regexp.Regexp, tail int) ([]logLine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log: %w", err)
+	}
+	defer file.Close()
+
+	var lines []logLine
+	reader := bufio.NewReader(file)
+	for number := 1; ; number++ {
+		text, err := reader.ReadString('\n')
+		if text != "" && (pattern == nil || pattern.MatchString(text)) {
+			lines = append(lines, logLine{number: number, text: trimNewline(text)})
+			if tail > 0 && len(lines) > tail {
+				lines = lines[1:]
+			}
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+		}
+	}
+}
+
+func trimNewline(text string) string {
+	if n := len(text); n > 0 && text[n-1] == '\n' {
+		text = text[:n-1]
+	}
+	return text
+}
+
+func runResult(result string) string {
+	if result == "" {
+		return "unfinished"
+	}
+	return result
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// startRun starts saving the step logs of a run. It returns nil, and the
+// steps run without saving their logs, when the run can't be created.
+func startRun(fileName string, cfg *config.Config, title string, revision string) *runlog.Run {
+	run, err := runlog.Start(runlog.Root(fileName), title, runlog.Options{
+		Timestamps: cfg.Logs.Timestamps,
+		Streams:    cfg.Logs.Streams,
+		Keep:       cfg.Logs.Keep,
+	})
+	if err != nil {
+		ui.Warning(fmt.Sprintf("Not saving the step logs: %v", err))
+		return nil
+	}
+	run.Revision = revision
+	return run
+}
+
+// finishRun records the result of run and points at its logs
+func finishRun(run *runlog.Run, result string) {
+	if run == nil {
+		return
+	}
+	if err := run.Finish(result); err != nil {
+		ui.Warning(fmt.Sprintf("Could not record the run: %v", err))
+		return
+	}
+	if len(run.Steps) > 0 {
+		ui.Info(fmt.Sprintf("Logs saved in %s, show them with: automateLife logs %s", run.Dir(), run.ID))
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"automateLife/ui"
+	"fmt"
+	"strings"
+)
+
+// checkSignature refuses the checkout in dir unless it is signed by a
+// trusted key, when git.require_signed is set. ref is what was checked
+// out: a pinned tag is verified rather than the commit it points to.
+func checkSignature(cfg *config.GitConfig, client git.Client, dir string, ref string) bool {
+	if !cfg.RequireSigned {
+		return true
+	}
+	return verifySigned(cfg, client, dir, signedRevision(client, dir, ref))
+}
+
+// verifySigned refuses rev unless it is signed by a trusted key, printing
+// the signer either way
+func verifySigned(cfg *config.GitConfig, client git.Client, dir string, rev string) bool {
+	sig, err := git.VerifyRevision(client, dir, rev, git.SigningKeysFromConfig(cfg))
+	if err != nil {
+		ui.Error(fmt.Sprintf("Could not verify the signature of %s: %v", rev, err))
+		return false
+	}
+	if sig.Verified {
+		ui.Success(fmt.Sprintf("Signature verified: %s %s signed by %s", sig.Object, rev, signerOf(sig)))
+		return true
+	}
+
+	if !sig.Signed {
+		ui.Error(fmt.Sprintf("Refusing to continue: the %s %s is not signed and git.require_signed is set", sig.Object, rev))
+		return false
+	}
+	ui.Error(fmt.Sprintf("Refusing to continue: the %s %s is not signed by a trusted key", sig.Object, rev))
+	fmt.Printf("  Signer: %s\n", signerOf(sig))
+	for _, line := range strings.Split(sig.Output, "\n") {
+		fmt.Printf("  | %s\n", line)
+	}
+	return false
+}
+
+// signedRevision picks what to verify: the tag or pull request ref a
+// detached checkout came from, or HEAD
+func signedRevision(client git.Client, dir string, ref string) string {
+	if branch, _ := client.CurrentBranch(dir); branch != "" || ref == "" {
+		return "HEAD"
+	}
+	if _, err := client.RevParse(dir, "refs/tags/"+ref); err == nil {
+		return "refs/tags/" + ref
+	}
+	// A pull request merged locally is verified by its head, the merge commit is never signed
+	if strings.HasPrefix(ref, "refs/") {
+		return ref
+	}
+	return "HEAD"
+}
+
+func signerOf(sig *git.Signature) string {
+	signer := sig.Signer
+	if signer == "" {
+		signer = "an unknown signer"
+	}
+	if sig.Key != "" {
+		signer += " (" + sig.Key + ")"
+	}
+	return signer
+}
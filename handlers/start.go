@@ -3,15 +3,32 @@ package handlers
 import (
 	"automateLife/config"
 	"automateLife/git"
+	"automateLife/provider"
+	"automateLife/state"
 	"automateLife/ui"
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
+
+	"github.com/manifoldco/promptui"
 )
 
-func HandleStart(fileName string) {
+func HandleStart(fileName string, args []string) {
+	flags := flag.NewFlagSet("start", flag.ContinueOnError)
+	forceFlag := flags.Bool("force", false, "hard-reset an existing clone that cannot be fast-forwarded")
+	prFlag := flags.Int("pr", 0, "check out this pull or merge request instead of git.branch or git.ref")
+	mergeFlag := flags.Bool("merge", false, "with --pr, test the pull request merged into its target branch")
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+	if *mergeFlag && *prFlag == 0 {
+		ui.Error("--merge needs a pull request number, e.g. --pr 123 --merge")
+		return
+	}
+
 	cfg, err := config.Load(fileName)
 	if err != nil {
 		ui.Error(fmt.Sprintf("Failed to load config: %v", err))
@@ -24,71 +41,103 @@ func HandleStart(fileName string) {
 		return
 	}
 
-	// Handle SSH authentication
-	if cfg.Git.AuthType == "ssh" {
-		if err := git.SetupSSH(cfg.Git.SSHKeyPath); err != nil {
-			ui.Error(err.Error())
-			return
-		}
-		ui.Info(fmt.Sprintf("Using SSH authentication with key: %s", cfg.Git.SSHKeyPath))
-	}
-
 	repoUrl, err := git.BuildAuthURL(&cfg.Git)
 	if err != nil {
 		ui.Error(fmt.Sprintf("Failed to build repo URL: %v", err))
 		return
 	}
 
-	authHeader, err := git.GetAuthHeader(&cfg.Git)
+	if err := unlockSSHKey(&cfg.Git); err != nil {
+		ui.Error(err.Error())
+		return
+	}
+
+	client, err := git.NewExecClient(&cfg.Git)
 	if err != nil {
-		ui.Error(fmt.Sprintf("Failed to build auth header: %v", err))
+		ui.Error(err.Error())
 		return
 	}
+	client.Stdout = os.Stdout
+	client.Stderr = os.Stderr
 
-	// Disable Git credential helper
-	os.Setenv("GIT_TERMINAL_PROMPT", "0")
-	os.Setenv("GCM_INTERACTIVE", "never")
+	if repoUrl != cfg.Git.RepoUrl {
+		ui.Info(fmt.Sprintf("Using %s for %s authentication", repoUrl, cfg.Git.AuthType))
+	}
+	if cfg.Git.AuthType == "ssh" {
+		if cfg.Git.SSHKeyPath != "" {
+			ui.Info(fmt.Sprintf("Using SSH authentication with key: %s", cfg.Git.SSHKeyPath))
+		} else {
+			ui.Info("Using SSH authentication with keys from ssh-agent")
+		}
+	}
 
-	// Prepare git clone command
-	var cmd *exec.Cmd
-	var args []string
+	cloneDir, err := state.ConfiguredCloneDir(fileName, &cfg.Git)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
 
-	// Add auth header if present (for token/basic auth)
-	if authHeader != "" {
-		args = append(args, "-c", authHeader)
+	var pullRequest *git.PullRequest
+	if *prFlag != 0 {
+		if pullRequest, err = pullRequestOf(&cfg.Git, *prFlag, *mergeFlag); err != nil {
+			ui.Error(err.Error())
+			return
+		}
 	}
 
-	args = append(args, "clone")
-	if cfg.Git.Branch != "" && cfg.Git.Branch != "main" {
-		args = append(args, "-b", cfg.Git.Branch)
-		fmt.Printf("Cloning repository (branch: %s%s%s%s) .....\n", ui.Bold, cfg.Git.Branch, ui.Reset, "")
+	if pullRequest != nil {
+		fmt.Printf("Syncing repository (pull request: %s#%d%s) .....\n", ui.Bold, pullRequest.Number, ui.Reset)
+	} else if cfg.Git.Ref != "" {
+		fmt.Printf("Syncing repository (ref: %s%s%s) .....\n", ui.Bold, cfg.Git.Ref, ui.Reset)
+	} else if cfg.Git.Branch != "" {
+		fmt.Printf("Syncing repository (branch: %s%s%s) .....\n", ui.Bold, cfg.Git.Branch, ui.Reset)
 	} else {
-		fmt.Println("Cloning repository .....")
+		fmt.Println("Syncing repository .....")
 	}
 
-	args = append(args, repoUrl)
-
-	cmd = exec.Command("git", args...)
-
-	cmd.Env = append(os.Environ(),
-		"GIT_TERMINAL_PROMPT=0",
-		"GCM_INTERACTIVE=never",
-		"GIT_ASKPASS=echo",
-	)
+	reference := ""
+	if cfg.Git.Cache {
+		reference = cachedReference(client, repoUrl, cloneDir)
+	}
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	result, err := git.CloneOrUpdate(client, git.SyncOptions{
+		URL:         repoUrl,
+		Dir:         cloneDir,
+		Branch:      cfg.Git.Branch,
+		Ref:         cfg.Git.Ref,
+		Force:       *forceFlag,
+		Reference:   reference,
+		PullRequest: pullRequest,
+		Tuning:      git.TuningFromConfig(&cfg.Git),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, git.ErrDirtyWorkTree), errors.Is(err, git.ErrRemoteMismatch), errors.Is(err, git.ErrDiverged), errors.Is(err, git.ErrMergeConflict):
+			ui.Error(err.Error())
+		default:
+			ui.Error(fmt.Sprintf("Cloning repo failed: %v", err))
+			printRemediation(err, &cfg.Git)
+		}
+		return
+	}
 
-	if err = cmd.Run(); err != nil {
-		ui.Error(fmt.Sprintf("Cloning repo failed: %v", err))
-		fmt.Println("\nTroubleshooting tips:")
-		fmt.Println("  1. Verify your PAT has the correct permissions (Code: Read)")
-		fmt.Println("  2. Check if the PAT has expired")
-		fmt.Println("  3. Ensure the repo_url is correct")
+	ref := saveCheckout(fileName, &cfg.Git, cloneDir, result)
+	if !checkSignature(&cfg.Git, client, cloneDir, result.Ref) {
 		return
 	}
 
-	ui.Success("Repo cloned successfully!")
+	switch {
+	case result.Cloned:
+		ui.Success(fmt.Sprintf("Repo cloned successfully! (%s at %s)", ref, shortSHA(result.NewCommit)))
+	case result.Updated():
+		ui.Success(fmt.Sprintf("Repo updated: %s %s -> %s", ref, shortSHA(result.OldCommit), shortSHA(result.NewCommit)))
+	default:
+		ui.Success(fmt.Sprintf("Repo already up to date: %s at %s", ref, shortSHA(result.NewCommit)))
+	}
+	if pullRequest != nil && pullRequest.Merge {
+		ui.Info(fmt.Sprintf("Testing pull request #%d merged into %s", pullRequest.Number, mergeTarget(&cfg.Git)))
+	}
+	printCommit(result.Commit)
 
 	// Ask if user wants to run tests
 	fmt.Print("\nDo you want to run tests now? y/n\n")
@@ -98,12 +147,102 @@ func HandleStart(fileName string) {
 
 	if input == "y" {
 		fmt.Print("\nStarting tests...\n\n")
-		HandleTest(fileName)
+		HandleTest(fileName, nil)
 	} else {
 		if cfg.Project.Name != "" {
 			fmt.Println("\nNext steps:")
-			fmt.Println("  cd into your project directory")
-			fmt.Printf("  Run %s%sautomateLife test%s to run tests\n", ui.Bold, ui.Blue, ui.Reset)
+			fmt.Printf("  Run %s%sautomateLife test%s to run tests in %s\n", ui.Bold, ui.Blue, ui.Reset, cloneDir)
 		}
 	}
 }
+
+// saveCheckout records where the clone is and what it has checked out for
+// the commands that follow, and returns the ref that was checked out
+func saveCheckout(fileName string, cfg *config.GitConfig, cloneDir string, result *git.SyncResult) string {
+	ref := result.Branch
+	if result.Ref != "" {
+		ref = result.Ref
+	}
+	if err := state.Save(fileName, &state.State{
+		RepoUrl:  cfg.RepoUrl,
+		CloneDir: cloneDir,
+		Branch:   result.Branch,
+		Revision: state.NewRevision(ref, result.Commit),
+	}); err != nil {
+		ui.Warning(fmt.Sprintf("Could not record the clone location: %v", err))
+	}
+	return ref
+}
+
+// pullRequestOf finds the ref of pull request n from git.provider, or the
+// provider repo_url points to
+func pullRequestOf(cfg *config.GitConfig, n int, merge bool) (*git.PullRequest, error) {
+	p, err := provider.Lookup(cfg.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		p = provider.FromURL(cfg.RepoUrl)
+	}
+	ref, merged, err := p.PullRequestRef(n)
+	if err != nil {
+		return nil, err
+	}
+	if merged && !merge {
+		ui.Warning(fmt.Sprintf("%s only publishes pull requests merged into their target branch, testing the merge result", p.Name))
+	}
+	// The provider has done the merge already
+	return &git.PullRequest{Number: n, Ref: ref, Merge: merge && !merged}, nil
+}
+
+// mergeTarget names the branch pull requests are merged into for testing
+func mergeTarget(cfg *config.GitConfig) string {
+	if cfg.Branch != "" {
+		return cfg.Branch
+	}
+	return "the default branch"
+}
+
+// unlockSSHKey asks for the passphrase of an encrypted SSH key when the
+// config doesn't provide one
+func unlockSSHKey(cfg *config.GitConfig) error {
+	if cfg.AuthType != "ssh" || cfg.SSHKeyPath == "" || cfg.SSHPassphrase != "" {
+		return nil
+	}
+	encrypted, err := git.KeyNeedsPassphrase(cfg.SSHKeyPath)
+	if err != nil || !encrypted {
+		return err
+	}
+
+	passphrasePrompt := promptui.Prompt{
+		Label: fmt.Sprintf("Passphrase for %s", cfg.SSHKeyPath),
+		Mask:  '*',
+	}
+	passphrase, err := passphrasePrompt.Run()
+	if err != nil {
+		return fmt.Errorf("passphrase input failed: %w", err)
+	}
+	cfg.SSHPassphrase = passphrase
+	return nil
+}
+
+// printCommit shows which commit the following stages will run against
+func printCommit(commit git.Commit) {
+	ui.Info(fmt.Sprintf("Commit %s by %s on %s: %s", shortSHA(commit.SHA), commit.Author, commit.Date.Format("2006-01-02 15:04"), commit.Subject))
+}
+
+func shortSHA(sha string) string {
+	return git.Commit{SHA: sha}.ShortSHA()
+}
+
+// printRemediation prints troubleshooting tips for the kind of git failure
+func printRemediation(err error, cfg *config.GitConfig) {
+	tips := git.Remediation(err, cfg)
+	if len(tips) == 0 {
+		return
+	}
+	fmt.Println("\nTroubleshooting tips:")
+	for i, tip := range tips {
+		fmt.Printf("  %d. %s\n", i+1, tip)
+	}
+}
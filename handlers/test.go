@@ -4,43 +4,63 @@ import (
 	"automateLife/builder"
 	"automateLife/config"
 	"automateLife/git"
+	"automateLife/runlog"
+	"automateLife/state"
 	"automateLife/ui"
+	"automateLife/workspace"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
 )
 
-func HandleTest(fileName string) {
+func HandleTest(fileName string, args []string) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	branchesFlag := flags.String("branches", "", "comma separated branches to test side by side")
+	jobsFlag := flags.Int("jobs", workspace.DefaultJobs, "how many branches to test at once")
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+	if *branchesFlag == "" {
+		runTests(fileName)
+		return
+	}
+	branches := splitBranches(*branchesFlag)
+	if len(branches) == 0 || *jobsFlag < 1 {
+		fmt.Println("Usage: automateLife test [--branches <a,b,...>] [--jobs <n>]")
+		return
+	}
+	testBranches(fileName, branches, *jobsFlag)
+}
+
+// runTests installs dependencies, builds and runs the tests in the clone,
+// and reports whether they passed
+func runTests(fileName string) bool {
 	cfg, err := config.Load(fileName)
 	if err != nil {
 		ui.Error(fmt.Sprintf("Failed to load config: %v", err))
-		return
+		return false
 	}
 
 	if err := cfg.Validate(); err != nil {
 		ui.Error(fmt.Sprintf("Configuration validation failed: %v", err))
-		return
+		return false
 	}
 
-	projectDir := git.GetProjectDirName(cfg.Git.RepoUrl)
-	if projectDir == "" {
-		ui.Error("Could not determine project directory name")
-		return
+	fullProjectPath, err := state.CloneDir(fileName, &cfg.Git)
+	if err != nil {
+		ui.Error(err.Error())
+		return false
 	}
 
 	originalDir, _ := os.Getwd()
-	fullProjectPath := projectDir
-
-	// If project directory is relative, make it absolute
-	if !filepath.IsAbs(projectDir) {
-		fullProjectPath = filepath.Join(originalDir, projectDir)
-	}
 
 	if _, err := os.Stat(fullProjectPath); os.IsNotExist(err) {
 		ui.Error(fmt.Sprintf("Project directory '%s' not found. Run 'automateLife start' first.", fullProjectPath))
 		ui.Info(fmt.Sprintf("Current directory: %s", originalDir))
 		ui.Info(fmt.Sprintf("Looking for: %s", fullProjectPath))
-		return
+		return false
 	}
 
 	fmt.Printf("%s%s=== Running Tests for %s ===%s\n\n", ui.Bold, ui.Blue, cfg.Project.Name, ui.Reset)
@@ -48,7 +68,7 @@ func HandleTest(fileName string) {
 
 	if err := os.Chdir(fullProjectPath); err != nil {
 		ui.Error(fmt.Sprintf("Could not change to project directory: %v", err))
-		return
+		return false
 	}
 	defer os.Chdir(originalDir)
 
@@ -60,47 +80,139 @@ func HandleTest(fileName string) {
 		os.Setenv(key, value)
 	}
 
+	revision, err := currentRevision(fileName, fullProjectPath)
+	if err != nil {
+		ui.Warning(fmt.Sprintf("Could not determine the checked out commit: %v", err))
+	} else {
+		printCommit(revision.Commit())
+		for key, value := range revision.Commit().Env(revision.Ref) {
+			os.Setenv(key, value)
+		}
+	}
+
+	if cfg.Git.RequireSigned {
+		client, err := git.NewExecClient(nil)
+		if err != nil {
+			ui.Error(err.Error())
+			return false
+		}
+		ref := ""
+		if revision != nil {
+			ref = revision.Ref
+		}
+		if !checkSignature(&cfg.Git, client, fullProjectPath, ref) {
+			return false
+		}
+	}
+
+	sha := ""
+	if revision != nil {
+		sha = revision.SHA
+	}
+	run := startRun(fileName, cfg, "test", sha)
+	result := runSteps(cfg, currentDir, run)
+	defer finishRun(run, result.String())
+
+	switch result {
+	case stepsPassed:
+		fmt.Printf("\n%s%s✓ All tests passed successfully!%s\n", ui.Bold, ui.Green, ui.Reset)
+		printTestedRevision(revision)
+		return true
+	case stepsFailed:
+		printTestedRevision(revision)
+	case stepsInterrupted:
+		ui.Warning("Stopped before the tests finished")
+	}
+	return false
+}
+
+// Outcome of the install, build and test steps
+type stepsResult int
+
+const (
+	stepsPassed      stepsResult = iota
+	stepsFailed                  // The tests ran and failed
+	stepsSkipped                 // The tests could not be run, e.g. dependencies failed to install or the build failed
+	stepsInterrupted             // automateLife was asked to stop
+)
+
+func (r stepsResult) String() string {
+	switch r {
+	case stepsPassed:
+		return "passed"
+	case stepsFailed:
+		return "failed"
+	case stepsSkipped:
+		return "skipped"
+	}
+	return "interrupted"
+}
+
+// runSteps installs dependencies, builds and runs the tests of the checkout
+// in dir, the working directory, saving their output in run. It leaves the
+// working directory changed.
+func runSteps(cfg *config.Config, dir string, run *runlog.Run) stepsResult {
+	ctx, cancel := cfg.Build.Timeouts.Context(context.Background())
+	defer cancel()
+
 	// Install dependencies
 	if cfg.Build.InstallCommand != "" {
 		fmt.Printf("%sStep 1:%s Installing dependencies...\n", ui.Bold, ui.Reset)
-		if err := builder.RunCommand(cfg.Build.InstallCommand); err != nil {
+		if err := run.RunStep(ctx, newStep(&cfg.Build, "install", cfg.Build.InstallCommand), os.Stdout, os.Stderr); err != nil {
 			ui.Error(fmt.Sprintf("Dependency installation failed: %v", err))
-			return
+			return stoppedSteps(err, stepsSkipped)
 		}
 		ui.Success("Dependencies installed successfully\n")
 	} else {
 		fmt.Printf("%sStep 1:%s Detecting and installing dependencies...\n", ui.Bold, ui.Reset)
-		if err := builder.AutoInstallDependencies(cfg.Build.Language); err != nil {
+		command, err := builder.DefaultInstallCommand(cfg.Build.Language)
+		if err == nil && command != "" {
+			err = run.RunStep(ctx, newStep(&cfg.Build, "install", command), os.Stdout, os.Stderr)
+		}
+		if errors.Is(err, builder.ErrInterrupted) || isTimeout(err) {
+			ui.Error(fmt.Sprintf("Dependency installation failed: %v", err))
+			return stoppedSteps(err, stepsSkipped)
+		} else if err != nil {
 			ui.Warning(fmt.Sprintf("Could not auto-install dependencies: %v", err))
 		} else {
 			ui.Success("Dependencies installed successfully\n")
 		}
 	}
 
-	// Step 2: Discover test files
-	fmt.Printf("%sStep 2:%s Discovering test files...\n", ui.Bold, ui.Reset)
-	testFiles, err := builder.DiscoverTests(currentDir)
+	// Step 2: Build, when a build command is configured
+	if cfg.Build.BuildCommand != "" {
+		fmt.Printf("%sStep 2:%s Building...\n", ui.Bold, ui.Reset)
+		if err := run.RunStep(ctx, newStep(&cfg.Build, "build", cfg.Build.BuildCommand), os.Stdout, os.Stderr); err != nil {
+			ui.Error(fmt.Sprintf("Build failed: %v", err))
+			return stoppedSteps(err, stepsSkipped)
+		}
+		ui.Success("Build succeeded\n")
+	}
+
+	// Step 3: Discover test files
+	fmt.Printf("%sStep 3:%s Discovering test files...\n", ui.Bold, ui.Reset)
+	testFiles, err := builder.DiscoverTests(dir)
 	if err != nil {
 		ui.Error(fmt.Sprintf("Failed to discover tests: %v", err))
-		return
+		return stepsSkipped
 	}
 
 	if len(testFiles) == 0 {
 		ui.Warning("No test files found in the project")
-		return
+		return stepsSkipped
 	}
 
-	// Step 3: Create unified test suite
-	fmt.Printf("\n%sStep 3:%s Creating unified test suite...\n", ui.Bold, ui.Reset)
-	unifiedDir, err := builder.CreateUnifiedTestSuite(testFiles, currentDir)
+	// Step 4: Create unified test suite
+	fmt.Printf("\n%sStep 4:%s Creating unified test suite...\n", ui.Bold, ui.Reset)
+	unifiedDir, err := builder.CreateUnifiedTestSuite(testFiles, dir)
 	if err != nil {
 		ui.Error(fmt.Sprintf("Failed to create unified test suite: %v", err))
-		return
+		return stepsSkipped
 	}
-	defer builder.CleanupUnifiedTestSuite(currentDir)
+	defer builder.CleanupUnifiedTestSuite(dir)
 
-	// Step 4: Run tests
-	fmt.Printf("\n%sStep 4:%s Running tests...\n", ui.Bold, ui.Reset)
+	// Step 5: Run tests
+	fmt.Printf("\n%sStep 5:%s Running tests...\n", ui.Bold, ui.Reset)
 	testCommand := cfg.Build.TestCommand
 	if testCommand == "" {
 		testCommand = builder.GetDefaultTestCommand(cfg.Build.Language)
@@ -110,16 +222,74 @@ func HandleTest(fileName string) {
 	// Run tests from the unified directory
 	if err := os.Chdir(unifiedDir); err != nil {
 		ui.Error(fmt.Sprintf("Failed to change to unified test directory: %v", err))
-		return
+		return stepsSkipped
 	}
 
 	ui.Info(fmt.Sprintf("Executing: %s", testCommand))
 
-	if err := builder.RunCommand(testCommand); err != nil {
+	if err := run.RunStep(ctx, newStep(&cfg.Build, "test", testCommand), os.Stdout, os.Stderr); errors.Is(err, builder.ErrInterrupted) {
+		return stepsInterrupted
+	} else if err != nil {
 		fmt.Printf("\n%s%s✗ Tests failed!%s\n", ui.Bold, ui.Red, ui.Reset)
 		ui.Info(fmt.Sprintf("Error: %v", err))
-		return
+		return stepsFailed
+	}
+	return stepsPassed
+}
+
+// newStep prepares command to run as the named step with the limits set
+// in build
+func newStep(build *config.BuildConfig, name string, command string) builder.Step {
+	return builder.Step{
+		Name:    name,
+		Command: command,
+		Shell:   build.Shell,
+		Timeout: build.Timeouts.Step(name),
+		Grace:   build.Timeouts.Grace(),
 	}
+}
 
-	fmt.Printf("\n%s%s✓ All tests passed successfully!%s\n", ui.Bold, ui.Green, ui.Reset)
+// stoppedSteps tells an interrupted run apart from a step that failed with result
+func stoppedSteps(err error, result stepsResult) stepsResult {
+	if errors.Is(err, builder.ErrInterrupted) {
+		return stepsInterrupted
+	}
+	return result
+}
+
+func isTimeout(err error) bool {
+	var timeout *builder.TimeoutError
+	return errors.As(err, &timeout)
+}
+
+// currentRevision describes the commit checked out in dir, keeping the ref
+// recorded by 'start' as long as it still points at the same commit
+func currentRevision(fileName string, dir string) (*state.Revision, error) {
+	client, err := git.NewExecClient(nil)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := client.Log(dir, "HEAD", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("%s has no commits", dir)
+	}
+	head := commits[0]
+
+	if recorded, err := state.Load(fileName); err == nil && recorded.Revision != nil && recorded.Revision.SHA == head.SHA {
+		return state.NewRevision(recorded.Revision.Ref, head), nil
+	}
+	ref, _ := client.CurrentBranch(dir)
+	if ref == "" {
+		ref = head.SHA
+	}
+	return state.NewRevision(ref, head), nil
+}
+
+func printTestedRevision(revision *state.Revision) {
+	if revision != nil {
+		ui.Info(fmt.Sprintf("Tested revision: %s (%s)", revision.SHA, revision.Ref))
+	}
 }
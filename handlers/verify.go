@@ -2,21 +2,140 @@ package handlers
 
 import (
 	"automateLife/config"
+	"automateLife/git"
+	"automateLife/state"
 	"automateLife/ui"
+	"bufio"
+	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
-func HandleVerify(fileName string) {
+func HandleVerify(fileName string, args []string) {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	remoteFlag := flags.Bool("remote", false, "check the repository, branch and credentials against the remote")
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+
 	cfg, err := config.Load(fileName)
 	if err != nil {
 		ui.Error(fmt.Sprintf("Failed to load config: %v", err))
 		return
 	}
 
+	// A config that doesn't validate yet is the one most likely being edited
+	// with secrets in it, so check those first
+	checkSecretHygiene(fileName, cfg)
+
 	if err := cfg.Validate(); err != nil {
 		ui.Error(fmt.Sprintf("Validation failed: %v", err))
 		return
 	}
 
+	if *remoteFlag && !verifyRemote(cfg) {
+		return
+	}
+
 	ui.Success("Directory verified successfully and ready for automation. Run 'automateLife start' to automate!")
 }
+
+// verifyRemote lists the remote with the configured credentials and reports
+// whether the repository and branch are reachable
+func verifyRemote(cfg *config.Config) bool {
+	repoUrl, err := git.BuildAuthURL(&cfg.Git)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to build repo URL: %v", err))
+		return false
+	}
+	if err := unlockSSHKey(&cfg.Git); err != nil {
+		ui.Error(err.Error())
+		return false
+	}
+	client, err := git.NewExecClient(&cfg.Git)
+	if err != nil {
+		ui.Error(err.Error())
+		return false
+	}
+	client.Stderr = os.Stderr
+
+	ui.Info(fmt.Sprintf("Checking %s with %s authentication", repoUrl, cfg.Git.AuthType))
+	check, err := git.VerifyRemote(client, repoUrl, cfg.Git.Branch, cfg.Git.Ref)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Remote check failed: %v", err))
+		printRemediation(err, &cfg.Git)
+		return false
+	}
+
+	ui.Success("Repository is reachable and the credentials have read access")
+	if check.DefaultBranch != "" {
+		fmt.Printf("  Default branch: %s\n", check.DefaultBranch)
+	}
+	if check.SHA != "" {
+		fmt.Printf("  %s %s: %s\n", check.Kind, check.Ref, shortSHA(check.SHA))
+	} else {
+		fmt.Printf("  %s %s: not advertised by the remote, it is fetched when cloning\n", check.Kind, check.Ref)
+	}
+	return true
+}
+
+// checkSecretHygiene warns about config files that could leak credentials
+func checkSecretHygiene(fileName string, cfg *config.Config) {
+	absPath, err := filepath.Abs(fileName)
+	if err != nil {
+		absPath = fileName
+	}
+
+	// Raw values decide whether the file itself holds literal secrets
+	raw, err := config.Read(fileName)
+	if err != nil {
+		raw = cfg
+	}
+
+	if raw.HasSecrets() {
+		if worldReadable, err := config.IsWorldReadable(fileName); err == nil && worldReadable {
+			ui.Warning(fmt.Sprintf("%s holds credentials and is readable by every user on this machine", fileName))
+			fmt.Printf("  Run: chmod 600 %s\n", fileName)
+		}
+
+		ignored, inRepo, err := git.IsIgnored(absPath)
+		if err != nil {
+			ui.Warning(fmt.Sprintf("Could not check whether %s is gitignored: %v", fileName, err))
+		} else if inRepo && !ignored {
+			ui.Warning(fmt.Sprintf("%s holds credentials but is not listed in .gitignore", fileName))
+			if confirm(fmt.Sprintf("Add %s to .gitignore? y/n", filepath.Base(fileName))) {
+				if err := git.AddToGitignore(absPath); err != nil {
+					ui.Error(err.Error())
+				} else {
+					ui.Success(fmt.Sprintf("Added %s to .gitignore", filepath.Base(fileName)))
+				}
+			}
+		}
+	}
+
+	cloneDir, err := state.CloneDir(fileName, &cfg.Git)
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(filepath.Join(cloneDir, ".git")); err != nil {
+		return
+	}
+	commits, err := git.CommitsTouching(cloneDir, filepath.Base(fileName))
+	if err != nil || len(commits) == 0 {
+		return
+	}
+	ui.Warning(fmt.Sprintf("%s is tracked in the history of %s and may have leaked credentials:", filepath.Base(fileName), cloneDir))
+	for _, commit := range commits {
+		fmt.Printf("  - %s\n", commit)
+	}
+	fmt.Println("  Rotate any credentials it contained and remove it from the repository")
+}
+
+func confirm(question string) bool {
+	fmt.Println(question)
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	return strings.TrimSpace(input) == "y"
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"automateLife/state"
+	"automateLife/ui"
+	"automateLife/watch"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func HandleWatch(fileName string, args []string) {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	remoteFlag := flags.Bool("remote", false, "poll the configured branch and run the pipeline for every new commit")
+	intervalFlag := flags.Duration("interval", watch.DefaultInterval, "time between polls (e.g. 30s, 5m)")
+	forceFlag := flags.Bool("force", false, "hard-reset the clone when the branch was force-pushed")
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+	if !*remoteFlag {
+		fmt.Println("Usage: automateLife watch --remote [--interval 1m] [--force]")
+		return
+	}
+	if *intervalFlag < time.Second {
+		ui.Error("--interval must be at least 1s")
+		return
+	}
+
+	cfg, err := config.Load(fileName)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to load config: %v", err))
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		ui.Error(fmt.Sprintf("Configuration validation failed: %v", err))
+		return
+	}
+	if cfg.Git.RepoUrl == "" {
+		ui.Error("watch follows git.repo_url, which is empty")
+		return
+	}
+
+	repoUrl, err := git.BuildAuthURL(&cfg.Git)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to build repo URL: %v", err))
+		return
+	}
+	if err := unlockSSHKey(&cfg.Git); err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	client, err := git.NewExecClient(&cfg.Git)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	client.Stdout = os.Stdout
+	client.Stderr = os.Stderr
+
+	cloneDir, err := state.ConfiguredCloneDir(fileName, &cfg.Git)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+
+	poller := &watch.Poller{
+		Client:     client,
+		ConfigFile: fileName,
+		RepoUrl:    cfg.Git.RepoUrl,
+		URL:        repoUrl,
+		Branch:     cfg.Git.Branch,
+		Ref:        cfg.Git.Ref,
+	}
+	if last, err := poller.Last(); err != nil {
+		ui.Warning(err.Error())
+	} else if last != nil {
+		result := "failed"
+		if last.Passed {
+			result = "passed"
+		}
+		ui.Info(fmt.Sprintf("Last processed: %s on %s (%s)", shortSHA(last.SHA), last.Branch, result))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ui.Info(fmt.Sprintf("Watching %s every %s, press Ctrl+C to stop", cfg.Git.RepoUrl, *intervalFlag))
+	for {
+		if !watchOnce(ctx, fileName, cfg, client, poller, cloneDir, *forceFlag) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			ui.Info("Stopped watching")
+			return
+		case <-time.After(*intervalFlag):
+		}
+	}
+}
+
+// watchOnce polls the remote and, when the branch moved, updates the clone
+// and runs the pipeline. It returns false when watching cannot go on.
+func watchOnce(ctx context.Context, fileName string, cfg *config.Config, client *git.ExecClient, poller *watch.Poller, cloneDir string, force bool) bool {
+	check, changed, err := poller.Poll()
+	if err != nil {
+		if errors.Is(err, watch.ErrNotBranch) {
+			ui.Error(err.Error())
+			return false
+		}
+		// Network hiccups shouldn't end the watch, the next poll tries again
+		ui.Warning(fmt.Sprintf("Polling %s failed: %v", cfg.Git.RepoUrl, err))
+		return true
+	}
+	if !changed {
+		return true
+	}
+
+	fmt.Println()
+	ui.Info(fmt.Sprintf("New commit on %s: %s", check.Ref, shortSHA(check.SHA)))
+	reference := ""
+	if cfg.Git.Cache {
+		reference = cachedReference(client, poller.URL, cloneDir)
+	}
+	result, err := git.CloneOrUpdate(client, git.SyncOptions{
+		URL:       poller.URL,
+		Dir:       cloneDir,
+		Branch:    check.Ref,
+		Force:     force,
+		Reference: reference,
+		Tuning:    git.TuningFromConfig(&cfg.Git),
+	})
+	if err != nil {
+		// Not recorded, so the commit is picked up again on the next poll
+		ui.Error(fmt.Sprintf("Updating the clone failed: %v", err))
+		printRemediation(err, &cfg.Git)
+		return true
+	}
+	saveCheckout(fileName, &cfg.Git, cloneDir, result)
+	printCommit(result.Commit)
+
+	passed := runTests(fileName)
+	if ctx.Err() != nil {
+		// Stopped halfway, the commit is tested again on the next watch
+		ui.Info("Stopped watching")
+		return false
+	}
+	if err := poller.Record(check.Ref, result.NewCommit, passed); err != nil {
+		ui.Warning(fmt.Sprintf("Could not record the processed commit: %v", err))
+	}
+	if passed {
+		ui.Success(fmt.Sprintf("Pipeline passed for %s on %s", shortSHA(result.NewCommit), check.Ref))
+	} else {
+		ui.Error(fmt.Sprintf("Pipeline failed for %s on %s", shortSHA(result.NewCommit), check.Ref))
+	}
+	return true
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"automateLife/ui"
+	"automateLife/workspace"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func HandleWorkspace(fileName string, args []string) {
+	if len(args) == 0 {
+		printWorkspaceUsage()
+		return
+	}
+	switch args[0] {
+	case "sync":
+		HandleWorkspaceSync(fileName, args[1:])
+	case "run":
+		HandleWorkspaceRun(fileName, args[1:])
+	default:
+		printWorkspaceUsage()
+	}
+}
+
+func printWorkspaceUsage() {
+	fmt.Println("Usage: automateLife workspace sync [--jobs N] [--force]")
+	fmt.Printf("       automateLife workspace run <%s> [--jobs N]\n", strings.Join(workspace.Stages, "|"))
+}
+
+// loadWorkspace loads and validates the config and resolves its repositories
+func loadWorkspace(fileName string) (*config.Config, []workspace.Repo, bool) {
+	cfg, err := config.Load(fileName)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to load config: %v", err))
+		return nil, nil, false
+	}
+	if err := cfg.Validate(); err != nil {
+		ui.Error(fmt.Sprintf("Configuration validation failed: %v", err))
+		return nil, nil, false
+	}
+	repos, err := workspace.Repos(fileName, cfg)
+	if err != nil {
+		ui.Error(err.Error())
+		return nil, nil, false
+	}
+	return cfg, repos, true
+}
+
+func HandleWorkspaceSync(fileName string, args []string) {
+	flags := flag.NewFlagSet("workspace sync", flag.ContinueOnError)
+	jobsFlag := flags.Int("jobs", workspace.DefaultJobs, "number of repositories to clone or update at once")
+	forceFlag := flags.Bool("force", false, "hard-reset clones that cannot be fast-forwarded")
+	if err := flags.Parse(args); err != nil {
+		return
+	}
+
+	cfg, repos, ok := loadWorkspace(fileName)
+	if !ok {
+		return
+	}
+	// Ask once up front rather than from several clones at a time
+	if err := unlockSSHKey(&cfg.Git); err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	for i := range repos {
+		repos[i].Git.SSHPassphrase = cfg.Git.SSHPassphrase
+	}
+
+	var cache *git.Cache
+	if cfg.Git.Cache {
+		if dir, err := git.DefaultCacheDir(); err != nil {
+			ui.Warning(fmt.Sprintf("Cloning without the cache: %v", err))
+		} else {
+			cache = git.NewCache(dir)
+		}
+	}
+
+	ui.Info(fmt.Sprintf("Syncing %d repositories, %d at a time", len(repos), *jobsFlag))
+	statuses := workspace.Sync(repos, *jobsFlag, *forceFlag, cache, func(gitCfg *config.GitConfig) (git.Client, error) {
+		return git.NewExecClient(gitCfg)
+	})
+
+	for i := range statuses {
+		if statuses[i].Err == nil && !verifyRepo(&statuses[i].Repo, statuses[i].Result.Ref) {
+			statuses[i].Err = errRefused
+		}
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "\nREPOSITORY\tREF\tSTATUS\tCOMMIT")
+	failed := 0
+	for _, status := range statuses {
+		if status.Err != nil {
+			failed++
+			fmt.Fprintf(table, "%s\t%s\t%s\t\n", status.Repo.Name, configuredRef(&status.Repo.Git), "failed")
+			continue
+		}
+		result := status.Result
+		ref := result.Branch
+		if result.Ref != "" {
+			ref = result.Ref
+		}
+		change := "up to date"
+		switch {
+		case result.Cloned:
+			change = "cloned"
+		case result.Updated():
+			change = fmt.Sprintf("updated from %s", shortSHA(result.OldCommit))
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", status.Repo.Name, ref, change, shortSHA(result.NewCommit))
+	}
+	table.Flush()
+
+	for _, status := range statuses {
+		if status.CacheErr != nil {
+			ui.Warning(fmt.Sprintf("%s was cloned without the cache: %v", status.Repo.Name, status.CacheErr))
+		}
+		if status.Err == nil {
+			continue
+		}
+		fmt.Println()
+		ui.Error(fmt.Sprintf("%s: %v", status.Repo.Name, status.Err))
+		printRemediation(status.Err, &status.Repo.Git)
+	}
+
+	if failed > 0 {
+		ui.Error(fmt.Sprintf("%d of %d repositories failed to sync", failed, len(statuses)))
+		return
+	}
+	ui.Success(fmt.Sprintf("All %d repositories are in sync", len(statuses)))
+}
+
+// errRefused marks a repository whose checkout failed signature
+// verification, checkSignature has already said why
+var errRefused = errors.New("signature verification failed, see above")
+
+// verifyRepo runs checkSignature in repo when it requires signed commits.
+// ref is the tag or commit the repository is pinned to, if any.
+func verifyRepo(repo *workspace.Repo, ref string) bool {
+	if !repo.Git.RequireSigned {
+		return true
+	}
+	client, err := git.NewExecClient(nil)
+	if err != nil {
+		ui.Error(fmt.Sprintf("%s: %v", repo.Name, err))
+		return false
+	}
+	ui.Info(fmt.Sprintf("Verifying the signature of %s", repo.Name))
+	return checkSignature(&repo.Git, client, repo.Dir, ref)
+}
+
+func configuredRef(cfg *config.GitConfig) string {
+	if cfg.Ref != "" {
+		return cfg.Ref
+	}
+	if cfg.Branch != "" {
+		return cfg.Branch
+	}
+	return "(default)"
+}
+
+func HandleWorkspaceRun(fileName string, args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		printWorkspaceUsage()
+		return
+	}
+	stage := args[0]
+	flags := flag.NewFlagSet("workspace run", flag.ContinueOnError)
+	jobsFlag := flags.Int("jobs", workspace.DefaultJobs, "number of repositories to run the stage in at once")
+	if err := flags.Parse(args[1:]); err != nil {
+		return
+	}
+
+	cfg, repos, ok := loadWorkspace(fileName)
+	if !ok {
+		return
+	}
+
+	var missing []string
+	for _, repo := range repos {
+		if _, err := os.Stat(repo.Dir); err != nil {
+			missing = append(missing, repo.Name)
+		}
+	}
+	if len(missing) > 0 {
+		ui.Error(fmt.Sprintf("Not cloned yet: %s. Run 'automateLife workspace sync' first.", strings.Join(missing, ", ")))
+		return
+	}
+
+	// Repositories whose checkout is refused don't run the stage
+	var verified, refused []workspace.Repo
+	for i := range repos {
+		if verifyRepo(&repos[i], repos[i].Git.Ref) {
+			verified = append(verified, repos[i])
+		} else {
+			refused = append(refused, repos[i])
+		}
+	}
+
+	for key, value := range cfg.Environment.Variables {
+		os.Setenv(key, value)
+	}
+
+	var statuses []workspace.RunStatus
+	run := startRun(fileName, cfg, "workspace run "+stage, "")
+	if len(verified) > 0 {
+		ui.Info(fmt.Sprintf("Running %s in %d repositories, %d at a time", stage, len(verified), *jobsFlag))
+		var err error
+		if statuses, err = workspace.Run(verified, stage, *jobsFlag, os.Stdout, run); err != nil {
+			finishRun(run, stepsSkipped.String())
+			ui.Error(err.Error())
+			return
+		}
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "\nREPOSITORY\tSTATUS\tTIME\tCOMMAND")
+	failed := len(refused)
+	for _, repo := range refused {
+		fmt.Fprintf(table, "%s\t%s\t\t\n", repo.Name, "refused")
+	}
+	for _, status := range statuses {
+		result := "passed"
+		switch {
+		case status.Command == "":
+			result = "skipped"
+		case status.Err != nil:
+			result = "failed"
+			failed++
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", status.Repo.Name, result, status.Duration.Round(time.Millisecond), status.Command)
+	}
+	table.Flush()
+
+	if failed > 0 {
+		ui.Error(fmt.Sprintf("%s failed in %d of %d repositories", stage, failed, len(repos)))
+		finishRun(run, stepsFailed.String())
+		return
+	}
+	ui.Success(fmt.Sprintf("%s finished in all %d repositories", stage, len(repos)))
+	finishRun(run, stepsPassed.String())
+}
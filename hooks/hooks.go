@@ -0,0 +1,196 @@
+package hooks
+
+import (
+	"automateLife/git"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Names are the git hooks automateLife can install
+var Names = []string{"pre-commit", "pre-push"}
+
+const (
+	marker   = "# Installed by automateLife"
+	settings = "# automateLife:"
+	// Suffix of a hook that was there before ours and now runs first
+	chainedSuffix = ".pre-automatelife"
+)
+
+var (
+	ErrUnknownHook   = errors.New("unknown hook")
+	ErrForeignHook   = errors.New("hook was not installed by automateLife")
+	ErrChainOccupied = errors.New("a chained hook is already in place")
+)
+
+// Options are the settings written into an installed hook
+type Options struct {
+	Executable string   // Absolute path of the automateLife binary
+	ConfigFile string   // Absolute path of the config file
+	Stages     []string // Stages to run, e.g. install, build, test
+	Changed    bool     // Only test the packages the commit or push changes
+}
+
+// Status describes one hook in a hooks directory
+type Status struct {
+	Name      string
+	Path      string
+	Installed bool // Ours
+	Foreign   bool // Someone else's hook is in place instead
+	Chained   bool // A hook that was there before ours runs first
+	Options   Options
+}
+
+// Dir returns the directory git runs the clone's hooks from
+func Dir(client git.Client, cloneDir string) (string, error) {
+	dir, err := client.GitPath(cloneDir, "hooks")
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the hooks directory: %w", err)
+	}
+	return dir, nil
+}
+
+// Install writes hook name into dir. A hook that is already there and not
+// ours is kept and run before ours, so nothing the user relied on is lost.
+func Install(dir string, name string, opts Options) (*Status, error) {
+	if !Known(name) {
+		return nil, fmt.Errorf("%w %q, expected one of: %s", ErrUnknownHook, name, strings.Join(Names, ", "))
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the hooks directory: %w", err)
+	}
+
+	status, err := Inspect(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if status.Foreign {
+		chained := status.Path + chainedSuffix
+		if _, err := os.Stat(chained); err == nil {
+			return nil, fmt.Errorf("%w at %s", ErrChainOccupied, chained)
+		}
+		if err := os.Rename(status.Path, chained); err != nil {
+			return nil, fmt.Errorf("failed to keep the existing %s hook: %w", name, err)
+		}
+	}
+
+	if err := os.WriteFile(status.Path, []byte(script(name, opts)), 0755); err != nil {
+		return nil, fmt.Errorf("failed to write the %s hook: %w", name, err)
+	}
+	// WriteFile leaves the mode of an existing file alone
+	if err := os.Chmod(status.Path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to make the %s hook executable: %w", name, err)
+	}
+	return Inspect(dir, name)
+}
+
+// Uninstall removes our hook name from dir and puts back the hook it
+// chained to. Hooks that aren't ours are left alone.
+func Uninstall(dir string, name string) (*Status, error) {
+	if !Known(name) {
+		return nil, fmt.Errorf("%w %q, expected one of: %s", ErrUnknownHook, name, strings.Join(Names, ", "))
+	}
+	status, err := Inspect(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if status.Foreign {
+		return status, fmt.Errorf("%s: %w", status.Path, ErrForeignHook)
+	}
+	if !status.Installed {
+		return status, nil
+	}
+
+	if err := os.Remove(status.Path); err != nil {
+		return nil, fmt.Errorf("failed to remove the %s hook: %w", name, err)
+	}
+	if status.Chained {
+		if err := os.Rename(status.Path+chainedSuffix, status.Path); err != nil {
+			return nil, fmt.Errorf("failed to restore the previous %s hook: %w", name, err)
+		}
+	}
+	return Inspect(dir, name)
+}
+
+// Inspect reports what is installed as hook name in dir
+func Inspect(dir string, name string) (*Status, error) {
+	status := &Status{Name: name, Path: filepath.Join(dir, name)}
+	if _, err := os.Stat(status.Path + chainedSuffix); err == nil {
+		status.Chained = true
+	}
+
+	data, err := os.ReadFile(status.Path)
+	if os.IsNotExist(err) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the %s hook: %w", name, err)
+	}
+	if !strings.Contains(string(data), marker) {
+		status.Foreign = true
+		return status, nil
+	}
+
+	status.Installed = true
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, settings) {
+			continue
+		}
+		for _, field := range strings.Fields(strings.TrimPrefix(line, settings)) {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "stages":
+				status.Options.Stages = strings.Split(value, ",")
+			case "changed":
+				status.Options.Changed, _ = strconv.ParseBool(value)
+			}
+		}
+	}
+	return status, nil
+}
+
+// script is the shell script git runs for hook name. It runs the chained
+// hook first, handing it the same arguments and input, then automateLife.
+func script(name string, opts Options) string {
+	run := []string{quote(opts.Executable), "hook-run", "--config", quote(opts.ConfigFile), "--stages", strings.Join(opts.Stages, ",")}
+	if opts.Changed {
+		run = append(run, "--changed")
+	}
+	run = append(run, name, `"$@"`)
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "%s, remove with 'automateLife hooks uninstall'\n", marker)
+	fmt.Fprintf(&b, "%s stages=%s changed=%t\n\n", settings, strings.Join(opts.Stages, ","), opts.Changed)
+	fmt.Fprintf(&b, "chained=\"$0%s\"\n", chainedSuffix)
+	if name == "pre-push" {
+		// git writes the pushed refs to stdin, both hooks need to read them
+		b.WriteString("input=$(mktemp) || exit 1\n")
+		b.WriteString("trap 'rm -f \"$input\"' EXIT\n")
+		b.WriteString("cat > \"$input\"\n")
+		b.WriteString("if [ -x \"$chained\" ]; then\n\t\"$chained\" \"$@\" < \"$input\" || exit $?\nfi\n")
+		fmt.Fprintf(&b, "%s < \"$input\"\n", strings.Join(run, " "))
+		return b.String()
+	}
+	b.WriteString("if [ -x \"$chained\" ]; then\n\t\"$chained\" \"$@\" || exit $?\nfi\n")
+	fmt.Fprintf(&b, "exec %s\n", strings.Join(run, " "))
+	return b.String()
+}
+
+// quote makes s a single word for sh
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Known reports whether name is one of Names
+func Known(name string) bool {
+	for _, n := range Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"automateLife/config"
+	"automateLife/git"
 	"automateLife/handlers"
 	"automateLife/ui"
 	"os"
@@ -9,6 +10,11 @@ import (
 )
 
 func main() {
+	// ssh runs this binary to ask for a key passphrase
+	if code, ok := git.Askpass(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
 	// Ensure HOME environment variable is set
 	if os.Getenv("HOME") == "" {
 		if currentUser, err := user.Current(); err == nil {
@@ -24,15 +30,45 @@ func main() {
 		return
 	}
 
+	// Every command but init may be run from a subdirectory such as the clone
+	if args[1] != "init" {
+		fileName = config.Find(fileName)
+	}
+
 	switch args[1] {
 	case "init":
-		handlers.HandleInit(fileName)
+		handlers.HandleInit(fileName, args[2:])
 	case "start":
-		handlers.HandleStart(fileName)
+		handlers.HandleStart(fileName, args[2:])
 	case "verify":
-		handlers.HandleVerify(fileName)
+		handlers.HandleVerify(fileName, args[2:])
 	case "test":
-		handlers.HandleTest(fileName)
+		handlers.HandleTest(fileName, args[2:])
+	case "watch":
+		handlers.HandleWatch(fileName, args[2:])
+	case "bisect":
+		handlers.HandleBisect(fileName, args[2:])
+	case "bisect-step":
+		// Run by git bisect run for every commit it checks out
+		if len(args) < 3 {
+			os.Exit(128)
+		}
+		os.Exit(handlers.HandleBisectStep(args[2]))
+	case "hooks":
+		handlers.HandleHooks(fileName, args[2:])
+	case "hook-run":
+		// Run by the git hooks 'hooks install' writes
+		os.Exit(handlers.HandleHookRun(args[2:]))
+	case "logs":
+		handlers.HandleLogs(fileName, args[2:])
+	case "config":
+		handlers.HandleConfig(fileName, args[2:])
+	case "workspace":
+		handlers.HandleWorkspace(fileName, args[2:])
+	case "cache":
+		handlers.HandleCache(args[2:])
+	case "credential":
+		handlers.HandleCredential(fileName, args[2:])
 	default:
 		showHelp()
 	}
@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Provider describes how a git hosting service lays out repository URLs
+// and which username it expects next to an access token
+type Provider struct {
+	Name      string   // Value of git.provider
+	Hosts     []string // Hosts of the hosted service, for HTTPS and SSH
+	TokenUser string   // Username sent with a token over HTTPS, empty for PATs without a user
+}
+
+var (
+	GitHub      = &Provider{Name: "github", Hosts: []string{"github.com"}, TokenUser: "x-access-token"}
+	GitLab      = &Provider{Name: "gitlab", Hosts: []string{"gitlab.com"}, TokenUser: "oauth2"}
+	Bitbucket   = &Provider{Name: "bitbucket", Hosts: []string{"bitbucket.org"}, TokenUser: "x-token-auth"}
+	AzureDevOps = &Provider{Name: "azure-devops", Hosts: []string{"dev.azure.com", "ssh.dev.azure.com", "visualstudio.com"}}
+)
+
+var all = []*Provider{GitHub, GitLab, Bitbucket, AzureDevOps}
+
+// Names lists the values accepted for git.provider
+func Names() []string {
+	names := make([]string, len(all))
+	for i, p := range all {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// Lookup returns the provider called name. An empty name returns nil,
+// which callers treat as a generic git server.
+func Lookup(name string) (*Provider, error) {
+	if name == "" {
+		return nil, nil
+	}
+	for _, p := range all {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown git provider %q, use one of: %s", name, strings.Join(Names(), ", "))
+}
+
+// FromURL guesses the provider of a repository URL, first from the hosted
+// services' hosts and then from a label of the host name of self-hosted
+// instances (e.g. gitlab.example.com). It returns nil when nothing matches,
+// and for local paths.
+func FromURL(raw string) *Provider {
+	parsed, err := ParseURL(raw)
+	if err != nil || parsed.Host == "" {
+		return nil
+	}
+	host := hostname(parsed.Host)
+	if p := hostedBy(host); p != nil {
+		return p
+	}
+	for _, label := range strings.Split(host, ".") {
+		for _, p := range []*Provider{GitHub, GitLab, Bitbucket} {
+			if label == p.Name {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// PullRequestRef returns the ref the provider publishes pull request n
+// under. merged reports that the ref is already the merge result with the
+// target branch: Azure DevOps doesn't publish the source branch's head.
+func (p *Provider) PullRequestRef(n int) (ref string, merged bool, err error) {
+	if n <= 0 {
+		return "", false, fmt.Errorf("invalid pull request number %d", n)
+	}
+	switch p {
+	case GitHub:
+		return fmt.Sprintf("refs/pull/%d/head", n), false, nil
+	case GitLab:
+		return fmt.Sprintf("refs/merge-requests/%d/head", n), false, nil
+	case Bitbucket:
+		return fmt.Sprintf("refs/pull-requests/%d/from", n), false, nil
+	case AzureDevOps:
+		return fmt.Sprintf("refs/pull/%d/merge", n), true, nil
+	}
+	return "", false, fmt.Errorf("pull requests can't be looked up without a provider, set git.provider to one of: %s", strings.Join(Names(), ", "))
+}
+
+// hostedBy returns the provider whose hosted service runs on host
+func hostedBy(host string) *Provider {
+	for _, p := range all {
+		for _, h := range p.Hosts {
+			if host == h || strings.HasSuffix(host, "."+h) {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+func hostname(host string) string {
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	return strings.ToLower(host)
+}
+
+// Repo is a repository URL broken down the way its provider structures it
+type Repo struct {
+	Provider *Provider
+	Scheme   string // http or https, used when building the HTTPS form
+	Host     string // Web host, e.g. github.com or dev.azure.com
+	Owner    string // User, organisation or group path; Azure DevOps organisation or collection
+	Project  string // Azure DevOps project, empty for other providers
+	Name     string
+}
+
+// Parse checks that raw is a repository URL of this provider and breaks it
+// down. URLs of another provider's hosted service are rejected, unknown
+// hosts are accepted as self-hosted instances.
+func (p *Provider) Parse(raw string) (*Repo, error) {
+	parsed, err := ParseURL(raw)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme == "file" || parsed.Host == "" {
+		return nil, fmt.Errorf("%s is a local path, not a %s repository URL", raw, p.Name)
+	}
+
+	host := hostname(parsed.Host)
+	if owner := hostedBy(host); owner != nil && owner != p {
+		return nil, fmt.Errorf("%s is a %s URL but git.provider is %s", raw, owner.Name, p.Name)
+	}
+
+	repo := &Repo{Provider: p, Scheme: "https", Host: parsed.Host, Name: parsed.Name}
+	if parsed.Scheme == "http" {
+		repo.Scheme = "http"
+	} else if parsed.Scheme != "https" {
+		repo.Host = host // SSH ports don't carry over to HTTPS
+	}
+
+	segments := strings.Split(parsed.Path, "/")
+	switch p {
+	case AzureDevOps:
+		err = repo.parseAzure(segments)
+	case GitLab:
+		// Groups can be nested
+		if len(segments) < 2 {
+			err = fmt.Errorf("expected %s/<group>/<repo>", host)
+		}
+		repo.Owner = strings.Join(segments[:len(segments)-1], "/")
+	default:
+		if len(segments) != 2 {
+			err = fmt.Errorf("expected %s/<owner>/<repo>", host)
+		}
+		repo.Owner = segments[0]
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid %s repository URL: %w", raw, p.Name, err)
+	}
+	return repo, nil
+}
+
+// parseAzure understands dev.azure.com/org/project/_git/repo,
+// org.visualstudio.com/[DefaultCollection/]project/_git/repo,
+// ssh.dev.azure.com:v3/org/project/repo and on-premises
+// server/collection/project/_git/repo URLs
+func (r *Repo) parseAzure(segments []string) error {
+	host := hostname(r.Host)
+	gitIndex := -1
+	for i, segment := range segments {
+		if segment == "_git" {
+			gitIndex = i
+		}
+	}
+
+	switch {
+	case gitIndex >= 1 && gitIndex == len(segments)-2:
+		r.Project = segments[gitIndex-1]
+		r.Owner = strings.Join(segments[:gitIndex-1], "/")
+	case hostedBy(host) == AzureDevOps && len(segments) == 3:
+		// SSH form, the v3/ prefix is already stripped
+		r.Owner, r.Project = segments[0], segments[1]
+	default:
+		return fmt.Errorf("expected dev.azure.com/<organization>/<project>/_git/<repo>")
+	}
+
+	switch {
+	case host == "dev.azure.com" || host == "ssh.dev.azure.com" || host == "vs-ssh.visualstudio.com":
+		r.Host = "dev.azure.com"
+	case strings.HasSuffix(host, ".visualstudio.com"):
+		// Legacy URLs carry the organisation in the host name
+		if r.Owner == "" || strings.EqualFold(r.Owner, "DefaultCollection") {
+			r.Owner = strings.TrimSuffix(host, ".visualstudio.com")
+		}
+		r.Host = "dev.azure.com"
+	}
+	if r.Host == "dev.azure.com" && r.Owner == "" {
+		return fmt.Errorf("missing organization")
+	}
+	return nil
+}
+
+// HTTPS returns the canonical HTTPS clone URL
+func (r *Repo) HTTPS() string {
+	if r.Provider == AzureDevOps {
+		path := strings.Trim(strings.Join([]string{r.Owner, r.Project, "_git", r.Name}, "/"), "/")
+		return fmt.Sprintf("%s://%s/%s", r.Scheme, r.Host, path)
+	}
+	return fmt.Sprintf("%s://%s/%s/%s.git", r.Scheme, r.Host, r.Owner, r.Name)
+}
+
+// SSH returns the canonical SSH clone URL
+func (r *Repo) SSH() string {
+	host := hostname(r.Host)
+	if r.Provider == AzureDevOps {
+		if host == "dev.azure.com" {
+			return fmt.Sprintf("git@ssh.dev.azure.com:v3/%s/%s/%s", r.Owner, r.Project, r.Name)
+		}
+		path := strings.Trim(strings.Join([]string{r.Owner, r.Project, "_git", r.Name}, "/"), "/")
+		return fmt.Sprintf("ssh://%s/%s", host, path)
+	}
+	return fmt.Sprintf("git@%s:%s/%s.git", host, r.Owner, r.Name)
+}
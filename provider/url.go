@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// URL is a repository URL broken into its parts
+type URL struct {
+	Scheme string // https, http, ssh, git, file, or "scp" for git@host:path
+	User   string
+	Host   string // Includes the port when one was given
+	Path   string // Without leading/trailing slashes and without the .git suffix
+	Name   string // Last path segment, the directory git clones into
+}
+
+// Matches scp-like URLs such as git@github.com:org/repo.git
+var scpURL = regexp.MustCompile(`^(?:([^@/]+)@)?([^:/]+):(.+)$`)
+
+// ParseURL parses HTTPS, SSH, scp-style (git@host:org/repo.git),
+// git://, file:// URLs and plain local paths, including Azure DevOps
+// "_git" and "v3" forms
+func ParseURL(raw string) (*URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("repository URL is empty")
+	}
+
+	parsed := &URL{}
+	switch {
+	case strings.Contains(raw, "://"):
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repository URL %q: %w", raw, err)
+		}
+		parsed.Scheme = u.Scheme
+		parsed.Host = u.Host
+		if u.User != nil {
+			parsed.User = u.User.Username()
+		}
+		parsed.Path = u.Path
+	case scpURL.MatchString(raw) && !strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "."):
+		match := scpURL.FindStringSubmatch(raw)
+		parsed.Scheme = "scp"
+		parsed.User = match[1]
+		parsed.Host = match[2]
+		parsed.Path = match[3]
+	default:
+		parsed.Scheme = "file"
+		parsed.Path = raw
+	}
+
+	parsed.Path = strings.Trim(parsed.Path, "/")
+	parsed.Path = strings.TrimSuffix(parsed.Path, ".git")
+	if parsed.Scheme != "file" {
+		parsed.Path = strings.TrimPrefix(parsed.Path, "v3/") // Azure DevOps SSH
+	}
+
+	segments := strings.Split(parsed.Path, "/")
+	parsed.Name = segments[len(segments)-1]
+	if parsed.Name == "" || parsed.Name == "_git" {
+		return nil, fmt.Errorf("repository URL %q has no repository name", raw)
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,124 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every secret in redacted text
+const Mask = "********"
+
+// Values shorter than this are too likely to appear in normal output
+const minLength = 4
+
+var (
+	mu      sync.RWMutex
+	secrets = map[string]bool{}
+)
+
+// Add registers secret values to scrub from output, together with the
+// URL-escaped and base64 forms they can show up in
+func Add(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, value := range values {
+		if len(value) < minLength {
+			continue
+		}
+		secrets[value] = true
+		secrets[url.QueryEscape(value)] = true
+		secrets[strings.ReplaceAll(url.QueryEscape(value), "+", "%20")] = true
+		secrets[url.PathEscape(value)] = true
+		secrets[base64.StdEncoding.EncodeToString([]byte(value))] = true
+	}
+}
+
+// Reset forgets every registered secret
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	secrets = map[string]bool{}
+}
+
+// String returns s with every registered secret masked
+func String(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(secrets) == 0 {
+		return s
+	}
+
+	// Longest first, so a secret containing another is masked whole
+	values := make([]string, 0, len(secrets))
+	for value := range secrets {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		s = strings.ReplaceAll(s, value, Mask)
+	}
+	return s
+}
+
+// Writer masks secrets in everything written through it. Output that could
+// be the start of a secret is held back until the next write, so a secret
+// split across writes is still caught; call Flush once the writer is done.
+type Writer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf []byte
+}
+
+// NewWriter wraps w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (rw *Writer) Write(p []byte) (int, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	rw.buf = append(rw.buf, p...)
+	ready := len(rw.buf) - partialSecret(rw.buf)
+	if ready > 0 {
+		if _, err := io.WriteString(rw.w, String(string(rw.buf[:ready]))); err != nil {
+			return 0, err
+		}
+		rw.buf = append(rw.buf[:0], rw.buf[ready:]...)
+	}
+	return len(p), nil
+}
+
+// partialSecret returns the length of the longest suffix of buf that is the
+// beginning of a registered secret
+func partialSecret(buf []byte) int {
+	mu.RLock()
+	defer mu.RUnlock()
+	longest := 0
+	for value := range secrets {
+		for n := min(len(value)-1, len(buf)); n > longest; n-- {
+			if bytes.HasSuffix(buf, []byte(value[:n])) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
+
+// Flush writes out any incomplete last line
+func (rw *Writer) Flush() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if len(rw.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(rw.w, String(string(rw.buf)))
+	rw.buf = rw.buf[:0]
+	return err
+}
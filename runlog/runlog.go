@@ -0,0 +1,392 @@
+package runlog
+
+import (
+	"automateLife/builder"
+	"automateLife/redact"
+	"automateLife/state"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DirName is the directory in the state directory holding one directory
+// per run
+const DirName = "runs"
+
+// DefaultKeep is how many runs are kept unless configured otherwise
+const DefaultKeep = 20
+
+const metaFileName = "run.json"
+
+// abandonedAfter is how long an unfinished run is taken to be still going.
+// Older ones were stopped before they could record a result.
+const abandonedAfter = 24 * time.Hour
+
+// ErrNoRuns is returned when no run has been logged yet
+var ErrNoRuns = errors.New("no runs logged yet")
+
+// Options controls how step output is written to the logs
+type Options struct {
+	Timestamps bool // Start every line with the time it was written
+	Streams    bool // Tag every line with out or err
+	Keep       int  // Runs to keep, DefaultKeep when 0
+}
+
+// Run is one run of the pipeline, saved as a directory of step logs and
+// a run.json describing them
+type Run struct {
+	ID       string        `json:"id"`
+	Title    string        `json:"title"`              // What ran, e.g. "test" or "hook pre-commit"
+	Revision string        `json:"revision,omitempty"` // Commit the run tested
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Result   string        `json:"result"` // Empty while the run goes on, or when it never finished
+	Steps    []*StepRecord `json:"steps"`
+
+	dir     string
+	options Options
+	mu      sync.Mutex
+}
+
+// StepRecord describes the log of one step
+type StepRecord struct {
+	Name     string        `json:"name"` // e.g. "test", or "api/test" in a workspace
+	Command  string        `json:"command"`
+	Log      string        `json:"log"` // Relative to the run directory
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Result   string        `json:"result"` // passed, failed, timed out or interrupted, empty while running
+	Error    string        `json:"error,omitempty"`
+}
+
+// Root returns the directory holding the runs of the given config file
+func Root(configFile string) string {
+	return filepath.Join(state.Dir(configFile), DirName)
+}
+
+// Start creates the directory of a new run in root, removing the oldest
+// runs beyond the number to keep. Runs still going, in another process
+// say, are left alone.
+func Start(root string, title string, options Options) (*Run, error) {
+	keep := options.Keep
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	if runs, err := List(root); err == nil {
+		excess := len(runs) - keep + 1
+		for _, old := range runs {
+			if excess <= 0 {
+				break
+			}
+			if old.Result == "" && time.Since(old.Started) < abandonedAfter {
+				continue
+			}
+			os.RemoveAll(old.dir)
+			excess--
+		}
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create run log directory: %w", err)
+	}
+	started := time.Now()
+	id := started.Format("20060102-150405")
+	for i := 2; ; i++ {
+		err := os.Mkdir(filepath.Join(root, id), 0755)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create run log directory: %w", err)
+		}
+		id = fmt.Sprintf("%s-%d", started.Format("20060102-150405"), i)
+	}
+
+	r := &Run{ID: id, Title: title, Started: started, Steps: []*StepRecord{}, dir: filepath.Join(root, id), options: options}
+	if err := r.save(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Dir returns the directory of the run
+func (r *Run) Dir() string {
+	return r.dir
+}
+
+// Step opens the log of the named step. Its writers tee into the log,
+// Finish closes it.
+func (r *Run) Step(name string, command string) (*Step, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	logName := logFileName(name)
+	for i := 2; r.logTaken(logName); i++ {
+		logName = logFileName(fmt.Sprintf("%s-%d", name, i))
+	}
+	path := filepath.Join(r.dir, filepath.FromSlash(logName))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create step log: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create step log: %w", err)
+	}
+
+	record := &StepRecord{Name: name, Command: redact.String(command), Log: logName, Started: time.Now()}
+	r.Steps = append(r.Steps, record)
+	if err := r.saveLocked(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	log := &logWriter{file: file, options: r.options}
+	return &Step{run: r, record: record, log: log, stdout: &streamWriter{log: log, tag: "out"}, stderr: &streamWriter{log: log, tag: "err"}}, nil
+}
+
+func (r *Run) logTaken(logName string) bool {
+	for _, step := range r.Steps {
+		if step.Log == logName {
+			return true
+		}
+	}
+	return false
+}
+
+// RunStep runs step, saving its output in the run as well as writing it to
+// stdout and stderr. A nil run only runs the step, and neither does a log
+// that can't be created stop it from running.
+func (r *Run) RunStep(ctx context.Context, step builder.Step, stdout io.Writer, stderr io.Writer) error {
+	if r == nil {
+		return step.Run(ctx, stdout, stderr)
+	}
+	log, err := r.Step(step.Name, step.Command)
+	if err != nil {
+		fmt.Fprintf(stderr, "automateLife: not saving the %s log: %v\n", step.Name, err)
+		return step.Run(ctx, stdout, stderr)
+	}
+	err = step.Run(ctx, io.MultiWriter(stdout, log.Stdout()), io.MultiWriter(stderr, log.Stderr()))
+	if finishErr := log.Finish(err); finishErr != nil {
+		fmt.Fprintf(stderr, "automateLife: %v\n", finishErr)
+	}
+	return err
+}
+
+// Finish records the result of the run, such as passed or failed. It does
+// nothing for a nil run.
+func (r *Run) Finish(result string) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Result = result
+	r.Duration = time.Since(r.Started)
+	return r.saveLocked()
+}
+
+func (r *Run) save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.saveLocked()
+}
+
+func (r *Run) saveLocked() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+	// Readers of a run still going on never see half a file
+	tmp := filepath.Join(r.dir, metaFileName+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(r.dir, metaFileName)); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	return nil
+}
+
+// Step is the log of a running step
+type Step struct {
+	run    *Run
+	record *StepRecord
+	log    *logWriter
+	stdout *streamWriter
+	stderr *streamWriter
+}
+
+// Stdout returns the writer for the standard output of the step
+func (s *Step) Stdout() io.Writer {
+	return s.stdout
+}
+
+// Stderr returns the writer for the standard error of the step
+func (s *Step) Stderr() io.Writer {
+	return s.stderr
+}
+
+// Finish closes the log and records how the step ended
+func (s *Step) Finish(err error) error {
+	closeErr := s.log.Close()
+
+	s.run.mu.Lock()
+	defer s.run.mu.Unlock()
+	s.record.Duration = time.Since(s.record.Started)
+	s.record.Result = StepResult(err)
+	if err != nil {
+		s.record.Error = redact.String(err.Error())
+	}
+	if saveErr := s.run.saveLocked(); saveErr != nil {
+		return saveErr
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to write the %s log: %w", s.record.Name, closeErr)
+	}
+	return nil
+}
+
+// StepResult describes how a step that returned err ended
+func StepResult(err error) string {
+	var timeout *builder.TimeoutError
+	switch {
+	case err == nil:
+		return "passed"
+	case errors.Is(err, builder.ErrInterrupted):
+		return "interrupted"
+	case errors.As(err, &timeout):
+		return "timed out"
+	}
+	return "failed"
+}
+
+// List returns the runs in root, oldest first
+func List(root string) ([]*Run, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run logs: %w", err)
+	}
+
+	var runs []*Run
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		run, err := load(filepath.Join(root, entry.Name()))
+		if err != nil {
+			// Not a run, or one that never got started properly
+			continue
+		}
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		if !runs[i].Started.Equal(runs[j].Started) {
+			return runs[i].Started.Before(runs[j].Started)
+		}
+		return runs[i].ID < runs[j].ID
+	})
+	return runs, nil
+}
+
+func load(dir string) (*Run, error) {
+	data, err := os.ReadFile(filepath.Join(dir, metaFileName))
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(dir, metaFileName), err)
+	}
+	run.dir = dir
+	return &run, nil
+}
+
+// Find returns the run in root named by ref: its ID, a unique start of it,
+// or "last" (also when empty) for the latest run
+func Find(root string, ref string) (*Run, error) {
+	runs, err := List(root)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, ErrNoRuns
+	}
+	if ref == "" || ref == "last" {
+		return runs[len(runs)-1], nil
+	}
+
+	var matches []*Run
+	for _, run := range runs {
+		if run.ID == ref {
+			return run, nil
+		}
+		if strings.HasPrefix(run.ID, ref) {
+			matches = append(matches, run)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no run %q, see automateLife logs for the runs kept", ref)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("%q matches %d runs, give more of the run ID", ref, len(matches))
+}
+
+// FindStep returns the step of the run named name, or the single step
+// ending in /name, such as "api/test" for "test"
+func (r *Run) FindStep(name string) (*StepRecord, error) {
+	var matches []*StepRecord
+	for _, step := range r.Steps {
+		if step.Name == name || strings.TrimSuffix(step.Log, ".log") == name {
+			return step, nil
+		}
+		if strings.HasSuffix(step.Name, "/"+name) {
+			matches = append(matches, step)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+
+	names := make([]string, len(r.Steps))
+	for i, step := range r.Steps {
+		names[i] = step.Name
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("several steps of run %s are called %s: %s", r.ID, name, strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("run %s has no step %s, it has: %s", r.ID, name, strings.Join(names, ", "))
+}
+
+// LogPath returns the log file of step
+func (r *Run) LogPath(step *StepRecord) string {
+	return filepath.Join(r.dir, filepath.FromSlash(step.Log))
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// logFileName turns a step name into a log path relative to the run
+// directory, keeping slashes as subdirectories
+func logFileName(name string) string {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		part = strings.Trim(unsafeName.ReplaceAllString(part, "-"), "-.")
+		if part == "" {
+			part = "step"
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, "/") + ".log"
+}
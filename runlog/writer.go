@@ -0,0 +1,95 @@
+package runlog
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"time"
+)
+
+// TimeFormat is how timestamps start the lines of a log
+const TimeFormat = "2006-01-02T15:04:05.000"
+
+// logWriter is the log file of one step, shared by its output streams
+type logWriter struct {
+	mu      sync.Mutex
+	file    *os.File
+	options Options
+	open    *streamWriter // Stream in the middle of a line, if any
+	err     error         // The first failed write, the log is incomplete after it
+}
+
+// streamWriter writes one output stream of a step to its log, starting
+// every line with a timestamp and stream tag when the options ask for them.
+// It never fails: a log that can't be written, on a full disk say, mustn't
+// stop the step, so the first error is kept for Close to report.
+type streamWriter struct {
+	log *logWriter
+	tag string
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	log := w.log
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if log.err != nil {
+		return len(p), nil
+	}
+	if !log.options.Timestamps && !log.options.Streams {
+		if _, err := log.file.Write(p); err != nil {
+			log.err = err
+		}
+		return len(p), nil
+	}
+
+	var buf bytes.Buffer
+	// A line left open by the other stream is ended, so each keeps its tag
+	if log.open != nil && log.open != w {
+		buf.WriteByte('\n')
+		log.open = nil
+	}
+	for rest := p; len(rest) > 0; {
+		if log.open == nil {
+			w.writePrefix(&buf)
+		}
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			buf.Write(rest)
+			log.open = w
+			break
+		}
+		buf.Write(rest[:i+1])
+		rest = rest[i+1:]
+		log.open = nil
+	}
+	if _, err := log.file.Write(buf.Bytes()); err != nil {
+		log.err = err
+	}
+	return len(p), nil
+}
+
+func (w *streamWriter) writePrefix(buf *bytes.Buffer) {
+	if w.log.options.Timestamps {
+		buf.WriteString(time.Now().Format(TimeFormat))
+		buf.WriteByte(' ')
+	}
+	if w.log.options.Streams {
+		buf.WriteString("[" + w.tag + "] ")
+	}
+}
+
+// Close ends a line left open and closes the file, returning the first
+// write that failed, if any
+func (l *logWriter) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.open != nil && l.err == nil {
+		l.file.Write([]byte("\n"))
+		l.open = nil
+	}
+	closeErr := l.file.Close()
+	if l.err != nil {
+		return l.err
+	}
+	return closeErr
+}
@@ -0,0 +1,338 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ErrNeedsShell is returned for scripts using pipes, redirections and the
+// like, which only run through /bin/sh
+var ErrNeedsShell = errors.New("needs a shell")
+
+// Command is one command of a script, ready to be run without a shell
+type Command struct {
+	Env  []string // NAME=value assignments in front of the command
+	Args []string
+}
+
+// String quotes the command back into a line Parse reads as the same command
+func (c Command) String() string {
+	var words []string
+	for _, assignment := range c.Env {
+		name, value, _ := strings.Cut(assignment, "=")
+		words = append(words, name+"="+Quote(value))
+	}
+	return strings.TrimSpace(strings.Join(words, " ") + " " + Join(c.Args))
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// word is a word being read, remembering where quoting started so that
+// only an unquoted NAME= counts as an assignment
+type word struct {
+	text     strings.Builder
+	started  bool
+	quotedAt int
+}
+
+func (w *word) quoted() {
+	if w.quotedAt < 0 {
+		w.quotedAt = w.text.Len()
+	}
+	w.started = true
+}
+
+// assignment reports whether the word read so far starts with an unquoted NAME=
+func (w *word) assignment() bool {
+	text := w.text.String()
+	eq := strings.IndexByte(text, '=')
+	return eq > 0 && (w.quotedAt < 0 || w.quotedAt > eq) && namePattern.MatchString(text[:eq])
+}
+
+// Parse splits a script into commands with POSIX shell quoting rules:
+// single and double quotes, backslash escapes and line continuations.
+// $NAME and ${NAME} are replaced with environment variables outside single
+// quotes. As in sh, an unquoted value is split into several words at
+// spaces, tabs and newlines, except when it is assigned, but its globs are
+// not expanded. ~ at the start of a word is replaced with the home
+// directory. Every line
+// is a command of its own, and so is every part of a line joined with &&.
+// Blank lines and # comments are skipped. Anything else a shell would
+// interpret, such as pipes, redirections, globs, ; or $(...), is refused
+// with ErrNeedsShell.
+func Parse(script string) ([]Command, error) {
+	return ParseEnv(script, os.Getenv)
+}
+
+// ParseEnv is Parse with variables looked up by getenv rather than in the
+// environment of automateLife
+func ParseEnv(script string, getenv func(string) string) ([]Command, error) {
+	var (
+		commands []Command
+		current  Command
+		inArgs   bool
+		w        = word{quotedAt: -1}
+		chained  bool // The last command ended with &&
+	)
+
+	endWord := func() {
+		if !w.started {
+			return
+		}
+		text := w.text.String()
+		if !inArgs && w.assignment() {
+			current.Env = append(current.Env, text)
+		} else {
+			current.Args = append(current.Args, text)
+			inArgs = true
+		}
+		w = word{quotedAt: -1}
+	}
+	endCommand := func(and bool) error {
+		endWord()
+		if len(current.Args) == 0 {
+			if len(current.Env) > 0 {
+				return fmt.Errorf("%q assigns variables but runs no command", strings.Join(current.Env, " "))
+			}
+			// A line may end with && and carry on with the next one
+			if and {
+				return fmt.Errorf("&& needs a command on both sides")
+			}
+			return nil
+		}
+		commands = append(commands, current)
+		current, inArgs, chained = Command{}, false, and
+		return nil
+	}
+	needsShell := func(syntax string) error {
+		return fmt.Errorf("%w to run %q", ErrNeedsShell, syntax)
+	}
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch r {
+		case ' ', '\t', '\r':
+			endWord()
+		case '\n':
+			if err := endCommand(false); err != nil {
+				return nil, err
+			}
+		case '\\':
+			switch {
+			case next == '\n':
+				i++ // Line continuation
+			case next != 0:
+				w.quoted()
+				w.text.WriteRune(next)
+				i++
+			default:
+				w.started = true
+				w.text.WriteRune(r)
+			}
+		case '\'':
+			w.quoted()
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			w.text.WriteString(string(runes[i+1 : end]))
+			i = end
+		case '"':
+			w.quoted()
+			closed := false
+			for i++; i < len(runes); i++ {
+				c := runes[i]
+				if c == '"' {
+					closed = true
+					break
+				}
+				if c == '`' {
+					return nil, needsShell("`")
+				}
+				if c == '$' {
+					value, end, err := variable(runes, i, getenv)
+					if err != nil {
+						return nil, err
+					}
+					w.text.WriteString(value)
+					i = end
+					continue
+				}
+				if c == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+					i++
+					if runes[i] != '\n' {
+						w.text.WriteRune(runes[i])
+					}
+					continue
+				}
+				w.text.WriteRune(c)
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+		case '#':
+			if w.started {
+				w.text.WriteRune(r)
+				continue
+			}
+			// A comment runs to the end of the line
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case '&':
+			if next != '&' {
+				return nil, needsShell("&")
+			}
+			i++
+			if err := endCommand(true); err != nil {
+				return nil, err
+			}
+		case '|':
+			if next == '|' {
+				return nil, needsShell("||")
+			}
+			return nil, needsShell("|")
+		case ';', '<', '>', '(', ')', '`', '*', '?', '[':
+			return nil, needsShell(string(r))
+		case '$':
+			value, end, err := variable(runes, i, getenv)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			// Like in a shell, an unquoted value is split into words on
+			// whitespace, and an empty one makes no word. Assigned values
+			// are kept whole.
+			if !inArgs && w.assignment() {
+				w.text.WriteString(value)
+				continue
+			}
+			for _, c := range value {
+				if c == ' ' || c == '\t' || c == '\n' {
+					endWord()
+					continue
+				}
+				w.started = true
+				w.text.WriteRune(c)
+			}
+		case '~':
+			if !w.started && (next == 0 || next == '/' || unicode.IsSpace(next)) {
+				if home, err := os.UserHomeDir(); err == nil {
+					w.started = true
+					w.text.WriteString(home)
+					continue
+				}
+			}
+			w.started = true
+			w.text.WriteRune(r)
+		default:
+			w.started = true
+			w.text.WriteRune(r)
+		}
+	}
+	if err := endCommand(false); err != nil {
+		return nil, err
+	}
+	if chained {
+		return nil, fmt.Errorf("&& needs a command on both sides")
+	}
+	return commands, nil
+}
+
+// variable reads the expansion starting with the $ at runes[i] and returns
+// its value and the index of its last rune. A $ starting no name stays as
+// it is, special parameters such as $1 or $? and ${NAME:-default} forms
+// need a shell.
+func variable(runes []rune, i int, getenv func(string) string) (string, int, error) {
+	if i+1 >= len(runes) {
+		return "$", i, nil
+	}
+	next := runes[i+1]
+	switch {
+	case next == '(':
+		return "", i, fmt.Errorf("%w to run %q", ErrNeedsShell, "$(")
+	case next == '{':
+		end := indexRune(runes, i+2, '}')
+		if end < 0 {
+			return "", i, fmt.Errorf("unterminated ${")
+		}
+		name := string(runes[i+2 : end])
+		if !namePattern.MatchString(name) {
+			return "", i, fmt.Errorf("%w to run %q", ErrNeedsShell, "${"+name+"}")
+		}
+		return getenv(name), end, nil
+	case isNameRune(next, true):
+		end := i + 1
+		for end+1 < len(runes) && isNameRune(runes[end+1], false) {
+			end++
+		}
+		return getenv(string(runes[i+1 : end+1])), end, nil
+	case '0' <= next && next <= '9' || strings.ContainsRune("?$@*#!-", next):
+		return "", i, fmt.Errorf("%w to run %q", ErrNeedsShell, "$"+string(next))
+	}
+	return "$", i, nil
+}
+
+func isNameRune(r rune, first bool) bool {
+	return r == '_' || 'A' <= r && r <= 'Z' || 'a' <= r && r <= 'z' || !first && '0' <= r && r <= '9'
+}
+
+// Split parses a single command line into its words
+func Split(line string) ([]string, error) {
+	commands, err := Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(commands) != 1 || len(commands[0].Env) > 0 {
+		return nil, fmt.Errorf("%q is not a single command", line)
+	}
+	return commands[0].Args, nil
+}
+
+// NeedsShell reports whether script only runs through /bin/sh
+func NeedsShell(script string) bool {
+	_, err := Parse(script)
+	return errors.Is(err, ErrNeedsShell)
+}
+
+var safePattern = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// Quote returns s as a single word for Parse and /bin/sh
+func Quote(s string) string {
+	if safePattern.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Join quotes args into a command line that Parse splits back into the
+// same words. A leading NAME=value word is quoted so it stays an argument.
+func Join(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = Quote(arg)
+	}
+	if len(args) > 0 && quoted[0] == args[0] && strings.Contains(args[0], "=") {
+		quoted[0] = "'" + args[0] + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,65 @@
+package state
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"fmt"
+	"path/filepath"
+)
+
+// ConfiguredCloneDir returns the absolute checkout path the config asks for:
+// git.clone_dir, or the repository name, relative to the config file
+func ConfiguredCloneDir(configFile string, cfg *config.GitConfig) (string, error) {
+	dir := cfg.CloneDir
+	if dir == "" {
+		dir = git.GetProjectDirName(cfg.RepoUrl)
+		if dir == "" {
+			return "", fmt.Errorf("could not determine a clone directory from repo_url %q, set git.clone_dir", cfg.RepoUrl)
+		}
+	}
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir), nil
+	}
+
+	absConfig, err := filepath.Abs(configFile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(absConfig), dir), nil
+}
+
+// CloneDir returns the checkout path recorded by the last 'start', as long
+// as it still belongs to the configured repository and clone_dir, and
+// falls back to the configured path otherwise
+func CloneDir(configFile string, cfg *config.GitConfig) (string, error) {
+	configured, err := ConfiguredCloneDir(configFile, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	recorded, err := Load(configFile)
+	if err != nil || recorded.CloneDir == "" || !git.SameRemote(recorded.RepoUrl, cfg.RepoUrl) {
+		return configured, nil
+	}
+	if cfg.CloneDir != "" && recorded.CloneDir != configured {
+		return configured, nil
+	}
+	return recorded.CloneDir, nil
+}
+
+// NewRevision records commit as the checkout of ref
+func NewRevision(ref string, commit git.Commit) *Revision {
+	return &Revision{
+		Ref:     ref,
+		SHA:     commit.SHA,
+		Author:  commit.Author,
+		Email:   commit.Email,
+		Date:    commit.Date,
+		Subject: commit.Subject,
+	}
+}
+
+// Commit converts the revision back into a git.Commit
+func (r *Revision) Commit() git.Commit {
+	return git.Commit{SHA: r.SHA, Author: r.Author, Email: r.Email, Date: r.Date, Subject: r.Subject}
+}
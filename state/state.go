@@ -0,0 +1,83 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DirName is the directory next to the config file that holds run state
+const DirName = ".automatelife"
+
+const fileName = "state.json"
+
+// State is what one command leaves behind for the next one
+type State struct {
+	RepoUrl   string    `json:"repo_url"`
+	CloneDir  string    `json:"clone_dir"` // Absolute path of the checkout
+	Branch    string    `json:"branch"`
+	Revision  *Revision `json:"revision,omitempty"` // Commit checked out by the last 'start'
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Revision records exactly which commit was checked out, so a run can be
+// traced back to its source
+type Revision struct {
+	Ref     string    `json:"ref"` // Branch, tag or SHA that was asked for
+	SHA     string    `json:"sha"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+}
+
+// Dir returns the state directory belonging to the given config file
+func Dir(configFile string) string {
+	absPath, err := filepath.Abs(configFile)
+	if err != nil {
+		absPath = configFile
+	}
+	return filepath.Join(filepath.Dir(absPath), DirName)
+}
+
+// Path returns the state file belonging to the given config file
+func Path(configFile string) string {
+	return filepath.Join(Dir(configFile), fileName)
+}
+
+// Load reads the state for configFile. A missing state file is not an
+// error and yields an empty state.
+func Load(configFile string) (*State, error) {
+	data, err := os.ReadFile(Path(configFile))
+	if os.IsNotExist(err) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode state file %s: %w", Path(configFile), err)
+	}
+	return &s, nil
+}
+
+// Save writes the state for configFile, creating the state directory if needed
+func Save(configFile string, s *State) error {
+	if err := os.MkdirAll(Dir(configFile), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	s.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if err := os.WriteFile(Path(configFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const watchFileName = "watch.json"
+
+// Watch is the last commit 'watch --remote' ran the pipeline for. It is
+// kept apart from State, which every 'start' rewrites.
+type Watch struct {
+	RepoUrl     string    `json:"repo_url"`
+	Branch      string    `json:"branch"`
+	SHA         string    `json:"sha"`
+	Passed      bool      `json:"passed"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// WatchPath returns the watch state file belonging to the given config file
+func WatchPath(configFile string) string {
+	return filepath.Join(Dir(configFile), watchFileName)
+}
+
+// LoadWatch reads the watch state for configFile. A missing file yields an
+// empty state.
+func LoadWatch(configFile string) (*Watch, error) {
+	data, err := os.ReadFile(WatchPath(configFile))
+	if os.IsNotExist(err) {
+		return &Watch{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch state: %w", err)
+	}
+
+	var w Watch
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("failed to decode watch state %s: %w", WatchPath(configFile), err)
+	}
+	return &w, nil
+}
+
+// SaveWatch writes the watch state for configFile
+func SaveWatch(configFile string, w *Watch) error {
+	if err := os.MkdirAll(Dir(configFile), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	w.ProcessedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal watch state: %w", err)
+	}
+	// Written to a temporary file first so an interrupted watch never leaves a truncated state
+	tmp := WatchPath(configFile) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	if err := os.Rename(tmp, WatchPath(configFile)); err != nil {
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	return nil
+}
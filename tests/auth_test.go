@@ -165,76 +165,90 @@ func TestBuildAuthURL(t *testing.T) {
 	}
 }
 
-func TestSetupSSH(t *testing.T) {
-	// Save original HOME
-	originalHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", originalHome)
-
+func TestSSHCommand(t *testing.T) {
 	tmpDir := t.TempDir()
-	os.Setenv("HOME", tmpDir)
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "config"))
+	t.Setenv("GIT_SSH_COMMAND", "")
 
-	// Create a test SSH key
-	sshDir := filepath.Join(tmpDir, ".ssh")
+	// Create a test SSH key in a directory with a space
+	sshDir := filepath.Join(tmpDir, "my keys")
 	os.Mkdir(sshDir, 0700)
 	sshKey := filepath.Join(sshDir, "id_rsa")
 	os.WriteFile(sshKey, []byte("test key"), 0600)
 
 	tests := []struct {
 		name        string
-		keyPath     string
-		expectError bool
+		config      config.GitConfig
+		agentSocket string
+		contains    []string
 		errorMsg    string
 	}{
 		{
-			name:        "Valid absolute path",
-			keyPath:     sshKey,
-			expectError: false,
+			name:     "Key path with a space is quoted",
+			config:   config.GitConfig{SSHKeyPath: sshKey},
+			contains: []string{"-i '" + sshKey + "'", "IdentitiesOnly=yes", "StrictHostKeyChecking=yes"},
 		},
 		{
-			name:        "Valid path with tilde",
-			keyPath:     "~/.ssh/id_rsa",
-			expectError: false,
+			name:     "Valid path with tilde",
+			config:   config.GitConfig{SSHKeyPath: "~/my keys/id_rsa"},
+			contains: []string{"-i '" + sshKey + "'"},
 		},
 		{
-			name:        "Empty key path",
-			keyPath:     "",
-			expectError: true,
-			errorMsg:    "ssh_key_path must be provided",
+			name:        "ssh-agent without a key",
+			config:      config.GitConfig{SSHAgent: true},
+			agentSocket: "/tmp/agent.sock",
+			contains:    []string{"StrictHostKeyChecking=yes"},
 		},
 		{
-			name:        "Non-existent key",
-			keyPath:     "/nonexistent/key",
-			expectError: true,
-			errorMsg:    "SSH key not found",
+			name:     "ssh-agent without a running agent",
+			config:   config.GitConfig{SSHAgent: true},
+			errorMsg: "SSH_AUTH_SOCK is not set",
+		},
+		{
+			name:     "Empty key path",
+			config:   config.GitConfig{},
+			errorMsg: "ssh_key_path must be provided",
+		},
+		{
+			name:     "Non-existent key",
+			config:   config.GitConfig{SSHKeyPath: "/nonexistent/key"},
+			errorMsg: "SSH key not found",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := git.SetupSSH(tt.keyPath)
+			t.Setenv("SSH_AUTH_SOCK", tt.agentSocket)
+			command, err := git.SSHCommand(&tt.config)
 
-			if tt.expectError {
-				if err == nil {
-					t.Errorf("git.SetupSSH() expected error containing %q, got nil", tt.errorMsg)
-				} else if !strings.Contains(err.Error(), tt.errorMsg) {
-					t.Errorf("git.SetupSSH() error = %q, want error containing %q", err.Error(), tt.errorMsg)
-				}
-			} else {
-				if err != nil {
-					t.Errorf("git.SetupSSH() unexpected error: %v", err)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("git.SSHCommand() error = %v, want error containing %q", err, tt.errorMsg)
 				}
-
-				// Verify GIT_SSH_COMMAND is set
-				gitSSHCmd := os.Getenv("GIT_SSH_COMMAND")
-				if gitSSHCmd == "" {
-					t.Error("git.SetupSSH() did not set GIT_SSH_COMMAND")
-				}
-				if !strings.Contains(gitSSHCmd, "ssh -i") {
-					t.Errorf("GIT_SSH_COMMAND = %q, should contain 'ssh -i'", gitSSHCmd)
+				return
+			}
+			if err != nil {
+				t.Fatalf("git.SSHCommand() unexpected error: %v", err)
+			}
+			for _, part := range tt.contains {
+				if !strings.Contains(command, part) {
+					t.Errorf("git.SSHCommand() = %q, should contain %q", command, part)
 				}
 			}
+			if strings.Contains(command, "StrictHostKeyChecking=no") {
+				t.Errorf("git.SSHCommand() = %q disables host key checking", command)
+			}
 		})
 	}
+
+	// The SSH command only goes to git child processes
+	if _, err := git.NewExecClient(&config.GitConfig{AuthType: "ssh", RepoUrl: "git@github.com:user/repo.git", SSHKeyPath: sshKey}); err != nil {
+		t.Fatalf("git.NewExecClient() failed: %v", err)
+	}
+	if os.Getenv("GIT_SSH_COMMAND") != "" {
+		t.Error("git.NewExecClient() changed GIT_SSH_COMMAND of the current process")
+	}
 }
 
 func TestGetProjectDirName(t *testing.T) {
@@ -281,6 +295,26 @@ func TestGetProjectDirName(t *testing.T) {
 		{
 			name:     "URL ending with slash",
 			repoUrl:  "https://github.com/user/repo/",
+			expected: "repo",
+		},
+		{
+			name:     "Azure DevOps HTTPS URL",
+			repoUrl:  "https://org@dev.azure.com/org/project/_git/service",
+			expected: "service",
+		},
+		{
+			name:     "Azure DevOps SSH URL",
+			repoUrl:  "git@ssh.dev.azure.com:v3/org/project/service",
+			expected: "service",
+		},
+		{
+			name:     "SSH URL with scheme and port",
+			repoUrl:  "ssh://git@gitlab.example.com:2222/group/tool.git",
+			expected: "tool",
+		},
+		{
+			name:     "Azure DevOps URL without repository",
+			repoUrl:  "https://dev.azure.com/org/project/_git/",
 			expected: "",
 		},
 	}
@@ -295,3 +329,36 @@ func TestGetProjectDirName(t *testing.T) {
 	}
 }
 
+func TestParseRepoURL(t *testing.T) {
+	tests := []struct {
+		raw          string
+		expectScheme string
+		expectUser   string
+		expectHost   string
+		expectPath   string
+	}{
+		{"https://github.com/user/repo.git", "https", "", "github.com", "user/repo"},
+		{"git@github.com:user/repo.git", "scp", "git", "github.com", "user/repo"},
+		{"ssh://git@host.example.com:2222/group/sub/repo.git", "ssh", "git", "host.example.com:2222", "group/sub/repo"},
+		{"https://org@dev.azure.com/org/project/_git/repo", "https", "org", "dev.azure.com", "org/project/_git/repo"},
+		{"git@ssh.dev.azure.com:v3/org/project/repo", "scp", "git", "ssh.dev.azure.com", "org/project/repo"},
+		{"file:///srv/git/repo.git", "file", "", "", "srv/git/repo"},
+		{"../local/repo", "file", "", "", "../local/repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			parsed, err := git.ParseRepoURL(tt.raw)
+			if err != nil {
+				t.Fatalf("git.ParseRepoURL() unexpected error: %v", err)
+			}
+			if parsed.Scheme != tt.expectScheme || parsed.User != tt.expectUser || parsed.Host != tt.expectHost || parsed.Path != tt.expectPath {
+				t.Errorf("git.ParseRepoURL(%q) = %+v", tt.raw, parsed)
+			}
+		})
+	}
+
+	if _, err := git.ParseRepoURL(""); err == nil {
+		t.Error("git.ParseRepoURL(\"\") expected error, got nil")
+	}
+}
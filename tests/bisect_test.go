@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExecClientBisect(t *testing.T) {
+	repo := t.TempDir()
+	initRepo(t, repo)
+	good := commitFile(t, repo, "a.txt", "a", "Good")
+	commitFile(t, repo, "b.txt", "b", "Still good")
+	broken := commitFile(t, repo, "broken", "", "Break the build")
+	for i := 0; i < 3; i++ {
+		commitFile(t, repo, fmt.Sprintf("c%d.txt", i), "c", "Later change")
+	}
+	client := newExecClient(t)
+
+	sha, err := client.Bisect(repo, good, "HEAD", []string{"sh", "-c", "test ! -f broken"})
+	if err != nil || sha != broken {
+		t.Fatalf("Bisect() = %q, %v, want %s", sha, err, broken)
+	}
+	if branch, _ := client.CurrentBranch(repo); branch != "main" {
+		t.Errorf("clone left on %q, want main restored", branch)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".git", "BISECT_LOG")); !os.IsNotExist(err) {
+		t.Error("bisect was not reset")
+	}
+
+	// Skipped commits around the culprit leave several candidates
+	script := "test -f c0.txt && exit 1; test -f broken && exit 125; exit 0"
+	if _, err := client.Bisect(repo, good, "HEAD", []string{"sh", "-c", script}); !errors.Is(err, git.ErrBisectInconclusive) {
+		t.Errorf("Bisect() with skipped commits = %v, want ErrBisectInconclusive", err)
+	}
+
+	if _, err := client.Bisect(repo, "no-such-ref", "HEAD", []string{"true"}); err == nil {
+		t.Error("Bisect() expected error for an unknown good ref, got nil")
+	}
+}
+
+func TestExecClientBisectWithSSHPassphrase(t *testing.T) {
+	repo := t.TempDir()
+	initRepo(t, repo)
+	good := commitFile(t, repo, "a.txt", "a", "Good")
+	broken := commitFile(t, repo, "broken", "", "Break the build")
+	commitFile(t, repo, "b.txt", "b", "Later change")
+
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	os.WriteFile(key, []byte("test key"), 0600)
+	client, err := git.NewExecClient(&config.GitConfig{
+		RepoUrl:       "git@github.com:test/repo.git",
+		AuthType:      "ssh",
+		SSHKeyPath:    key,
+		SSHPassphrase: "secret",
+	})
+	if err != nil {
+		t.Fatalf("NewExecClient() failed: %v", err)
+	}
+	// Even when automateLife itself was started with them
+	t.Setenv("AUTOMATELIFE_ASKPASS", "1")
+	t.Setenv("AUTOMATELIFE_SSH_PASSPHRASE", "secret")
+
+	// Any commit that sees the askpass variables counts as bad
+	script := `test -z "$AUTOMATELIFE_ASKPASS$AUTOMATELIFE_SSH_PASSPHRASE$SSH_ASKPASS" || exit 1; test ! -f broken`
+	sha, err := client.Bisect(repo, good, "HEAD", []string{"sh", "-c", script})
+	if err != nil || sha != broken {
+		t.Errorf("Bisect() = %q, %v, want %s", sha, err, broken)
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"automateLife/git"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheMirrorPath(t *testing.T) {
+	cache := git.NewCache("/cache")
+
+	https := cache.MirrorPath("https://github.com/org/repo.git")
+	if ssh := cache.MirrorPath("git@github.com:org/repo.git"); ssh != https {
+		t.Errorf("MirrorPath() = %q for SSH and %q for HTTPS, want one mirror", ssh, https)
+	}
+	if other := cache.MirrorPath("https://github.com/other/repo.git"); other == https {
+		t.Errorf("MirrorPath() = %q for two different repositories", other)
+	}
+	if filepath.Dir(https) != "/cache" || !strings.HasPrefix(filepath.Base(https), "repo-") {
+		t.Errorf("MirrorPath() = %q, want a repo-<hash>.git directory in the cache", https)
+	}
+}
+
+func TestCacheClone(t *testing.T) {
+	url, work := newBareRemote(t)
+	client := newExecClient(t)
+	cache := git.NewCache(filepath.Join(t.TempDir(), "repos"))
+	dest := filepath.Join(t.TempDir(), "clone")
+
+	mirror, err := cache.ReferenceFor(client, url, dest)
+	if err != nil {
+		t.Fatalf("ReferenceFor() failed: %v", err)
+	}
+	if mirror != cache.MirrorPath(url) {
+		t.Errorf("ReferenceFor() = %q, want %q", mirror, cache.MirrorPath(url))
+	}
+	if sha := runGitIn(t, mirror, "rev-parse", "feature"); sha != runGitIn(t, work, "rev-parse", "feature") {
+		t.Errorf("mirror feature = %s, want every branch mirrored", sha)
+	}
+
+	result, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, Reference: mirror})
+	if err != nil || !result.Cloned {
+		t.Fatalf("CloneOrUpdate() = %+v, %v", result, err)
+	}
+	// Dissociated clones keep working when the cache is pruned
+	if _, err := os.Stat(filepath.Join(dest, ".git", "objects", "info", "alternates")); !os.IsNotExist(err) {
+		t.Error("clone still borrows objects from the cache")
+	}
+	if origin := runGitIn(t, dest, "remote", "get-url", "origin"); origin != url {
+		t.Errorf("origin = %q, want the remote rather than the mirror", origin)
+	}
+
+	// Existing clones don't touch the cache
+	if mirror, err := cache.ReferenceFor(client, url, dest); err != nil || mirror != "" {
+		t.Errorf("ReferenceFor() of an existing clone = %q, %v", mirror, err)
+	}
+
+	head := commitFile(t, work, "new.txt", "new", "New commit")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+	if _, err := cache.Update(client, url); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if sha := runGitIn(t, mirror, "rev-parse", "main"); sha != head {
+		t.Errorf("mirror main = %s after Update(), want %s", sha, head)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	client := newExecClient(t)
+	cache := git.NewCache(filepath.Join(t.TempDir(), "repos"))
+
+	var mirrors []string
+	for i := 0; i < 3; i++ {
+		url, _ := newBareRemote(t)
+		mirror, err := cache.Update(client, url)
+		if err != nil {
+			t.Fatalf("Update() failed: %v", err)
+		}
+		// Oldest first: used 40, 20 and 0 days ago
+		used := time.Now().Add(-time.Duration(2-i) * 20 * 24 * time.Hour)
+		os.Chtimes(mirror, used, used)
+		mirrors = append(mirrors, mirror)
+	}
+
+	entries, err := cache.Entries(client)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("Entries() = %v, %v", entries, err)
+	}
+	if entries[0].Path != mirrors[0] || entries[0].Size == 0 || !strings.HasPrefix(entries[0].URL, "file://") {
+		t.Errorf("Entries()[0] = %+v, want the least recently used mirror with its size and URL", entries[0])
+	}
+
+	removed, err := cache.Prune(client, 30*24*time.Hour, 0)
+	if err != nil || len(removed) != 1 || removed[0].Path != mirrors[0] {
+		t.Fatalf("Prune() by age = %v, %v, want the mirror unused for 40 days", removed, err)
+	}
+
+	removed, err = cache.Prune(client, 0, entries[2].Size)
+	if err != nil || len(removed) != 1 || removed[0].Path != mirrors[1] {
+		t.Fatalf("Prune() by size = %v, %v, want the least recently used mirror", removed, err)
+	}
+	if _, err := os.Stat(mirrors[2]); err != nil {
+		t.Errorf("most recently used mirror was removed: %v", err)
+	}
+}
+
+func TestDefaultCacheDirFollowsXDG(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", xdg)
+	dir, err := git.DefaultCacheDir()
+	if err != nil || dir != filepath.Join(xdg, "automatelife", "repos") {
+		t.Errorf("DefaultCacheDir() = %q, %v, want it under XDG_CACHE_HOME", dir, err)
+	}
+}
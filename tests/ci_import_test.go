@@ -0,0 +1,276 @@
+package tests
+
+import (
+	"automateLife/ci"
+	"automateLife/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCIFile(t *testing.T, relPath string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), relPath)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write CI file: %v", err)
+	}
+	return path
+}
+
+func TestImportGitHubActions(t *testing.T) {
+	path := writeCIFile(t, ".github/workflows/ci.yml", `
+name: CI
+on: [push]
+env:
+  GOFLAGS: -mod=mod
+  TOKEN: ${{ secrets.TOKEN }}
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    env:
+      CGO_ENABLED: "0"
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@v5
+      - name: Install
+        run: go mod download
+      - name: Build
+        run: go build ./...
+      - name: Test
+        run: |
+          go test ./...
+          echo ${{ github.sha }}
+`)
+
+	imported, err := ci.ImportFile(path)
+	if err != nil {
+		t.Fatalf("ci.ImportFile() unexpected error: %v", err)
+	}
+
+	if imported.Format != ci.FormatGitHub {
+		t.Errorf("Format = %q, want %q", imported.Format, ci.FormatGitHub)
+	}
+	if imported.InstallCommand != "go mod download" {
+		t.Errorf("InstallCommand = %q", imported.InstallCommand)
+	}
+	if imported.BuildCommand != "go build ./..." {
+		t.Errorf("BuildCommand = %q", imported.BuildCommand)
+	}
+	if imported.TestCommand != "go test ./..." {
+		t.Errorf("TestCommand = %q", imported.TestCommand)
+	}
+	if imported.Variables["GOFLAGS"] != "-mod=mod" || imported.Variables["CGO_ENABLED"] != "0" {
+		t.Errorf("Variables = %v", imported.Variables)
+	}
+	if _, ok := imported.Variables["TOKEN"]; ok {
+		t.Error("secret expression should not be imported as a variable")
+	}
+
+	notes := strings.Join(imported.Untranslated, "\n")
+	for _, expected := range []string{"actions/setup-go@v5", "github.sha", "TOKEN"} {
+		if !strings.Contains(notes, expected) {
+			t.Errorf("Untranslated should mention %q, got:\n%s", expected, notes)
+		}
+	}
+	if strings.Contains(notes, "actions/checkout") {
+		t.Error("checkout action should be skipped silently")
+	}
+}
+
+func TestImportAzurePipelines(t *testing.T) {
+	path := writeCIFile(t, "azure-pipelines.yml", `
+trigger:
+  - main
+pool:
+  vmImage: ubuntu-latest
+variables:
+  - name: buildConfiguration
+    value: Release
+  - group: shared-secrets
+stages:
+  - stage: Build
+    jobs:
+      - job: BuildJob
+        steps:
+          - script: dotnet restore
+            displayName: Restore
+          - script: dotnet build --configuration $(buildConfiguration)
+          - bash: dotnet test
+          - task: PublishBuildArtifacts@1
+`)
+
+	imported, err := ci.ImportFile(path)
+	if err != nil {
+		t.Fatalf("ci.ImportFile() unexpected error: %v", err)
+	}
+
+	if imported.Format != ci.FormatAzure {
+		t.Errorf("Format = %q, want %q", imported.Format, ci.FormatAzure)
+	}
+	if imported.InstallCommand != "dotnet restore" {
+		t.Errorf("InstallCommand = %q", imported.InstallCommand)
+	}
+	if imported.BuildCommand != "" {
+		t.Errorf("BuildCommand = %q, macro-based command should not be imported", imported.BuildCommand)
+	}
+	if imported.TestCommand != "dotnet test" {
+		t.Errorf("TestCommand = %q", imported.TestCommand)
+	}
+	if imported.Variables["buildConfiguration"] != "Release" {
+		t.Errorf("Variables = %v", imported.Variables)
+	}
+
+	notes := strings.Join(imported.Untranslated, "\n")
+	for _, expected := range []string{"PublishBuildArtifacts@1", "shared-secrets", "$(buildConfiguration)"} {
+		if !strings.Contains(notes, expected) {
+			t.Errorf("Untranslated should mention %q, got:\n%s", expected, notes)
+		}
+	}
+}
+
+func TestImportGitLabCI(t *testing.T) {
+	path := writeCIFile(t, ".gitlab-ci.yml", `
+stages:
+  - build
+  - test
+variables:
+  NODE_ENV: test
+before_script:
+  - npm ci
+.template:
+  script:
+    - echo hidden
+compile:
+  stage: build
+  script:
+    - npm run build
+unit:
+  stage: test
+  script:
+    - npm test
+    - ./scripts/report-coverage.sh
+lint:
+  stage: lint
+  script:
+    - eslint .
+`)
+
+	imported, err := ci.ImportFile(path)
+	if err != nil {
+		t.Fatalf("ci.ImportFile() unexpected error: %v", err)
+	}
+
+	if imported.Format != ci.FormatGitLab {
+		t.Errorf("Format = %q, want %q", imported.Format, ci.FormatGitLab)
+	}
+	if imported.InstallCommand != "npm ci" {
+		t.Errorf("InstallCommand = %q", imported.InstallCommand)
+	}
+	if imported.BuildCommand != "npm run build" {
+		t.Errorf("BuildCommand = %q", imported.BuildCommand)
+	}
+	if imported.TestCommand != "npm test\n./scripts/report-coverage.sh" {
+		t.Errorf("TestCommand = %q", imported.TestCommand)
+	}
+	if imported.Variables["NODE_ENV"] != "test" {
+		t.Errorf("Variables = %v", imported.Variables)
+	}
+
+	notes := strings.Join(imported.Untranslated, "\n")
+	if !strings.Contains(notes, "eslint .") {
+		t.Errorf("Untranslated should mention the lint step, got:\n%s", notes)
+	}
+	if strings.Contains(notes, "hidden") {
+		t.Error("hidden jobs should be ignored")
+	}
+}
+
+func TestImportKeepsScriptBlocksWhole(t *testing.T) {
+	path := writeCIFile(t, ".github/workflows/ci.yml", `
+on: [push]
+jobs:
+  ci:
+    steps:
+      - name: Install
+        run: npm ci
+      - name: Test
+        run: |
+          go test \
+            -race ./...
+          if [ -f x ]; then
+            make build
+          fi
+      - name: Report
+        run: |
+          for f in *.out; do
+            echo "${{ github.sha }} $f"
+          done
+          cat <<'EOF'
+          done
+          EOF
+`)
+
+	imported, err := ci.ImportFile(path)
+	if err != nil {
+		t.Fatalf("ci.ImportFile() unexpected error: %v", err)
+	}
+	// The block is classified as a whole, make build stays inside its if
+	if want := "go test \\\n-race ./...\nif [ -f x ]; then\nmake build\nfi"; imported.TestCommand != want {
+		t.Errorf("TestCommand = %q, want %q", imported.TestCommand, want)
+	}
+	if imported.BuildCommand != "" {
+		t.Errorf("BuildCommand = %q, want nothing taken out of the test block", imported.BuildCommand)
+	}
+
+	// A loop using an expression is dropped whole, the here-document isn't split
+	notes := strings.Join(imported.Untranslated, "\n")
+	if !strings.Contains(notes, "for f in *.out; do") || !strings.Contains(notes, "github.sha") {
+		t.Errorf("Untranslated should mention the whole loop, got:\n%s", notes)
+	}
+	if !strings.Contains(notes, `cat <<'EOF'\ndone\nEOF`) {
+		t.Errorf("Untranslated should mention the whole here-document, got:\n%s", notes)
+	}
+}
+
+func TestImportUnknownFormat(t *testing.T) {
+	path := writeCIFile(t, "random.yml", "foo: bar\n")
+	if _, err := ci.ImportFile(path); err == nil {
+		t.Error("ci.ImportFile() expected error for unrecognised file, got nil")
+	}
+
+	if _, err := ci.ImportFile("/nonexistent/ci.yml"); err == nil {
+		t.Error("ci.ImportFile() expected error for missing file, got nil")
+	}
+}
+
+func TestImportApply(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Build.InstallCommand = "existing install"
+
+	imported := &ci.Import{
+		TestCommand: "go test ./...",
+		Variables:   map[string]string{"ENV": "ci"},
+	}
+	imported.Apply(cfg)
+
+	if cfg.Build.InstallCommand != "existing install" {
+		t.Errorf("Apply() overwrote install command with empty value")
+	}
+	if cfg.Build.TestCommand != "go test ./..." {
+		t.Errorf("TestCommand = %q", cfg.Build.TestCommand)
+	}
+	if cfg.Environment.Variables["ENV"] != "ci" {
+		t.Errorf("Variables = %v", cfg.Environment.Variables)
+	}
+	if cfg.Build.Shell {
+		t.Error("Apply() enabled build.shell for plain commands")
+	}
+
+	// Steps written for a shell keep running in one
+	(&ci.Import{BuildCommand: "make build 2>&1 | tee build.log"}).Apply(cfg)
+	if !cfg.Build.Shell {
+		t.Error("Apply() left build.shell off for a piped command")
+	}
+}
@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCloneDepthAndSingleBranch(t *testing.T) {
+	url, work := newBareRemote(t)
+	commitFile(t, work, "second.txt", "2", "Second commit")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+
+	opts := git.CloneOptions{URL: url, Dir: dest, Branch: "main", Tuning: git.Tuning{Depth: 1, SingleBranch: true}}
+	if err := client.Clone(opts); err != nil {
+		t.Fatalf("Clone() failed: %v", err)
+	}
+
+	if count := runGitIn(t, dest, "rev-list", "--count", "HEAD"); count != "1" {
+		t.Errorf("shallow clone has %s commits, want 1", count)
+	}
+	if branches := runGitIn(t, dest, "branch", "-r"); strings.Contains(branches, "feature") {
+		t.Errorf("single-branch clone fetched other branches: %s", branches)
+	}
+
+	// A single-branch clone can still be moved to another branch
+	result, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, Branch: "feature", Tuning: opts.Tuning})
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() switching branch failed: %v", err)
+	}
+	if result.NewCommit != runGitIn(t, work, "rev-parse", "feature") {
+		t.Errorf("CloneOrUpdate() landed on %s", result.NewCommit)
+	}
+}
+
+func TestCloneSparsePaths(t *testing.T) {
+	root := t.TempDir()
+	work := filepath.Join(root, "work")
+	bare := filepath.Join(root, "remote.git")
+	os.MkdirAll(filepath.Join(work, "services", "api"), 0755)
+	os.MkdirAll(filepath.Join(work, "services", "web"), 0755)
+	initRepo(t, work)
+	os.WriteFile(filepath.Join(work, "README.md"), []byte("root"), 0644)
+	os.WriteFile(filepath.Join(work, "services", "api", "main.go"), []byte("api"), 0644)
+	os.WriteFile(filepath.Join(work, "services", "web", "index.js"), []byte("web"), 0644)
+	runGitIn(t, work, "add", ".")
+	runGitIn(t, work, "commit", "-q", "-m", "monorepo")
+	runGitIn(t, root, "clone", "-q", "--bare", work, bare)
+
+	dest := filepath.Join(root, "clone")
+	client := newExecClient(t)
+	err := client.Clone(git.CloneOptions{URL: "file://" + bare, Dir: dest, Tuning: git.Tuning{SparsePaths: []string{"services/api"}}})
+	if err != nil {
+		t.Fatalf("Clone() failed: %v", err)
+	}
+
+	for _, path := range []string{"README.md", "services/api/main.go"} {
+		if _, err := os.Stat(filepath.Join(dest, path)); err != nil {
+			t.Errorf("sparse checkout is missing %s", path)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, "services", "web", "index.js")); err == nil {
+		t.Error("sparse checkout contains services/web outside the cone")
+	}
+}
+
+func TestCloneRecurseSubmodules(t *testing.T) {
+	// Local file:// submodules are blocked by default since git 2.38.1
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	libURL, _ := newBareRemote(t)
+	url, work := newBareRemote(t)
+	runGitIn(t, work, "submodule", "add", "-q", libURL, "lib")
+	runGitIn(t, work, "commit", "-q", "-m", "Add lib submodule")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+
+	client := newExecClient(t)
+	withSubmodules := filepath.Join(t.TempDir(), "with")
+	if err := client.Clone(git.CloneOptions{URL: url, Dir: withSubmodules, Tuning: git.Tuning{RecurseSubmodules: true}}); err != nil {
+		t.Fatalf("Clone() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(withSubmodules, "lib", "README.md")); err != nil {
+		t.Errorf("submodule was not checked out: %v", err)
+	}
+
+	without := filepath.Join(t.TempDir(), "without")
+	if err := client.Clone(git.CloneOptions{URL: url, Dir: without}); err != nil {
+		t.Fatalf("Clone() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(without, "lib", "README.md")); err == nil {
+		t.Error("submodule was checked out without recurse_submodules")
+	}
+
+	if err := client.UpdateSubmodules(without, 0); err != nil {
+		t.Fatalf("UpdateSubmodules() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(without, "lib", "README.md")); err != nil {
+		t.Errorf("UpdateSubmodules() did not check out the submodule: %v", err)
+	}
+}
+
+func TestCloneLFSPullRequiresGitLFS(t *testing.T) {
+	if err := exec.Command("git", "lfs", "version").Run(); err == nil {
+		t.Skip("git-lfs is installed")
+	}
+	url, _ := newBareRemote(t)
+	client := newExecClient(t)
+
+	err := client.Clone(git.CloneOptions{URL: url, Dir: filepath.Join(t.TempDir(), "clone"), Tuning: git.Tuning{LFS: "pull"}})
+	if err == nil || !strings.Contains(err.Error(), "git-lfs is not installed") {
+		t.Errorf("Clone() with lfs pull = %v, want a git-lfs error", err)
+	}
+
+	// Skipping smudge works without git-lfs
+	if err := client.Clone(git.CloneOptions{URL: url, Dir: filepath.Join(t.TempDir(), "clone"), Tuning: git.Tuning{LFS: "skip"}}); err != nil {
+		t.Errorf("Clone() with lfs skip failed: %v", err)
+	}
+}
+
+func TestUpdateKeepsDepth(t *testing.T) {
+	url, work := newBareRemote(t)
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+	tuning := git.Tuning{Depth: 1}
+	if _, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, Branch: "main", Tuning: tuning}); err != nil {
+		t.Fatalf("CloneOrUpdate() clone failed: %v", err)
+	}
+
+	commitFile(t, work, "second.txt", "2", "Second commit")
+	commitFile(t, work, "third.txt", "3", "Third commit")
+	tip := commitFile(t, work, "fourth.txt", "4", "Fourth commit")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+
+	result, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, Branch: "main", Tuning: tuning})
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() update failed: %v", err)
+	}
+	if result.NewCommit != tip {
+		t.Errorf("update landed on %s, want %s", result.NewCommit, tip)
+	}
+	if count := runGitIn(t, dest, "rev-list", "--count", "HEAD"); count != "1" {
+		t.Errorf("updated shallow clone has %s commits, want 1", count)
+	}
+}
+
+func TestUpdateSingleBranch(t *testing.T) {
+	url, work := newBareRemote(t)
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+	opts := git.SyncOptions{URL: url, Dir: dest, Branch: "main", Tuning: git.Tuning{SingleBranch: true}}
+	if _, err := git.CloneOrUpdate(client, opts); err != nil {
+		t.Fatalf("CloneOrUpdate() clone failed: %v", err)
+	}
+
+	runGitIn(t, work, "push", "-q", "origin", "feature")
+	if _, err := git.CloneOrUpdate(client, opts); err != nil {
+		t.Fatalf("CloneOrUpdate() update failed: %v", err)
+	}
+	if branches := runGitIn(t, dest, "branch", "-r"); strings.Contains(branches, "feature") {
+		t.Errorf("single-branch update fetched other branches: %s", branches)
+	}
+
+	opts.SingleBranch = false
+	if _, err := git.CloneOrUpdate(client, opts); err != nil {
+		t.Fatalf("CloneOrUpdate() without single_branch failed: %v", err)
+	}
+	if branches := runGitIn(t, dest, "branch", "-r"); !strings.Contains(branches, "origin/feature") {
+		t.Errorf("update without single_branch did not fetch the other branches: %s", branches)
+	}
+}
+
+func TestUpdateSparsePaths(t *testing.T) {
+	url, work := newBareRemote(t)
+	for _, dir := range []string{"api", "web"} {
+		os.MkdirAll(filepath.Join(work, dir), 0755)
+		os.WriteFile(filepath.Join(work, dir, "main.txt"), []byte(dir), 0644)
+	}
+	runGitIn(t, work, "add", ".")
+	runGitIn(t, work, "commit", "-q", "-m", "Add services")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+	opts := git.SyncOptions{URL: url, Dir: dest, Branch: "main", Tuning: git.Tuning{SparsePaths: []string{"api"}}}
+	if _, err := git.CloneOrUpdate(client, opts); err != nil {
+		t.Fatalf("CloneOrUpdate() clone failed: %v", err)
+	}
+
+	checkedOut := func(dir string) bool {
+		_, err := os.Stat(filepath.Join(dest, dir, "main.txt"))
+		return err == nil
+	}
+	opts.SparsePaths = []string{"web"}
+	if _, err := git.CloneOrUpdate(client, opts); err != nil {
+		t.Fatalf("CloneOrUpdate() with new sparse paths failed: %v", err)
+	}
+	if checkedOut("api") || !checkedOut("web") {
+		t.Errorf("after changing sparse_paths to web: api checked out %v, web checked out %v", checkedOut("api"), checkedOut("web"))
+	}
+
+	opts.SparsePaths = nil
+	if _, err := git.CloneOrUpdate(client, opts); err != nil {
+		t.Fatalf("CloneOrUpdate() without sparse paths failed: %v", err)
+	}
+	if !checkedOut("api") || !checkedOut("web") {
+		t.Error("clearing sparse_paths did not check out every file again")
+	}
+}
+
+func TestUpdateSkipsLFSSmudge(t *testing.T) {
+	url, work := newBareRemote(t)
+	client, err := git.NewExecClient(&config.GitConfig{RepoUrl: url, AuthType: "basic", UserName: "user", Password: "secret", LFS: "skip"})
+	if err != nil {
+		t.Fatalf("git.NewExecClient() failed: %v", err)
+	}
+	dest := filepath.Join(t.TempDir(), "clone")
+	opts := git.SyncOptions{URL: url, Dir: dest, Branch: "main", Tuning: git.Tuning{LFS: "skip"}}
+	if _, err := git.CloneOrUpdate(client, opts); err != nil {
+		t.Fatalf("CloneOrUpdate() clone failed: %v", err)
+	}
+
+	// A stand-in for git-lfs that fails unless smudging is skipped
+	runGitIn(t, dest, "config", "filter.lfs.smudge", `sh -c 'test -n "$GIT_LFS_SKIP_SMUDGE" && cat'`)
+	runGitIn(t, dest, "config", "filter.lfs.required", "true")
+	commitFile(t, work, ".gitattributes", "*.bin filter=lfs\n", "Track binaries with LFS")
+	commitFile(t, work, "data.bin", "pointer", "Add data")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+
+	if _, err := git.CloneOrUpdate(client, opts); err != nil {
+		t.Fatalf("CloneOrUpdate() update ran the LFS smudge filter: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "data.bin")); string(data) != "pointer" {
+		t.Errorf("data.bin = %q, want the pointer left as it is", data)
+	}
+}
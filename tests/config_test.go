@@ -2,6 +2,7 @@ package tests
 
 import (
 	"automateLife/config"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -218,9 +219,9 @@ func TestExpandPaths(t *testing.T) {
 		t.Errorf("Build.OutputDir = %q, want %q", cfg.Build.OutputDir, expectedOutputDir)
 	}
 
-	expectedBuildCmd := "go build -o " + filepath.Join(testHome, "app")
-	if cfg.Build.BuildCommand != expectedBuildCmd {
-		t.Errorf("Build.BuildCommand = %q, want %q", cfg.Build.BuildCommand, expectedBuildCmd)
+	// Commands are expanded when they run, not when the config loads
+	if cfg.Build.BuildCommand != "go build -o ~/app" {
+		t.Errorf("Build.BuildCommand = %q, want it left as written", cfg.Build.BuildCommand)
 	}
 
 	// Check Environment variables
@@ -253,3 +254,107 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func TestReadConfigKeepsVariables(t *testing.T) {
+	t.Setenv("AUTOMATELIFE_TEST_TOKEN", "secret-value")
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test_config.json")
+	content := `{"git": {"token": "${AUTOMATELIFE_TEST_TOKEN}"}, "environment": {"variables": {"KEY": "$AUTOMATELIFE_TEST_TOKEN"}}}`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := config.Read(testFile)
+	if err != nil {
+		t.Fatalf("config.Read() failed: %v", err)
+	}
+	if cfg.Git.Token != "${AUTOMATELIFE_TEST_TOKEN}" {
+		t.Errorf("config.Read() expanded token to %q", cfg.Git.Token)
+	}
+
+	expanded := cfg.Expanded()
+	if expanded.Git.Token != "secret-value" || expanded.Environment.Variables["KEY"] != "secret-value" {
+		t.Errorf("Expanded() did not expand values: token=%q vars=%v", expanded.Git.Token, expanded.Environment.Variables)
+	}
+	if cfg.Git.Token != "${AUTOMATELIFE_TEST_TOKEN}" || cfg.Environment.Variables["KEY"] != "$AUTOMATELIFE_TEST_TOKEN" {
+		t.Error("Expanded() modified the original config")
+	}
+}
+
+func TestConfigFilePermissions(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		expected os.FileMode
+	}{
+		{
+			name:     "Config without secrets",
+			cfg:      config.Config{Git: config.GitConfig{AuthType: "ssh"}},
+			expected: config.DefaultFileMode,
+		},
+		{
+			name:     "Config with token",
+			cfg:      config.Config{Git: config.GitConfig{AuthType: "token", Token: "ghp_secret"}},
+			expected: config.SecretFileMode,
+		},
+		{
+			name:     "Config with password",
+			cfg:      config.Config{Git: config.GitConfig{AuthType: "basic", UserName: "u", Password: "p"}},
+			expected: config.SecretFileMode,
+		},
+		{
+			name:     "Token read from environment variable",
+			cfg:      config.Config{Git: config.GitConfig{AuthType: "token", Token: "${GIT_TOKEN}"}},
+			expected: config.DefaultFileMode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := filepath.Join(tmpDir, tt.name+"-saved.json")
+			if err := config.Save(saved, &tt.cfg); err != nil {
+				t.Fatalf("config.Save() failed: %v", err)
+			}
+			if info, _ := os.Stat(saved); info.Mode().Perm() != tt.expected {
+				t.Errorf("config.Save() mode = %v, want %v", info.Mode().Perm(), tt.expected)
+			}
+
+			created := filepath.Join(tmpDir, tt.name+"-created.json")
+			data, _ := json.Marshal(tt.cfg)
+			if err := config.Create(created, string(data)); err != nil {
+				t.Fatalf("config.Create() failed: %v", err)
+			}
+			if info, _ := os.Stat(created); info.Mode().Perm() != tt.expected {
+				t.Errorf("config.Create() mode = %v, want %v", info.Mode().Perm(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestSaveTightensExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ConfigFile.json")
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	os.Chmod(path, 0644)
+
+	cfg := &config.Config{Git: config.GitConfig{Token: "ghp_secret"}}
+	if err := config.Save(path, cfg); err != nil {
+		t.Fatalf("config.Save() failed: %v", err)
+	}
+
+	worldReadable, err := config.IsWorldReadable(path)
+	if err != nil {
+		t.Fatalf("config.IsWorldReadable() failed: %v", err)
+	}
+	if worldReadable {
+		t.Error("config.Save() left a file with secrets world-readable")
+	}
+	// The file is replaced by renaming a complete temporary file over it
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("config.Save() left %d files behind, want only the config", len(entries))
+	}
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestExecClientKeepsTokenOutOfArguments(t *testing.T) {
+	const token = "s3cret-token-value"
+	var mu sync.Mutex
+	var authHeaders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+		mu.Unlock()
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	// A config entry the user already passes through the environment
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "core.askPass")
+	t.Setenv("GIT_CONFIG_VALUE_0", "")
+	trace := filepath.Join(t.TempDir(), "trace")
+	t.Setenv("GIT_TRACE", trace)
+
+	client, err := git.NewExecClient(&config.GitConfig{RepoUrl: server.URL + "/repo.git", AuthType: "token", Token: token})
+	if err != nil {
+		t.Fatalf("git.NewExecClient() failed: %v", err)
+	}
+	_, err = client.LsRemote(server.URL + "/repo.git")
+	if err == nil {
+		t.Fatal("LsRemote() expected an error from the test server, got nil")
+	}
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(token + ":"))
+	mu.Lock()
+	defer mu.Unlock()
+	if len(authHeaders) == 0 || authHeaders[0] != "Basic "+encoded {
+		t.Errorf("server saw Authorization headers %q, want Basic %s", authHeaders, encoded)
+	}
+
+	traced, _ := os.ReadFile(trace)
+	for _, secret := range []string{token, encoded} {
+		if strings.Contains(string(traced), secret) {
+			t.Errorf("git command line contains the credential %q", secret)
+		}
+		if strings.Contains(err.Error(), secret) {
+			t.Errorf("error %q contains the credential", err)
+		}
+	}
+}
+
+func TestCredentialsFor(t *testing.T) {
+	cfg := &config.GitConfig{Provider: "github", RepoUrl: "git@github.com:user/repo.git", AuthType: "token", Token: "ghp_token"}
+
+	username, password, ok, err := git.CredentialsFor(cfg, "https", "github.com")
+	if err != nil || !ok || username != "x-access-token" || password != "ghp_token" {
+		t.Errorf("git.CredentialsFor(github.com) = %q, %q, %v, %v", username, password, ok, err)
+	}
+
+	if _, _, ok, err := git.CredentialsFor(cfg, "https", "gitlab.com"); ok || err != nil {
+		t.Errorf("git.CredentialsFor(gitlab.com) = %v, %v, want no answer", ok, err)
+	}
+	if _, _, ok, err := git.CredentialsFor(cfg, "http", "github.com"); ok || err != nil {
+		t.Errorf("git.CredentialsFor() over http = %v, %v, want no answer", ok, err)
+	}
+
+	basic := &config.GitConfig{RepoUrl: "https://git.example.com/team/repo.git", AuthType: "basic", UserName: "me", Password: "pass"}
+	if username, password, ok, _ := git.CredentialsFor(basic, "https", "git.example.com"); !ok || username != "me" || password != "pass" {
+		t.Errorf("git.CredentialsFor() with basic auth = %q, %q, %v", username, password, ok)
+	}
+
+	sshCfg := &config.GitConfig{RepoUrl: "git@github.com:user/repo.git", AuthType: "ssh"}
+	if _, _, ok, err := git.CredentialsFor(sshCfg, "https", "github.com"); ok || err != nil {
+		t.Errorf("git.CredentialsFor() with ssh auth = %v, %v, want no answer", ok, err)
+	}
+}
@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"automateLife/config"
+	"automateLife/detect"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name            string
+		files           map[string]string
+		expectLanguage  string
+		expectInstall   string
+		expectBuild     string
+		expectTest      string
+		expectMarkerLen int
+	}{
+		{
+			name:            "Go module",
+			files:           map[string]string{"go.mod": "module example"},
+			expectLanguage:  "go",
+			expectInstall:   "go mod download",
+			expectBuild:     "go build ./...",
+			expectTest:      "go test ./...",
+			expectMarkerLen: 1,
+		},
+		{
+			name:            "Node with yarn and build script",
+			files:           map[string]string{"package.json": `{"scripts": {"build": "tsc"}}`, "yarn.lock": ""},
+			expectLanguage:  "nodejs",
+			expectInstall:   "yarn install",
+			expectBuild:     "npm run build",
+			expectTest:      "npm test",
+			expectMarkerLen: 1,
+		},
+		{
+			name:            "Node without build script",
+			files:           map[string]string{"package.json": `{"scripts": {"test": "jest"}}`},
+			expectLanguage:  "nodejs",
+			expectInstall:   "npm install",
+			expectBuild:     "",
+			expectTest:      "npm test",
+			expectMarkerLen: 1,
+		},
+		{
+			name:            "Python with poetry",
+			files:           map[string]string{"pyproject.toml": "", "poetry.lock": ""},
+			expectLanguage:  "python",
+			expectInstall:   "poetry install",
+			expectBuild:     "poetry build",
+			expectTest:      "poetry run pytest",
+			expectMarkerLen: 1,
+		},
+		{
+			name:            "Dotnet project",
+			files:           map[string]string{"App.csproj": "<Project/>"},
+			expectLanguage:  "dotnet",
+			expectInstall:   "dotnet restore",
+			expectBuild:     "dotnet build --no-restore",
+			expectTest:      "dotnet test --no-build",
+			expectMarkerLen: 1,
+		},
+		{
+			name:            "Rust crate",
+			files:           map[string]string{"Cargo.toml": ""},
+			expectLanguage:  "rust",
+			expectInstall:   "cargo fetch",
+			expectBuild:     "cargo build",
+			expectTest:      "cargo test",
+			expectMarkerLen: 1,
+		},
+		{
+			name:            "Ruby with Gemfile",
+			files:           map[string]string{"Gemfile": ""},
+			expectLanguage:  "ruby",
+			expectInstall:   "bundle install",
+			expectTest:      "bundle exec rspec",
+			expectMarkerLen: 1,
+		},
+		{
+			name:            "Maven project",
+			files:           map[string]string{"pom.xml": ""},
+			expectLanguage:  "java",
+			expectInstall:   "mvn dependency:resolve",
+			expectBuild:     "mvn package -DskipTests",
+			expectTest:      "mvn test",
+			expectMarkerLen: 1,
+		},
+		{
+			name:            "No markers",
+			files:           map[string]string{"README.md": ""},
+			expectLanguage:  "",
+			expectMarkerLen: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+			}
+
+			result, err := detect.Detect(dir)
+			if err != nil {
+				t.Fatalf("detect.Detect() unexpected error: %v", err)
+			}
+
+			if result.Language != tt.expectLanguage {
+				t.Errorf("Language = %q, want %q", result.Language, tt.expectLanguage)
+			}
+			if result.InstallCommand != tt.expectInstall {
+				t.Errorf("InstallCommand = %q, want %q", result.InstallCommand, tt.expectInstall)
+			}
+			if result.BuildCommand != tt.expectBuild {
+				t.Errorf("BuildCommand = %q, want %q", result.BuildCommand, tt.expectBuild)
+			}
+			if result.TestCommand != tt.expectTest {
+				t.Errorf("TestCommand = %q, want %q", result.TestCommand, tt.expectTest)
+			}
+			if len(result.Markers) != tt.expectMarkerLen {
+				t.Errorf("Markers = %v, want %d entries", result.Markers, tt.expectMarkerLen)
+			}
+		})
+	}
+}
+
+func TestLanguagesListsEveryDetector(t *testing.T) {
+	want := []string{"go", "nodejs", "python", "dotnet", "rust", "ruby", "java"}
+	got := detect.Languages()
+	if !slices.Equal(got, want) {
+		t.Errorf("Languages() = %v, want %v", got, want)
+	}
+}
+
+func TestDetectDeploymentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		expected string
+	}{
+		{name: "Dockerfile means container", files: []string{"Dockerfile"}, expected: "container"},
+		{name: "host.json means function", files: []string{"host.json"}, expected: "function"},
+		{name: "Function with Dockerfile", files: []string{"host.json", "Dockerfile"}, expected: "function"},
+		{name: "Nothing to detect", files: []string{}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.files {
+				os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644)
+			}
+
+			result, err := detect.Detect(dir)
+			if err != nil {
+				t.Fatalf("detect.Detect() unexpected error: %v", err)
+			}
+			if result.DeploymentType != tt.expected {
+				t.Errorf("DeploymentType = %q, want %q", result.DeploymentType, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetectGitRemote(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	runGit := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	runGit("init", "-q", "-b", "develop")
+	runGit("remote", "add", "origin", "https://gitlab.com/group/project.git")
+
+	result, err := detect.Detect(dir)
+	if err != nil {
+		t.Fatalf("detect.Detect() unexpected error: %v", err)
+	}
+
+	if result.RepoUrl != "https://gitlab.com/group/project.git" {
+		t.Errorf("RepoUrl = %q", result.RepoUrl)
+	}
+	if result.Provider != "gitlab" {
+		t.Errorf("Provider = %q, want %q", result.Provider, "gitlab")
+	}
+	if result.Branch != "develop" {
+		t.Errorf("Branch = %q, want %q", result.Branch, "develop")
+	}
+}
+
+func TestDetectInvalidDirectory(t *testing.T) {
+	if _, err := detect.Detect("/nonexistent/checkout"); err == nil {
+		t.Error("detect.Detect() expected error for missing directory, got nil")
+	}
+}
+
+func TestProviderFromURL(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/user/repo.git":             "github",
+		"git@gitlab.com:group/repo.git":                "gitlab",
+		"https://bitbucket.org/team/repo.git":          "bitbucket",
+		"https://dev.azure.com/org/project/_git/repo":  "azure-devops",
+		"https://org.visualstudio.com/project/_git/re": "azure-devops",
+		"https://example.com/repo.git":                 "",
+		"https://gitlab.example.com/group/repo.git":    "gitlab",
+		"git@github.corp.example.com:team/repo.git":    "github",
+		"https://mygitlab.example.com/repo.git":        "",
+		"/home/me/gitlab-mirror/repo.git":              "",
+		"file:///srv/github/repo.git":                  "",
+		"../bitbucket/repo":                            "",
+	}
+
+	for url, expected := range tests {
+		if got := detect.ProviderFromURL(url); got != expected {
+			t.Errorf("detect.ProviderFromURL(%q) = %q, want %q", url, got, expected)
+		}
+	}
+}
+
+func TestDetectApply(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Build.Language = "go"
+	cfg.Azure.DeploymentType = "webapp"
+
+	result := &detect.Result{
+		Provider:    "github",
+		RepoUrl:     "https://github.com/user/repo.git",
+		Language:    "python",
+		TestCommand: "pytest",
+	}
+	result.Apply(cfg)
+
+	if cfg.Git.Provider != "github" || cfg.Git.RepoUrl != "https://github.com/user/repo.git" {
+		t.Errorf("Apply() did not copy git settings: %+v", cfg.Git)
+	}
+	if cfg.Build.Language != "python" || cfg.Build.TestCommand != "pytest" {
+		t.Errorf("Apply() did not copy build settings: %+v", cfg.Build)
+	}
+	if cfg.Azure.DeploymentType != "webapp" {
+		t.Errorf("Apply() overwrote deployment type with empty value: %q", cfg.Azure.DeploymentType)
+	}
+}
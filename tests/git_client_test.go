@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"automateLife/git"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newBareRemote creates a bare repository with one commit on main and one
+// on a feature branch, and returns its file:// URL together with the
+// working copy used to push to it
+func newBareRemote(t *testing.T) (string, string) {
+	t.Helper()
+	root := t.TempDir()
+	work := filepath.Join(root, "work")
+	bare := filepath.Join(root, "remote.git")
+	os.MkdirAll(work, 0755)
+
+	initRepo(t, work)
+	commitFile(t, work, "README.md", "hello", "Initial commit")
+	runGitIn(t, work, "checkout", "-q", "-b", "feature")
+	commitFile(t, work, "feature.txt", "feature", "Add feature")
+	runGitIn(t, work, "checkout", "-q", "main")
+
+	runGitIn(t, root, "init", "-q", "--bare", "-b", "main", bare)
+	runGitIn(t, work, "remote", "add", "origin", bare)
+	runGitIn(t, work, "push", "-q", "origin", "main", "feature")
+
+	return "file://" + bare, work
+}
+
+func commitFile(t *testing.T, dir string, name string, content string, message string) string {
+	t.Helper()
+	os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	runGitIn(t, dir, "add", name)
+	runGitIn(t, dir, "commit", "-q", "-m", message)
+	return runGitIn(t, dir, "rev-parse", "HEAD")
+}
+
+func newExecClient(t *testing.T) *git.ExecClient {
+	t.Helper()
+	client, err := git.NewExecClient(nil)
+	if err != nil {
+		t.Fatalf("git.NewExecClient() failed: %v", err)
+	}
+	return client
+}
+
+func TestExecClientCloneAndRevParse(t *testing.T) {
+	url, work := newBareRemote(t)
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+
+	if err := client.Clone(git.CloneOptions{URL: url, Dir: dest, Branch: "feature"}); err != nil {
+		t.Fatalf("Clone() failed: %v", err)
+	}
+
+	head, err := client.RevParse(dest, "HEAD")
+	if err != nil {
+		t.Fatalf("RevParse() failed: %v", err)
+	}
+	expected := runGitIn(t, work, "rev-parse", "feature")
+	if head != expected {
+		t.Errorf("RevParse(HEAD) = %q, want %q", head, expected)
+	}
+
+	if _, err := client.RevParse(dest, "does-not-exist"); err == nil {
+		t.Error("RevParse() expected error for unknown revision, got nil")
+	}
+}
+
+func TestExecClientCloneMissingBranch(t *testing.T) {
+	url, _ := newBareRemote(t)
+	client := newExecClient(t)
+
+	err := client.Clone(git.CloneOptions{URL: url, Dir: filepath.Join(t.TempDir(), "clone"), Branch: "missing"})
+	if err == nil {
+		t.Fatal("Clone() expected error for missing branch, got nil")
+	}
+	if !strings.Contains(err.Error(), "git clone failed") || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Clone() error = %q, want git stderr included", err.Error())
+	}
+}
+
+func TestExecClientFetchAndCheckout(t *testing.T) {
+	url, work := newBareRemote(t)
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+
+	if err := client.Clone(git.CloneOptions{URL: url, Dir: dest}); err != nil {
+		t.Fatalf("Clone() failed: %v", err)
+	}
+
+	newSHA := commitFile(t, work, "CHANGELOG.md", "v2", "Second commit")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+
+	if err := client.Fetch(dest, git.FetchOptions{Prune: true}); err != nil {
+		t.Fatalf("Fetch() failed: %v", err)
+	}
+	remoteHead, err := client.RevParse(dest, "origin/main")
+	if err != nil || remoteHead != newSHA {
+		t.Fatalf("RevParse(origin/main) = %q, %v; want %q", remoteHead, err, newSHA)
+	}
+
+	if err := client.Checkout(dest, "origin/main"); err != nil {
+		t.Fatalf("Checkout() failed: %v", err)
+	}
+	head, _ := client.RevParse(dest, "HEAD")
+	if head != newSHA {
+		t.Errorf("HEAD after Checkout() = %q, want %q", head, newSHA)
+	}
+}
+
+func TestExecClientLsRemote(t *testing.T) {
+	url, work := newBareRemote(t)
+	client := newExecClient(t)
+
+	refs, err := client.LsRemote(url)
+	if err != nil {
+		t.Fatalf("LsRemote() failed: %v", err)
+	}
+
+	byName := map[string]git.RemoteRef{}
+	for _, ref := range refs {
+		byName[ref.Name] = ref
+	}
+	if byName["HEAD"].Target != "refs/heads/main" {
+		t.Errorf("HEAD target = %q, want refs/heads/main", byName["HEAD"].Target)
+	}
+	if byName["refs/heads/feature"].SHA != runGitIn(t, work, "rev-parse", "feature") {
+		t.Errorf("feature ref = %+v", byName["refs/heads/feature"])
+	}
+
+	filtered, err := client.LsRemote(url, "refs/heads/feature")
+	if err != nil || len(filtered) != 1 {
+		t.Errorf("LsRemote(refs/heads/feature) = %v, %v; want one ref", filtered, err)
+	}
+}
+
+func TestExecClientLog(t *testing.T) {
+	url, _ := newBareRemote(t)
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+	if err := client.Clone(git.CloneOptions{URL: url, Dir: dest, Branch: "feature"}); err != nil {
+		t.Fatalf("Clone() failed: %v", err)
+	}
+
+	commits, err := client.Log(dest, "HEAD", 0)
+	if err != nil {
+		t.Fatalf("Log() failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("Log() returned %d commits, want 2", len(commits))
+	}
+	if commits[0].Subject != "Add feature" || commits[0].Author != "Test User" || commits[0].Email != "test@example.com" {
+		t.Errorf("Log()[0] = %+v", commits[0])
+	}
+	if commits[0].Date.IsZero() {
+		t.Error("Log()[0].Date was not parsed")
+	}
+
+	limited, _ := client.Log(dest, "HEAD", 1)
+	if len(limited) != 1 {
+		t.Errorf("Log() with limit 1 returned %d commits", len(limited))
+	}
+}
+
+func TestExecClientWorktree(t *testing.T) {
+	url, _ := newBareRemote(t)
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+	if err := client.Clone(git.CloneOptions{URL: url, Dir: dest}); err != nil {
+		t.Fatalf("Clone() failed: %v", err)
+	}
+
+	worktree := filepath.Join(t.TempDir(), "feature")
+	if err := client.Worktree(dest, worktree, "origin/feature"); err != nil {
+		t.Fatalf("Worktree() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(worktree, "feature.txt")); err != nil {
+		t.Errorf("worktree is missing feature.txt: %v", err)
+	}
+
+	if err := client.RemoveWorktree(dest, worktree); err != nil {
+		t.Fatalf("RemoveWorktree() failed: %v", err)
+	}
+	if _, err := os.Stat(worktree); !os.IsNotExist(err) {
+		t.Error("RemoveWorktree() left the directory behind")
+	}
+
+	// A worktree whose directory was deleted doesn't block its path
+	if err := client.Worktree(dest, worktree, "origin/main"); err != nil {
+		t.Fatalf("Worktree() failed: %v", err)
+	}
+	os.RemoveAll(worktree)
+	if err := client.Worktree(dest, worktree, "origin/feature"); err != nil {
+		t.Errorf("Worktree() on the path of a deleted worktree failed: %v", err)
+	}
+}
+
+func TestFakeClient(t *testing.T) {
+	const url = "https://github.com/user/repo.git"
+	fake := git.NewFakeClient()
+	fake.AddCommit(url, "main", git.Commit{SHA: "aaaaaaaa11111111", Subject: "Initial"})
+	fake.AddCommit(url, "release", git.Commit{SHA: "bbbbbbbb22222222", Subject: "Release"})
+
+	if err := fake.Clone(git.CloneOptions{URL: url}); err != nil {
+		t.Fatalf("Clone() failed: %v", err)
+	}
+	if err := fake.Clone(git.CloneOptions{URL: url}); err == nil {
+		t.Error("Clone() into an existing directory should fail")
+	}
+
+	head, _ := fake.RevParse("repo", "HEAD")
+	if head != "aaaaaaaa11111111" {
+		t.Errorf("RevParse(HEAD) = %q", head)
+	}
+
+	fake.AddCommit(url, "main", git.Commit{SHA: "cccccccc33333333", Subject: "Second"})
+	if sha, _ := fake.RevParse("repo", "origin/main"); sha != "aaaaaaaa11111111" {
+		t.Errorf("RevParse(origin/main) before fetch = %q", sha)
+	}
+	fake.Fetch("repo", git.FetchOptions{})
+	fake.Checkout("repo", "origin/main")
+	if commits, _ := fake.Log("repo", "HEAD", 1); len(commits) != 1 || commits[0].Subject != "Second" {
+		t.Errorf("Log(HEAD) after fetch and checkout = %v", commits)
+	}
+
+	refs, _ := fake.LsRemote(url, "release")
+	if len(refs) != 1 || refs[0].SHA != "bbbbbbbb22222222" {
+		t.Errorf("LsRemote(release) = %v", refs)
+	}
+
+	fake.Errors["Fetch"] = os.ErrPermission
+	if err := fake.Fetch("repo", git.FetchOptions{}); err != os.ErrPermission {
+		t.Errorf("Fetch() with injected error = %v", err)
+	}
+
+	expectedCalls := []string{"Clone " + url, "Clone " + url, "RevParse repo HEAD"}
+	for i, call := range expectedCalls {
+		if fake.Calls[i] != call {
+			t.Errorf("Calls[%d] = %q, want %q", i, fake.Calls[i], call)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCommandErrorCategories(t *testing.T) {
+	tests := []struct {
+		name     string
+		stderr   string
+		expected error
+	}{
+		{"HTTPS authentication", "fatal: Authentication failed for 'https://github.com/o/r.git/'", git.ErrAuthFailed},
+		{"Prompts disabled", "fatal: could not read Username for 'https://github.com': terminal prompts disabled", git.ErrAuthFailed},
+		{"SSH key rejected", "git@github.com: Permission denied (publickey).\r\nfatal: Could not read from remote repository.", git.ErrAuthFailed},
+		{"Repository not found", "remote: Repository not found.\nfatal: repository 'https://github.com/o/r.git/' not found", git.ErrRepoNotFound},
+		{"Azure repository not found", "remote: TF401019: The Git repository with name or identifier r does not exist", git.ErrRepoNotFound},
+		{"Branch not found", "warning: Could not find remote branch nope to clone.\nfatal: Remote branch nope not found in upstream origin", git.ErrBranchNotFound},
+		{"Ref not fetched", "fatal: couldn't find remote ref refs/heads/nope", git.ErrBranchNotFound},
+		{"DNS failure", "fatal: unable to access 'https://gihtub.com/o/r.git/': Could not resolve host: gihtub.com", git.ErrHostUnreachable},
+		{"Connection refused", "ssh: connect to host example.com port 22: Connection refused", git.ErrHostUnreachable},
+		{"TLS", "fatal: unable to access 'https://git.example.com/r.git/': SSL certificate problem: self-signed certificate", git.ErrTLS},
+		{"Host key changed", "@@@ WARNING: REMOTE HOST IDENTIFICATION HAS CHANGED! @@@\nHost key verification failed.", git.ErrHostKey},
+		{"Disk full", "error: unable to write file: No space left on device", git.ErrDiskFull},
+	}
+	categories := []error{git.ErrAuthFailed, git.ErrRepoNotFound, git.ErrBranchNotFound, git.ErrHostUnreachable, git.ErrTLS, git.ErrHostKey, git.ErrDiskFull}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := error(&git.CommandError{Args: []string{"clone"}, Stderr: tt.stderr, Err: errors.New("exit status 128")})
+			for _, category := range categories {
+				if errors.Is(err, category) != (category == tt.expected) {
+					t.Errorf("errors.Is(err, %v) = %v, want %v", category, !(category == tt.expected), category == tt.expected)
+				}
+			}
+		})
+	}
+
+	unknown := &git.CommandError{Args: []string{"clone"}, Stderr: "fatal: something else", Err: errors.New("exit status 128")}
+	for _, category := range categories {
+		if errors.Is(unknown, category) {
+			t.Errorf("errors.Is(unknown, %v) = true, want false", category)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name      string
+		stderr    string
+		transient bool
+	}{
+		{"Dropped connection", "error: RPC failed; curl 56 Recv failure: Connection reset by peer\nfatal: early EOF", true},
+		{"Server unavailable", "fatal: unable to access 'https://github.com/o/r.git/': The requested URL returned error: 503", true},
+		{"Rejected login behind a hang-up", "error: RPC failed; HTTP 401 curl 22 The requested URL returned error: 401\nfatal: the remote end hung up unexpectedly", false},
+		{"Missing repository behind a hang-up", "remote: Repository not found.\nfatal: the remote end hung up unexpectedly", false},
+		{"Missing branch behind an RPC failure", "error: RPC failed\nfatal: couldn't find remote ref refs/heads/nope", false},
+		{"DNS failure", "fatal: Could not resolve host: gihtub.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := &git.CommandError{Args: []string{"fetch"}, Stderr: tt.stderr, Err: errors.New("exit status 128")}
+			if got := git.IsTransient(err); got != tt.transient {
+				t.Errorf("git.IsTransient() = %v, want %v", got, tt.transient)
+			}
+		})
+	}
+}
+
+func TestExecClientClassifiesFailures(t *testing.T) {
+	url, _ := newBareRemote(t)
+	client := newExecClient(t)
+
+	err := client.Clone(git.CloneOptions{URL: url, Dir: filepath.Join(t.TempDir(), "clone"), Branch: "missing"})
+	if !errors.Is(err, git.ErrBranchNotFound) {
+		t.Errorf("Clone() of a missing branch = %v, want ErrBranchNotFound", err)
+	}
+
+	err = client.Clone(git.CloneOptions{URL: url + "-missing", Dir: filepath.Join(t.TempDir(), "clone")})
+	if !errors.Is(err, git.ErrRepoNotFound) {
+		t.Errorf("Clone() of a missing repository = %v, want ErrRepoNotFound", err)
+	}
+}
+
+func TestExecClientRetriesTransientErrors(t *testing.T) {
+	var requests, status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	client := newExecClient(t)
+	client.Retries = 2
+	client.RetryDelay = time.Millisecond
+
+	err := client.Clone(git.CloneOptions{URL: server.URL + "/repo.git", Dir: filepath.Join(t.TempDir(), "clone")})
+	if err == nil || !git.IsTransient(err) {
+		t.Fatalf("Clone() = %v, want a transient error", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("server received %d requests, want 3 (one try and two retries)", got)
+	}
+
+	// Permanent failures are not retried
+	requests.Store(0)
+	status.Store(http.StatusNotFound)
+	err = client.Clone(git.CloneOptions{URL: server.URL + "/repo.git", Dir: filepath.Join(t.TempDir(), "clone")})
+	if !errors.Is(err, git.ErrRepoNotFound) {
+		t.Errorf("Clone() = %v, want ErrRepoNotFound", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+}
+
+func TestRemediation(t *testing.T) {
+	authErr := &git.CommandError{Args: []string{"clone"}, Stderr: "fatal: Authentication failed", Err: errors.New("exit status 128")}
+
+	tests := []struct {
+		name     string
+		cfg      config.GitConfig
+		err      error
+		contains string
+	}{
+		{"GitHub token", config.GitConfig{Provider: "github", RepoUrl: "https://github.com/o/r", AuthType: "token", Token: "t"}, authErr, "Contents: Read"},
+		{"Azure token", config.GitConfig{Provider: "azure-devops", RepoUrl: "https://dev.azure.com/org/p/_git/r", AuthType: "token", Token: "t"}, authErr, "Code (Read)"},
+		{"SSH key", config.GitConfig{RepoUrl: "git@github.com:o/r.git", AuthType: "ssh", SSHKeyPath: "~/.ssh/id_ed25519"}, authErr, "ssh -T git@github.com"},
+		{"Basic auth", config.GitConfig{RepoUrl: "https://git.example.com/r.git", AuthType: "basic"}, authErr, "git.username and git.password"},
+		{"Missing branch", config.GitConfig{RepoUrl: "https://github.com/o/r", AuthType: "token", Token: "t", Branch: "develop"}, git.ErrBranchNotFound, `"develop" does not exist`},
+		{"Host key", config.GitConfig{RepoUrl: "git@github.com:o/r.git", AuthType: "ssh"}, git.ErrHostKey, "ssh-keygen -R github.com"},
+		{"Unreachable over SSH", config.GitConfig{RepoUrl: "git@github.com:o/r.git", AuthType: "ssh"}, git.ErrHostUnreachable, "port 22"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tips := git.Remediation(tt.err, &tt.cfg)
+			if !strings.Contains(strings.Join(tips, "\n"), tt.contains) {
+				t.Errorf("Remediation() = %q, want a tip containing %q", tips, tt.contains)
+			}
+		})
+	}
+
+	if tips := git.Remediation(errors.New("something else"), &config.GitConfig{}); tips != nil {
+		t.Errorf("Remediation() of an unknown error = %q, want nil", tips)
+	}
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"automateLife/builder"
+	"automateLife/hooks"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeScript writes an executable shell script
+func writeScript(t *testing.T, path string, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// stubAutomateLife writes a stand-in for the automateLife binary that logs
+// its arguments and input, and fails when HOOK_EXIT says so
+func stubAutomateLife(t *testing.T, dir string, log string) string {
+	t.Helper()
+	stub := filepath.Join(dir, "automateLife")
+	writeScript(t, stub, `echo "automateLife $*" >> '`+log+`'
+cat >> '`+log+`'
+exit ${HOOK_EXIT:-0}
+`)
+	return stub
+}
+
+func readLog(t *testing.T, log string) string {
+	t.Helper()
+	data, _ := os.ReadFile(log)
+	os.Remove(log)
+	return string(data)
+}
+
+func TestHooksInstallChainsExistingHook(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	os.MkdirAll(repo, 0755)
+	initRepo(t, repo)
+	commitFile(t, repo, "README.md", "hello", "Initial commit")
+	runGitIn(t, repo, "config", "core.hooksPath", ".githooks")
+	log := filepath.Join(root, "log")
+
+	dir, err := hooks.Dir(newExecClient(t), repo)
+	if err != nil || dir != filepath.Join(repo, ".githooks") {
+		t.Fatalf("Dir() = %q, %v, want core.hooksPath honoured", dir, err)
+	}
+	os.MkdirAll(dir, 0755)
+	previous := "echo previous >> '" + log + "'\n"
+	writeScript(t, filepath.Join(dir, "pre-commit"), previous)
+
+	opts := hooks.Options{Executable: stubAutomateLife(t, root, log), ConfigFile: "/cfg/ConfigFile.json", Stages: []string{"install", "test"}, Changed: true}
+	status, err := hooks.Install(dir, "pre-commit", opts)
+	if err != nil || !status.Installed || !status.Chained {
+		t.Fatalf("Install() = %+v, %v, want installed and chained", status, err)
+	}
+	if !reflect.DeepEqual(status.Options.Stages, opts.Stages) || !status.Options.Changed {
+		t.Errorf("Install() options = %+v", status.Options)
+	}
+
+	commitFile(t, repo, "a.go", "package a", "Add a")
+	got := readLog(t, log)
+	want := "previous\nautomateLife hook-run --config /cfg/ConfigFile.json --stages install,test --changed pre-commit\n"
+	if got != want {
+		t.Errorf("hooks ran:\n%s\nwant:\n%s", got, want)
+	}
+
+	// A failing run aborts the commit
+	os.WriteFile(filepath.Join(repo, "b.go"), []byte("package a"), 0644)
+	runGitIn(t, repo, "add", "b.go")
+	cmd := exec.Command("git", "-C", repo, "commit", "-q", "-m", "Add b")
+	cmd.Env = append(os.Environ(), "HOOK_EXIT=1")
+	if err := cmd.Run(); err == nil {
+		t.Error("commit succeeded although the hook failed")
+	}
+
+	// Installing again keeps the chained hook
+	if status, err := hooks.Install(dir, "pre-commit", hooks.Options{Executable: opts.Executable, Stages: []string{"test"}}); err != nil || !status.Chained || status.Options.Changed {
+		t.Errorf("reinstall = %+v, %v", status, err)
+	}
+
+	status, err = hooks.Uninstall(dir, "pre-commit")
+	if err != nil || status.Installed || status.Chained || !status.Foreign {
+		t.Errorf("Uninstall() = %+v, %v, want the previous hook back", status, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "pre-commit")); string(data) != "#!/bin/sh\n"+previous {
+		t.Errorf("restored hook = %q", data)
+	}
+	if _, err := hooks.Uninstall(dir, "pre-commit"); !errors.Is(err, hooks.ErrForeignHook) {
+		t.Errorf("Uninstall() of someone else's hook = %v, want ErrForeignHook", err)
+	}
+	if _, err := hooks.Install(dir, "post-merge", opts); !errors.Is(err, hooks.ErrUnknownHook) {
+		t.Errorf("Install(post-merge) = %v, want ErrUnknownHook", err)
+	}
+}
+
+func TestPrePushHookPassesRefsToBoth(t *testing.T) {
+	_, work := newBareRemote(t)
+	root := t.TempDir()
+	log := filepath.Join(root, "log")
+	dir := filepath.Join(work, ".git", "hooks")
+	writeScript(t, filepath.Join(dir, "pre-push"), "echo \"previous $1\" >> '"+log+"'\ncat >> '"+log+"'\n")
+
+	if _, err := hooks.Install(dir, "pre-push", hooks.Options{Executable: stubAutomateLife(t, root, log), ConfigFile: "/cfg/ConfigFile.json", Stages: []string{"test"}}); err != nil {
+		t.Fatal(err)
+	}
+	sha := commitFile(t, work, "b.txt", "b", "Change")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+
+	line := "refs/heads/main " + sha + " refs/heads/main "
+	got := readLog(t, log)
+	if strings.Count(got, line) != 2 || !strings.Contains(got, "previous origin\n") || !strings.Contains(got, "--stages test pre-push origin ") {
+		t.Errorf("hooks ran:\n%s\nwant both to get the pushed refs", got)
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	repo := t.TempDir()
+	initRepo(t, repo)
+	base := commitFile(t, repo, "README.md", "hello", "Initial commit")
+	runGitIn(t, repo, "checkout", "-q", "-b", "feature")
+	os.MkdirAll(filepath.Join(repo, "pkg"), 0755)
+	commitFile(t, repo, "pkg/a.go", "package pkg", "Add pkg")
+	client := newExecClient(t)
+
+	if files, err := client.ChangedFiles(repo, "main", "feature"); err != nil || !reflect.DeepEqual(files, []string{"pkg/a.go"}) {
+		t.Errorf("ChangedFiles(main, feature) = %v, %v", files, err)
+	}
+	// Commits on the base after the fork don't count
+	runGitIn(t, repo, "checkout", "-q", "main")
+	commitFile(t, repo, "main.go", "package main", "Change main")
+	runGitIn(t, repo, "checkout", "-q", "feature")
+	if files, _ := client.ChangedFiles(repo, "main", "feature"); !reflect.DeepEqual(files, []string{"pkg/a.go"}) {
+		t.Errorf("ChangedFiles() after main moved = %v", files)
+	}
+
+	os.WriteFile(filepath.Join(repo, "staged file.go"), []byte("package main"), 0644)
+	runGitIn(t, repo, "add", ".")
+	if files, err := client.ChangedFiles(repo, "HEAD", ""); err != nil || !reflect.DeepEqual(files, []string{"staged file.go"}) {
+		t.Errorf("ChangedFiles(HEAD, staged) = %v, %v", files, err)
+	}
+	if _, err := client.ChangedFiles(repo, base+"0", "feature"); err == nil {
+		t.Error("ChangedFiles() expected error for an unknown base, got nil")
+	}
+}
+
+func TestChangedTestCommand(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"a/a.go", "a/b/b.go", "root.go"} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0755)
+		os.WriteFile(filepath.Join(dir, file), []byte("package x"), 0644)
+	}
+
+	tests := []struct {
+		name     string
+		language string
+		command  string
+		files    []string
+		want     string
+		ok       bool
+	}{
+		{"packages", "go", "go test -race ./... -count=1", []string{"a/b/b.go", "a/a.go", "a/a_test.go", "README.md"}, "go test -race ./a ./a/b -count=1", true},
+		{"root package", "go", "go test ./...", []string{"root.go"}, "go test .", true},
+		{"deleted package", "go", "go test ./...", []string{"gone/gone.go"}, "", true},
+		{"no go files", "go", "go test ./...", []string{"docs/index.md", "a/testdata/x.go"}, "", true},
+		{"module change", "go", "go test ./...", []string{"a/a.go", "go.mod"}, "go test ./...", false},
+		{"custom command", "go", "make test", []string{"a/a.go"}, "make test", false},
+		{"other language", "python", "pytest", []string{"a.py"}, "pytest", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := builder.ChangedTestCommand(tt.language, tt.command, dir, tt.files)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("ChangedTestCommand() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHookRunDescribesTheCommit(t *testing.T) {
+	dir := t.TempDir()
+	initRepo(t, dir)
+	sha := commitFile(t, dir, "a.txt", "a", "First")
+	binary := buildBinary(t)
+
+	configFile := filepath.Join(t.TempDir(), "ConfigFile.json")
+	os.WriteFile(configFile, []byte(`{"build": {"test_command": "echo commit $AUTOMATELIFE_COMMIT_REF $AUTOMATELIFE_COMMIT_SHA"}}`), 0600)
+
+	cmd := exec.Command(binary, "hook-run", "--config", configFile, "--stages", "test", "pre-commit")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(out), "commit main "+sha+"\n") {
+		t.Errorf("hook-run = %v, output:\n%s\nwant the commit described", err, out)
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"automateLife/git"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepo creates an empty git repository with a committer identity
+func initRepo(t *testing.T, dir string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	runGitIn(t, dir, "init", "-q", "-b", "main")
+	runGitIn(t, dir, "config", "user.email", "test@example.com")
+	runGitIn(t, dir, "config", "user.name", "Test User")
+	runGitIn(t, dir, "config", "commit.gpgsign", "false")
+}
+
+func runGitIn(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestIsIgnored(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "ConfigFile.json")
+	os.WriteFile(configPath, []byte("{}"), 0600)
+
+	// Outside of a repository
+	if _, inRepo, err := git.IsIgnored(configPath); err != nil || inRepo {
+		t.Fatalf("git.IsIgnored() outside repo = inRepo %v, err %v", inRepo, err)
+	}
+
+	initRepo(t, dir)
+	ignored, inRepo, err := git.IsIgnored(configPath)
+	if err != nil || !inRepo || ignored {
+		t.Fatalf("git.IsIgnored() = ignored %v, inRepo %v, err %v; want not ignored inside repo", ignored, inRepo, err)
+	}
+
+	if err := git.AddToGitignore(configPath); err != nil {
+		t.Fatalf("git.AddToGitignore() failed: %v", err)
+	}
+	// Adding twice must not duplicate the entry
+	if err := git.AddToGitignore(configPath); err != nil {
+		t.Fatalf("git.AddToGitignore() failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if strings.Count(string(data), "/ConfigFile.json") != 1 {
+		t.Errorf(".gitignore = %q, want a single entry", string(data))
+	}
+
+	ignored, _, err = git.IsIgnored(configPath)
+	if err != nil || !ignored {
+		t.Errorf("git.IsIgnored() after AddToGitignore = %v, %v; want ignored", ignored, err)
+	}
+}
+
+func TestCommitsTouching(t *testing.T) {
+	dir := t.TempDir()
+	initRepo(t, dir)
+
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("readme"), 0644)
+	runGitIn(t, dir, "add", ".")
+	runGitIn(t, dir, "commit", "-q", "-m", "initial")
+
+	commits, err := git.CommitsTouching(dir, "ConfigFile.json")
+	if err != nil || len(commits) != 0 {
+		t.Fatalf("git.CommitsTouching() = %v, %v; want none", commits, err)
+	}
+
+	os.MkdirAll(filepath.Join(dir, "deploy"), 0755)
+	os.WriteFile(filepath.Join(dir, "deploy", "ConfigFile.json"), []byte(`{"git":{"token":"x"}}`), 0644)
+	runGitIn(t, dir, "add", ".")
+	runGitIn(t, dir, "commit", "-q", "-m", "add config")
+	runGitIn(t, dir, "rm", "-q", "deploy/ConfigFile.json")
+	runGitIn(t, dir, "commit", "-q", "-m", "remove config")
+
+	commits, err = git.CommitsTouching(dir, "ConfigFile.json")
+	if err != nil {
+		t.Fatalf("git.CommitsTouching() failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Errorf("git.CommitsTouching() = %v, want 2 commits", commits)
+	}
+}
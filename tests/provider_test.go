@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"automateLife/provider"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestProviderLookup(t *testing.T) {
+	if p, err := provider.Lookup(""); p != nil || err != nil {
+		t.Errorf("provider.Lookup(\"\") = %v, %v, want nil, nil", p, err)
+	}
+	if p, err := provider.Lookup("gitlab"); p != provider.GitLab || err != nil {
+		t.Errorf("provider.Lookup(\"gitlab\") = %v, %v", p, err)
+	}
+	if _, err := provider.Lookup("sourceforge"); err == nil {
+		t.Error("provider.Lookup() expected error for an unknown provider, got nil")
+	}
+}
+
+func TestProviderParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider *provider.Provider
+		url      string
+		https    string
+		ssh      string
+		errorMsg string
+	}{
+		{
+			name:     "GitHub HTTPS",
+			provider: provider.GitHub,
+			url:      "https://github.com/user/repo",
+			https:    "https://github.com/user/repo.git",
+			ssh:      "git@github.com:user/repo.git",
+		},
+		{
+			name:     "GitHub SSH",
+			provider: provider.GitHub,
+			url:      "git@github.com:user/repo.git",
+			https:    "https://github.com/user/repo.git",
+			ssh:      "git@github.com:user/repo.git",
+		},
+		{
+			name:     "GitHub Enterprise",
+			provider: provider.GitHub,
+			url:      "https://github.example.com/team/repo.git",
+			https:    "https://github.example.com/team/repo.git",
+			ssh:      "git@github.example.com:team/repo.git",
+		},
+		{
+			name:     "GitLab nested groups",
+			provider: provider.GitLab,
+			url:      "ssh://git@gitlab.com/group/sub/repo.git",
+			https:    "https://gitlab.com/group/sub/repo.git",
+			ssh:      "git@gitlab.com:group/sub/repo.git",
+		},
+		{
+			name:     "Bitbucket with user",
+			provider: provider.Bitbucket,
+			url:      "https://someone@bitbucket.org/team/repo.git",
+			https:    "https://bitbucket.org/team/repo.git",
+			ssh:      "git@bitbucket.org:team/repo.git",
+		},
+		{
+			name:     "Azure DevOps HTTPS",
+			provider: provider.AzureDevOps,
+			url:      "https://org@dev.azure.com/org/project/_git/repo",
+			https:    "https://dev.azure.com/org/project/_git/repo",
+			ssh:      "git@ssh.dev.azure.com:v3/org/project/repo",
+		},
+		{
+			name:     "Azure DevOps SSH",
+			provider: provider.AzureDevOps,
+			url:      "git@ssh.dev.azure.com:v3/org/project/repo",
+			https:    "https://dev.azure.com/org/project/_git/repo",
+			ssh:      "git@ssh.dev.azure.com:v3/org/project/repo",
+		},
+		{
+			name:     "Azure DevOps legacy visualstudio.com",
+			provider: provider.AzureDevOps,
+			url:      "https://org.visualstudio.com/DefaultCollection/project/_git/repo",
+			https:    "https://dev.azure.com/org/project/_git/repo",
+			ssh:      "git@ssh.dev.azure.com:v3/org/project/repo",
+		},
+		{
+			name:     "URL of another provider",
+			provider: provider.GitHub,
+			url:      "https://gitlab.com/group/repo.git",
+			errorMsg: "is a gitlab URL but git.provider is github",
+		},
+		{
+			name:     "GitHub URL with extra path",
+			provider: provider.GitHub,
+			url:      "https://github.com/user/repo/tree/main",
+			errorMsg: "not a valid github repository URL",
+		},
+		{
+			name:     "Azure DevOps URL without _git",
+			provider: provider.AzureDevOps,
+			url:      "https://dev.azure.com/org/project/repo/extra",
+			errorMsg: "not a valid azure-devops repository URL",
+		},
+		{
+			name:     "Local path",
+			provider: provider.GitHub,
+			url:      "/srv/git/repo.git",
+			errorMsg: "is a local path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := tt.provider.Parse(tt.url)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Parse(%q) error = %v, want error containing %q", tt.url, err, tt.errorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.url, err)
+			}
+			if got := repo.HTTPS(); got != tt.https {
+				t.Errorf("HTTPS() = %q, want %q", got, tt.https)
+			}
+			if got := repo.SSH(); got != tt.ssh {
+				t.Errorf("SSH() = %q, want %q", got, tt.ssh)
+			}
+		})
+	}
+}
+
+func TestBuildAuthURLConvertsForProvider(t *testing.T) {
+	cfg := config.GitConfig{Provider: "github", RepoUrl: "git@github.com:user/repo.git", AuthType: "token", Token: "t"}
+	if got, err := git.BuildAuthURL(&cfg); err != nil || got != "https://github.com/user/repo.git" {
+		t.Errorf("git.BuildAuthURL() with token = %q, %v", got, err)
+	}
+
+	cfg = config.GitConfig{Provider: "azure-devops", RepoUrl: "https://dev.azure.com/org/project/_git/repo", AuthType: "ssh"}
+	if got, err := git.BuildAuthURL(&cfg); err != nil || got != "git@ssh.dev.azure.com:v3/org/project/repo" {
+		t.Errorf("git.BuildAuthURL() with ssh = %q, %v", got, err)
+	}
+
+	cfg = config.GitConfig{Provider: "bitbucket", RepoUrl: "https://github.com/user/repo.git", AuthType: "token", Token: "t"}
+	if _, err := git.BuildAuthURL(&cfg); err == nil {
+		t.Error("git.BuildAuthURL() expected error for a URL of another provider, got nil")
+	}
+}
+
+func TestTokenAuthHeaderUsername(t *testing.T) {
+	tests := map[string]string{
+		"":             "secret:",
+		"github":       "x-access-token:secret",
+		"gitlab":       "oauth2:secret",
+		"bitbucket":    "x-token-auth:secret",
+		"azure-devops": ":secret",
+	}
+
+	for name, expected := range tests {
+		header, err := git.GetAuthHeader(&config.GitConfig{Provider: name, AuthType: "token", Token: "secret"})
+		if err != nil {
+			t.Fatalf("git.GetAuthHeader() for %q failed: %v", name, err)
+		}
+		encoded := header[strings.LastIndex(header, " ")+1:]
+		decoded, _ := base64.StdEncoding.DecodeString(encoded)
+		if string(decoded) != expected {
+			t.Errorf("token credentials for provider %q = %q, want %q", name, decoded, expected)
+		}
+	}
+}
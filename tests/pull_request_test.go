@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"automateLife/git"
+	"automateLife/provider"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPullRequestRef(t *testing.T) {
+	tests := []struct {
+		provider *provider.Provider
+		ref      string
+		merged   bool
+	}{
+		{provider.GitHub, "refs/pull/42/head", false},
+		{provider.GitLab, "refs/merge-requests/42/head", false},
+		{provider.Bitbucket, "refs/pull-requests/42/from", false},
+		{provider.AzureDevOps, "refs/pull/42/merge", true},
+	}
+	for _, tt := range tests {
+		ref, merged, err := tt.provider.PullRequestRef(42)
+		if err != nil || ref != tt.ref || merged != tt.merged {
+			t.Errorf("%s PullRequestRef(42) = %q, %v, %v, want %q, %v", tt.provider.Name, ref, merged, err, tt.ref, tt.merged)
+		}
+	}
+
+	var generic *provider.Provider
+	if _, _, err := generic.PullRequestRef(42); err == nil || !strings.Contains(err.Error(), "git.provider") {
+		t.Errorf("PullRequestRef() without a provider = %v, want an error asking for git.provider", err)
+	}
+	if _, _, err := provider.GitHub.PullRequestRef(0); err == nil {
+		t.Error("PullRequestRef(0) expected error, got nil")
+	}
+}
+
+// pushPullRequest commits name on a branch off main and publishes it as refs/pull/<n>/head
+func pushPullRequest(t *testing.T, work string, n string, name string, content string) string {
+	t.Helper()
+	runGitIn(t, work, "checkout", "-q", "-b", "pr-"+n, "main")
+	sha := commitFile(t, work, name, content, "Pull request "+n)
+	runGitIn(t, work, "push", "-q", "origin", "HEAD:refs/pull/"+n+"/head")
+	runGitIn(t, work, "checkout", "-q", "main")
+	return sha
+}
+
+func TestCloneOrUpdatePullRequest(t *testing.T) {
+	url, work := newBareRemote(t)
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+	prHead := pushPullRequest(t, work, "1", "pr.txt", "pull request")
+	// main moves on after the pull request was opened
+	mainHead := commitFile(t, work, "main.txt", "main", "Main moves on")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+
+	pr := &git.PullRequest{Number: 1, Ref: "refs/pull/1/head"}
+	result, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, PullRequest: pr})
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() failed: %v", err)
+	}
+	if !result.Cloned || result.NewCommit != prHead || result.Ref != "refs/pull/1/head" || result.Branch != "" {
+		t.Errorf("result = %+v, want the pull request head checked out", result)
+	}
+	if branch, _ := client.CurrentBranch(dest); branch != "" {
+		t.Errorf("HEAD is on %s, want detached", branch)
+	}
+
+	// Merged into the target branch, updating the detached clone
+	pr.Merge = true
+	result, err = git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, PullRequest: pr})
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() with merge failed: %v", err)
+	}
+	if result.Cloned || result.OldCommit != prHead {
+		t.Errorf("result = %+v, want an update from the pull request head", result)
+	}
+	parents := strings.Fields(runGitIn(t, dest, "rev-list", "--parents", "-n1", "HEAD"))
+	if len(parents) != 3 || parents[1] != mainHead || parents[2] != prHead {
+		t.Errorf("HEAD parents = %v, want a merge of %s into %s", parents[1:], prHead, mainHead)
+	}
+	if !strings.Contains(result.Commit.Subject, "#1 into main") {
+		t.Errorf("merge subject = %q", result.Commit.Subject)
+	}
+
+	// A plain start afterwards goes back to the default branch
+	result, err = git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest})
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() after the pull request failed: %v", err)
+	}
+	if result.Branch != "main" || result.NewCommit != mainHead {
+		t.Errorf("result = %+v, want main at %s", result, mainHead)
+	}
+	if branch, _ := client.CurrentBranch(dest); branch != "main" {
+		t.Errorf("HEAD is on %q, want main", branch)
+	}
+}
+
+func TestCloneOrUpdatePullRequestConflicts(t *testing.T) {
+	url, work := newBareRemote(t)
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+	pushPullRequest(t, work, "2", "README.md", "changed by the pull request")
+	commitFile(t, work, "README.md", "changed on main", "Conflicting change")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+
+	pr := &git.PullRequest{Number: 2, Ref: "refs/pull/2/head", Merge: true}
+	_, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, PullRequest: pr})
+	if !errors.Is(err, git.ErrMergeConflict) || !strings.Contains(err.Error(), "README.md") {
+		t.Fatalf("CloneOrUpdate() = %v, want a merge conflict in README.md", err)
+	}
+	if dirty, _ := client.IsDirty(dest); dirty {
+		t.Error("clone was left mid-merge, want the merge aborted")
+	}
+
+	pr = &git.PullRequest{Number: 3, Ref: "refs/pull/3/head"}
+	if _, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, PullRequest: pr}); !errors.Is(err, git.ErrBranchNotFound) {
+		t.Errorf("CloneOrUpdate() of a missing pull request = %v, want ErrBranchNotFound", err)
+	}
+}
+
+func TestCloneOrUpdatePullRequestWithFake(t *testing.T) {
+	const url = "https://github.com/user/repo.git"
+	fake := git.NewFakeClient()
+	fake.AddCommit(url, "main", git.Commit{SHA: "1111111111", Subject: "First"})
+	fake.AddCommit(url, "topic", git.Commit{SHA: "2222222222", Subject: "Topic"})
+	fake.Remotes[url].Refs["refs/pull/7/head"] = "2222222222"
+
+	pr := &git.PullRequest{Number: 7, Ref: "refs/pull/7/head", Merge: true}
+	result, err := git.CloneOrUpdate(fake, git.SyncOptions{URL: url, Dir: "repo", PullRequest: pr})
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() failed: %v", err)
+	}
+	if result.Commit.Subject != "Merge pull request #7 into main" {
+		t.Errorf("result = %+v, want the merge commit", result)
+	}
+	if last := fake.Calls[len(fake.Calls)-2]; last != "Merge repo refs/pull/7/head" {
+		t.Errorf("calls = %v", fake.Calls)
+	}
+
+	fake.Errors["Merge"] = git.ErrMergeConflict
+	if _, err := git.CloneOrUpdate(fake, git.SyncOptions{URL: url, Dir: "repo", PullRequest: pr}); !errors.Is(err, git.ErrMergeConflict) {
+		t.Errorf("CloneOrUpdate() = %v, want ErrMergeConflict", err)
+	}
+}
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"automateLife/builder"
+	"automateLife/redact"
+	"automateLife/runlog"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunLogLines(t *testing.T) {
+	run, err := runlog.Start(t.TempDir(), "test", runlog.Options{Timestamps: true, Streams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, err := run.Step("test", "go test ./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	step.Stdout().Write([]byte("one\npart"))
+	step.Stderr().Write([]byte("warning\n"))
+	step.Stdout().Write([]byte("ial\nlast"))
+	if err := step.Finish(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(run.LogPath(run.Steps[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A line cut short by the other stream ends there, and its rest gets a tag of its own
+	want := []string{"[out] one", "[out] part", "[err] warning", "[out] ial", "[out] last"}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	stamp := regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3} `)
+	if len(lines) != len(want) {
+		t.Fatalf("log = %q, want %d lines", data, len(want))
+	}
+	for i, line := range lines {
+		if !stamp.MatchString(line) || stamp.ReplaceAllString(line, "") != want[i] {
+			t.Errorf("line %d = %q, want a timestamp and %q", i+1, line, want[i])
+		}
+	}
+	if run.Steps[0].Result != "passed" || run.Steps[0].Log != "test.log" {
+		t.Errorf("step = %+v", run.Steps[0])
+	}
+}
+
+func TestRunLogRunStep(t *testing.T) {
+	redact.Add("s3cr3t-token")
+	defer redact.Reset()
+
+	run, err := runlog.Start(t.TempDir(), "hook pre-commit", runlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	step := builder.Step{Name: "test", Command: "echo using s3cr3t-token"}
+	if err := run.RunStep(context.Background(), step, &out, &out); err != nil {
+		t.Fatalf("RunStep() = %v", err)
+	}
+	if data, _ := os.ReadFile(run.LogPath(run.Steps[0])); string(data) != out.String() || string(data) != "using "+redact.Mask+"\n" {
+		t.Errorf("log = %q, terminal = %q, want both masked", data, out.String())
+	}
+	if strings.Contains(run.Steps[0].Command, "s3cr3t") {
+		t.Errorf("recorded command %q shows the secret", run.Steps[0].Command)
+	}
+
+	// A second step of the same name gets a log of its own
+	step = builder.Step{Name: "test", Command: "sleep 5", Timeout: 100 * time.Millisecond, Grace: 100 * time.Millisecond}
+	var timeout *builder.TimeoutError
+	if err := run.RunStep(context.Background(), step, &out, &out); !errors.As(err, &timeout) {
+		t.Fatalf("RunStep() = %v, want a timeout", err)
+	}
+	if err := run.Finish("failed"); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := runlog.Find(filepath.Dir(run.Dir()), "last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Title != "hook pre-commit" || saved.Result != "failed" || len(saved.Steps) != 2 {
+		t.Fatalf("saved run = %+v", saved)
+	}
+	if second := saved.Steps[1]; second.Log != "test-2.log" || second.Result != "timed out" || !strings.Contains(second.Error, "timed out after") {
+		t.Errorf("second step = %+v", second)
+	}
+
+	// A nil run still runs the step
+	var none *runlog.Run
+	if err := none.RunStep(context.Background(), builder.Step{Command: "true"}, &out, &out); err != nil {
+		t.Errorf("RunStep() without a run = %v", err)
+	}
+}
+
+func TestRunLogWriteErrorsDontFailTheStep(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("/dev/full is not available")
+	}
+	run, err := runlog.Start(t.TempDir(), "test", runlog.Options{Streams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every write to the log fails as if the disk were full
+	if err := os.Symlink("/dev/full", filepath.Join(run.Dir(), "test.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := run.RunStep(context.Background(), builder.Step{Name: "test", Command: "echo hello"}, &out, &out); err != nil {
+		t.Fatalf("RunStep() = %v, want the step to pass without its log", err)
+	}
+	if !strings.HasPrefix(out.String(), "hello\n") {
+		t.Errorf("terminal = %q, want the step output", out.String())
+	}
+	if strings.Count(out.String(), "failed to write the test log") != 1 {
+		t.Errorf("terminal = %q, want the log error reported once", out.String())
+	}
+	if run.Steps[0].Result != "passed" {
+		t.Errorf("step = %+v, want passed", run.Steps[0])
+	}
+}
+
+func TestRunLogFind(t *testing.T) {
+	root := t.TempDir()
+	if _, err := runlog.Find(root, "last"); !errors.Is(err, runlog.ErrNoRuns) {
+		t.Errorf("Find() without runs = %v, want ErrNoRuns", err)
+	}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		run, err := runlog.Start(root, "test", runlog.Options{Keep: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		run.Finish("passed")
+		ids = append(ids, run.ID)
+	}
+	runs, err := runlog.List(root)
+	if err != nil || len(runs) != 2 || runs[0].ID != ids[1] || runs[1].ID != ids[2] {
+		t.Fatalf("List() = %d runs, %v, want the newest 2 of %v", len(runs), err, ids)
+	}
+
+	if run, err := runlog.Find(root, ""); err != nil || run.ID != ids[2] {
+		t.Errorf("Find(last) = %v, %v, want %s", run, err, ids[2])
+	}
+	if run, err := runlog.Find(root, ids[1]); err != nil || run.ID != ids[1] {
+		t.Errorf("Find(%s) = %v, %v", ids[1], run, err)
+	}
+	if _, err := runlog.Find(root, "2"); err == nil || !strings.Contains(err.Error(), "matches 2 runs") {
+		t.Errorf("Find() with an ambiguous prefix = %v", err)
+	}
+	if _, err := runlog.Find(root, "19990101"); err == nil {
+		t.Error("Find() expected an error for an unknown run, got nil")
+	}
+}
+
+func TestRunLogKeepsLiveRuns(t *testing.T) {
+	root := t.TempDir()
+	live, err := runlog.Start(root, "watch", runlog.Options{Keep: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var finished []string
+	for i := 0; i < 2; i++ {
+		run, err := runlog.Start(root, "test", runlog.Options{Keep: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		run.Finish("passed")
+		finished = append(finished, run.ID)
+	}
+
+	// The oldest finished run makes way, the one still going stays
+	runs, err := runlog.List(root)
+	if err != nil || len(runs) != 2 || runs[0].ID != live.ID || runs[1].ID != finished[1] {
+		t.Fatalf("List() = %d runs, %v, want %s still going and %s", len(runs), err, live.ID, finished[1])
+	}
+	if _, err := os.Stat(live.Dir()); err != nil {
+		t.Errorf("the live run's directory was removed: %v", err)
+	}
+}
@@ -2,12 +2,17 @@ package tests
 
 import (
 	"automateLife/builder"
+	"automateLife/shell"
+	"context"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
-func TestRunCommand(t *testing.T) {
+func TestStepRun(t *testing.T) {
 	tests := []struct {
 		name        string
 		command     string
@@ -42,21 +47,95 @@ func TestRunCommand(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := builder.RunCommand(tt.command)
+			err := builder.Step{Name: "test", Command: tt.command}.Run(context.Background(), io.Discard, io.Discard)
 
 			if tt.expectError {
 				if err == nil {
-					t.Error("builder.RunCommand() expected error, got nil")
+					t.Error("Step.Run() expected error, got nil")
 				}
 			} else {
 				if err != nil {
-					t.Errorf("builder.RunCommand() unexpected error: %v", err)
+					t.Errorf("Step.Run() unexpected error: %v", err)
 				}
 			}
 		})
 	}
 }
 
+func TestStepScripts(t *testing.T) {
+	dir := t.TempDir()
+	var out strings.Builder
+	run := func(script string, useShell bool) error {
+		return builder.Step{Name: "test", Command: script, Shell: useShell, Dir: dir}.Run(context.Background(), &out, &out)
+	}
+
+	// Quoted words reach the command whole, env prefixes set its variables
+	script := "GREETING='hello world' sh -c 'echo \"$GREETING|$1\"' sh \"a b\"\n# comment\n\ntouch first && touch 'second file'"
+	if err := run(script, false); err != nil {
+		t.Fatalf("Run() error: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "hello world|a b\n") {
+		t.Errorf("output = %q, want the quoted words intact", out.String())
+	}
+	for _, name := range []string{"first", "second file"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was not created: %v", name, err)
+		}
+	}
+
+	// The first failing command stops the script
+	err := run("touch one\nfalse\ntouch two", false)
+	if err == nil || !strings.Contains(err.Error(), "false") {
+		t.Errorf("Run() = %v, want the failing command named", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "two")); !os.IsNotExist(err) {
+		t.Error("commands after the failing one ran")
+	}
+
+	if err := run("echo a | cat", false); !errors.Is(err, shell.ErrNeedsShell) {
+		t.Errorf("Run() with a pipe = %v, want ErrNeedsShell", err)
+	}
+
+	out.Reset()
+	if err := run("echo piped | tr a-z A-Z > upper\ncat upper", true); err != nil || out.String() != "PIPED\n" {
+		t.Errorf("Run() with a shell = %q, %v", out.String(), err)
+	}
+	// The shell expands its own variables and globs
+	out.Reset()
+	if err := run("for f in first s*; do echo \"$f\"; done", true); err != nil || out.String() != "first\nsecond file\n" {
+		t.Errorf("Run() of a loop = %q, %v", out.String(), err)
+	}
+	if err := run("false\ntouch after-failure", true); err == nil {
+		t.Error("shell script expected to fail at its first failing command")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "after-failure")); !os.IsNotExist(err) {
+		t.Error("shell script carried on after a failing command")
+	}
+
+	// Variables of the step reach the command and its expansions, those
+	// set in front of the command win
+	withEnv := func(script string, useShell bool) string {
+		out.Reset()
+		step := builder.Step{Name: "test", Command: script, Shell: useShell, Dir: dir, Env: []string{"STEP_VAR=step"}}
+		if err := step.Run(context.Background(), &out, &out); err != nil {
+			t.Errorf("Run(%q) error: %v", script, err)
+		}
+		return out.String()
+	}
+	if got := withEnv("echo $STEP_VAR", false); got != "step\n" {
+		t.Errorf("expanded step variable = %q, want %q", got, "step\n")
+	}
+	if got := withEnv("sh -c 'echo $STEP_VAR'", false); got != "step\n" {
+		t.Errorf("step variable in the environment = %q, want %q", got, "step\n")
+	}
+	if got := withEnv("STEP_VAR=inline sh -c 'echo $STEP_VAR'", false); got != "inline\n" {
+		t.Errorf("variable set in front of the command = %q, want %q", got, "inline\n")
+	}
+	if got := withEnv("echo $STEP_VAR", true); got != "step\n" {
+		t.Errorf("step variable in a shell = %q, want %q", got, "step\n")
+	}
+}
+
 func TestGetDefaultTestCommand(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -160,8 +239,7 @@ func TestGetDefaultTestCommand(t *testing.T) {
 	}
 }
 
-func TestAutoInstallDependencies(t *testing.T) {
-	// Create a temporary directory for testing
+func TestDefaultInstallCommand(t *testing.T) {
 	tmpDir := t.TempDir()
 	originalDir, _ := os.Getwd()
 	defer os.Chdir(originalDir)
@@ -169,144 +247,92 @@ func TestAutoInstallDependencies(t *testing.T) {
 	tests := []struct {
 		name        string
 		language    string
-		setupFiles  []string // Files to create before test
-		shouldSkip  bool     // Should return nil without running command
-		expectError bool     // Should return error
+		setupFiles  map[string]string // filename -> content
+		expected    string            // Empty when there is nothing to install
+		expectError bool
 	}{
 		{
-			name:        "Go with go.mod",
-			language:    "go",
-			setupFiles:  []string{"go.mod"},
-			shouldSkip:  false, // Will attempt to run go mod download
-			expectError: false,
+			name:       "Go with go.mod",
+			language:   "go",
+			setupFiles: map[string]string{"go.mod": "module test"},
+			expected:   "go mod download",
 		},
 		{
-			name:        "Go without go.mod",
-			language:    "go",
-			setupFiles:  []string{},
-			shouldSkip:  true, // Should return nil immediately
-			expectError: false,
+			name:     "Go without go.mod",
+			language: "go",
+			expected: "",
 		},
 		{
-			name:        "Node.js with package.json",
-			language:    "nodejs",
-			setupFiles:  []string{"package.json"},
-			shouldSkip:  false, // Will attempt npm install
-			expectError: false,
+			name:       "Node.js with package.json",
+			language:   "nodejs",
+			setupFiles: map[string]string{"package.json": `{"name": "test"}`},
+			expected:   "npm install",
 		},
 		{
-			name:        "Node.js without package.json",
-			language:    "nodejs",
-			setupFiles:  []string{},
-			shouldSkip:  true,
-			expectError: false,
+			name:       "Node.js with yarn.lock",
+			language:   "nodejs",
+			setupFiles: map[string]string{"package.json": `{"name": "test"}`, "yarn.lock": ""},
+			expected:   "yarn install",
 		},
 		{
-			name:        "Unknown language",
-			language:    "fortran",
-			setupFiles:  []string{},
-			shouldSkip:  false,
-			expectError: true, // Should error for unknown language
+			name:     "Node.js without package.json",
+			language: "nodejs",
+			expected: "",
 		},
 		{
-			name:        "Empty language",
-			language:    "",
-			setupFiles:  []string{},
-			shouldSkip:  false,
-			expectError: true,
+			name:       "Python with requirements.txt",
+			language:   "python",
+			setupFiles: map[string]string{"requirements.txt": "requests==2.28.0"},
+			expected:   "pip install -r requirements.txt",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Create a unique test directory
-			testDir := filepath.Join(tmpDir, tt.name)
-			os.MkdirAll(testDir, 0755)
-			os.Chdir(testDir)
-
-			// Create setup files
-			for _, file := range tt.setupFiles {
-				os.WriteFile(file, []byte("test content"), 0644)
-			}
-
-			err := builder.AutoInstallDependencies(tt.language)
-
-			if tt.expectError {
-				if err == nil {
-					t.Error("builder.AutoInstallDependencies() expected error, got nil")
-				}
-			} else if tt.shouldSkip {
-				if err != nil {
-					t.Errorf("builder.AutoInstallDependencies() should skip gracefully, got error: %v", err)
-				}
-			}
-			// For cases where commands actually run, we don't check errors
-			// because they may fail in test environment (which is expected)
-		})
-	}
-}
-
-func TestAutoInstallDependenciesWithMockFiles(t *testing.T) {
-	// This test verifies that the function recognizes the right files
-	// without actually running installation commands
-
-	tmpDir := t.TempDir()
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-
-	tests := []struct {
-		name       string
-		language   string
-		setupFiles map[string]string // filename -> content
-	}{
 		{
-			name:     "Node.js with package.json",
-			language: "nodejs",
-			setupFiles: map[string]string{
-				"package.json": `{"name": "test"}`,
-			},
+			name:       "Python with Pipfile",
+			language:   "python",
+			setupFiles: map[string]string{"Pipfile": "[packages]\nrequests = \"*\""},
+			expected:   "pipenv install",
 		},
 		{
-			name:     "Python with requirements.txt",
-			language: "python",
-			setupFiles: map[string]string{
-				"requirements.txt": "requests==2.28.0",
-			},
+			name:       "Ruby with Gemfile",
+			language:   "ruby",
+			setupFiles: map[string]string{"Gemfile": "source 'https://rubygems.org'"},
+			expected:   "bundle install",
 		},
 		{
-			name:     "Python with Pipfile",
-			language: "python",
-			setupFiles: map[string]string{
-				"Pipfile": "[packages]\nrequests = \"*\"",
-			},
+			name:     "Rust",
+			language: "rust",
+			expected: "cargo fetch",
 		},
 		{
-			name:     "Ruby with Gemfile",
-			language: "ruby",
-			setupFiles: map[string]string{
-				"Gemfile": "source 'https://rubygems.org'",
-			},
+			name:        "Unknown language",
+			language:    "fortran",
+			expectError: true,
+		},
+		{
+			name:        "Empty language",
+			language:    "",
+			expectError: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Create a subdirectory for this test
 			testDir := filepath.Join(tmpDir, tt.name)
 			os.MkdirAll(testDir, 0755)
 			os.Chdir(testDir)
-
-			// Create setup files
 			for filename, content := range tt.setupFiles {
 				os.WriteFile(filename, []byte(content), 0644)
 			}
 
-			// Call the function (it will likely fail because commands aren't available,
-			// but we're just testing that it detects the files correctly)
-			_ = builder.AutoInstallDependencies(tt.language)
-
-			// The main goal here is to ensure no panic occurs and the function
-			// attempts to use the correct command based on detected files
+			command, err := builder.DefaultInstallCommand(tt.language)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("builder.DefaultInstallCommand(%q) expected error, got %q", tt.language, command)
+				}
+				return
+			}
+			if err != nil || command != tt.expected {
+				t.Errorf("builder.DefaultInstallCommand(%q) = %q, %v, want %q", tt.language, command, err, tt.expected)
+			}
 		})
 	}
 }
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"automateLife/config"
+	"automateLife/shell"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShellParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []shell.Command
+	}{
+		{"plain words", "go test ./...", []shell.Command{{Args: []string{"go", "test", "./..."}}}},
+		{"quoted pattern", `go test -run "TestA|TestB" ./...`, []shell.Command{{Args: []string{"go", "test", "-run", "TestA|TestB", "./..."}}}},
+		{"quoted path", `pytest 'tests/my suite' "it's"`, []shell.Command{{Args: []string{"pytest", "tests/my suite", "it's"}}}},
+		{"escapes", `echo a\ b "say \"hi\"" '\n' ""`, []shell.Command{{Args: []string{"echo", "a b", `say "hi"`, `\n`, ""}}}},
+		{"env prefix", `CGO_ENABLED=0 GOFLAGS="-count=1 -v" go test`, []shell.Command{{Env: []string{"CGO_ENABLED=0", "GOFLAGS=-count=1 -v"}, Args: []string{"go", "test"}}}},
+		{"assignment after the command", "make VERBOSE=1", []shell.Command{{Args: []string{"make", "VERBOSE=1"}}}},
+		{"quoted name is no assignment", `"A=1" run`, []shell.Command{{Args: []string{"A=1", "run"}}}},
+		{"and list", "go vet ./... && go test ./...", []shell.Command{{Args: []string{"go", "vet", "./..."}}, {Args: []string{"go", "test", "./..."}}}},
+		{"lines and comments", "# setup\nnpm ci\n\n  npm test # unit\n", []shell.Command{{Args: []string{"npm", "ci"}}, {Args: []string{"npm", "test"}}}},
+		{"continuations", "go test \\\n  -race &&\n  go build", []shell.Command{{Args: []string{"go", "test", "-race"}}, {Args: []string{"go", "build"}}}},
+		{"hash inside a word", "echo a#b", []shell.Command{{Args: []string{"echo", "a#b"}}}},
+		{"empty", "  \n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shell.Parse(tt.script)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %#v, %v, want %#v", tt.script, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellParseErrors(t *testing.T) {
+	for _, script := range []string{"go test | tee log", "a || b", "a; b", "a > out", "a 2>&1", "echo $(date)", "echo \"`date`\"", "sleep 1 &", "(cd x)"} {
+		if _, err := shell.Parse(script); !errors.Is(err, shell.ErrNeedsShell) {
+			t.Errorf("Parse(%q) = %v, want ErrNeedsShell", script, err)
+		}
+		if !shell.NeedsShell(script) {
+			t.Errorf("NeedsShell(%q) = false", script)
+		}
+	}
+	for _, script := range []string{`echo "open`, "echo 'open", "a &&", "&& b", "a && && b", "FOO=bar"} {
+		if _, err := shell.Parse(script); err == nil || errors.Is(err, shell.ErrNeedsShell) {
+			t.Errorf("Parse(%q) = %v, want a syntax error", script, err)
+		}
+	}
+}
+
+func TestShellParseExpansion(t *testing.T) {
+	t.Setenv("HOME", "/home/dev")
+	t.Setenv("NAME", "it's a b")
+	t.Setenv("EMPTY", "")
+	t.Setenv("FLAGS", " -race\t-v\n")
+
+	tests := []struct {
+		script string
+		want   []string
+	}{
+		{`echo $NAME`, []string{"echo", "it's", "a", "b"}},
+		{`echo "$NAME"`, []string{"echo", "it's a b"}},
+		{`go test $FLAGS ./...`, []string{"go", "test", "-race", "-v", "./..."}},
+		{`echo x$FLAGS"y z"`, []string{"echo", "x", "-race", "-v", "y z"}},
+		{`echo "${NAME}!"`, []string{"echo", "it's a b!"}},
+		{`echo '$NAME' \$NAME`, []string{"echo", "$NAME", "$NAME"}},
+		{`echo $EMPTY "$EMPTY" x`, []string{"echo", "", "x"}},
+		{`echo $ cost$`, []string{"echo", "$", "cost$"}},
+		{`ls ~ ~/src a~ "~/x"`, []string{"ls", "/home/dev", "/home/dev/src", "a~", "~/x"}},
+	}
+	for _, tt := range tests {
+		got, err := shell.Parse(tt.script)
+		if err != nil || len(got) != 1 || !reflect.DeepEqual(got[0].Args, tt.want) {
+			t.Errorf("Parse(%q) = %#v, %v, want %q", tt.script, got, err, tt.want)
+		}
+	}
+
+	// Assigned values stay whole, as in sh
+	if got, err := shell.Parse(`GOFLAGS=$FLAGS go test`); err != nil || len(got) != 1 || !reflect.DeepEqual(got[0].Env, []string{"GOFLAGS= -race\t-v\n"}) {
+		t.Errorf("Parse() of an assigned expansion = %#v, %v", got, err)
+	}
+
+	// Values are only split, never parsed themselves
+	t.Setenv("NAME", "x; rm -rf ~")
+	if got, err := shell.Split("echo $NAME"); err != nil || !reflect.DeepEqual(got, []string{"echo", "x;", "rm", "-rf", "~"}) {
+		t.Errorf("Split() = %q, %v", got, err)
+	}
+	if got, err := shell.Split(`echo "$NAME"`); err != nil || !reflect.DeepEqual(got, []string{"echo", "x; rm -rf ~"}) {
+		t.Errorf("Split() of a quoted expansion = %q, %v", got, err)
+	}
+
+	for _, script := range []string{"echo $1", "echo $?", `echo "$@"`, "echo ${NAME:-x}", "rm *.tmp", "ls file?.txt", "ls [ab].go"} {
+		if _, err := shell.Parse(script); !errors.Is(err, shell.ErrNeedsShell) {
+			t.Errorf("Parse(%q) = %v, want ErrNeedsShell", script, err)
+		}
+	}
+	if got, err := shell.Split(`go test -run 'Test.*' "./x?"`); err != nil || !reflect.DeepEqual(got, []string{"go", "test", "-run", "Test.*", "./x?"}) {
+		t.Errorf("Split() of quoted globs = %q, %v", got, err)
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	for _, args := range [][]string{
+		{"go", "test", "-run", "TestA|TestB", "./..."},
+		{"echo", "it's", "", "a b", `"quoted"`, "$HOME", "#hash"},
+		{"A=1", "B=2"},
+	} {
+		line := shell.Join(args)
+		got, err := shell.Split(line)
+		if err != nil || !reflect.DeepEqual(got, args) {
+			t.Errorf("Split(Join(%q)) = %q, %v (line %s)", args, got, err, line)
+		}
+	}
+
+	command := shell.Command{Env: []string{"GOFLAGS=-count=1 -v"}, Args: []string{"go", "test"}}
+	if got, _ := shell.Parse(command.String()); !reflect.DeepEqual(got, []shell.Command{command}) {
+		t.Errorf("Parse(%s) = %#v", command, got)
+	}
+}
+
+func TestBuildCommandArrays(t *testing.T) {
+	var cfg config.Config
+	data := `{"build": {"language": "go", "install_command": "go mod download", "test_command": ["go", "test", "-run", "TestA|TestB", "./..."]}}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Build.InstallCommand != "go mod download" || cfg.Build.Language != "go" {
+		t.Errorf("Build = %+v", cfg.Build)
+	}
+	if args, err := shell.Split(cfg.Build.TestCommand); err != nil || !reflect.DeepEqual(args, []string{"go", "test", "-run", "TestA|TestB", "./..."}) {
+		t.Errorf("test_command array read as %q, splitting to %q, %v", cfg.Build.TestCommand, args, err)
+	}
+
+	// Array words are passed on exactly, variables included
+	if err := json.Unmarshal([]byte(`{"build": {"test_command": ["echo", "$HOME", "it's"]}}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if args, err := shell.Split(cfg.Build.TestCommand); err != nil || !reflect.DeepEqual(args, []string{"echo", "$HOME", "it's"}) {
+		t.Errorf("test_command array read as %q, splitting to %q, %v", cfg.Build.TestCommand, args, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"build": {"test_command": 42}}`), &cfg); err == nil {
+		t.Error("Unmarshal() expected error for a numeric command, got nil")
+	}
+}
+
+func TestValidateCommands(t *testing.T) {
+	cfg := &config.Config{
+		Git:     config.GitConfig{RepoUrl: "https://github.com/user/repo.git", AuthType: "token", Token: "t"},
+		Project: config.ProjectConfig{Type: "backend"},
+		Build:   config.BuildConfig{Language: "go", TestCommand: "go test ./... | tee test.log"},
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "build.shell") {
+		t.Errorf("Validate() with a pipe = %v, want a hint at build.shell", err)
+	}
+
+	cfg.Build.Shell = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with build.shell = %v", err)
+	}
+
+	cfg.Build = config.BuildConfig{Language: "go", InstallCommand: `echo "unterminated`}
+	if errs := cfg.ValidateAll(); len(errs) != 1 || errs[0].Field != "build.install_command" {
+		t.Errorf("ValidateAll() = %v, want build.install_command", errs)
+	}
+
+	// Workspace repositories inherit the shared commands
+	cfg.Build = config.BuildConfig{Language: "go"}
+	cfg.Workspace = []config.RepositoryConfig{{RepoUrl: "https://github.com/user/other.git", Build: config.BuildConfig{TestCommand: "make test; make lint"}}}
+	if errs := cfg.ValidateAll(); len(errs) != 1 || errs[0].Field != "workspace[0].build.test_command" {
+		t.Errorf("ValidateAll() = %v, want workspace[0].build.test_command", errs)
+	}
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newSSHSigningKey creates an SSH key and returns its path and public key line
+func newSSHSigningKey(t *testing.T, dir string, name string) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+	key := filepath.Join(dir, name)
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", name, "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen failed: %v\n%s", err, out)
+	}
+	public, _ := os.ReadFile(key + ".pub")
+	return key, strings.TrimSpace(string(public))
+}
+
+func TestVerifySSHSignatures(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	os.MkdirAll(repo, 0755)
+	initRepo(t, repo)
+	trusted, trustedPublic := newSSHSigningKey(t, root, "trusted")
+	untrusted, _ := newSSHSigningKey(t, root, "untrusted")
+	signers := filepath.Join(root, "allowed_signers")
+	os.WriteFile(signers, []byte("release@example.com "+trustedPublic+"\n"), 0644)
+	keys := git.SigningKeys{AllowedSigners: signers}
+	client := newExecClient(t)
+
+	signWith := func(key string, args ...string) {
+		runGitIn(t, repo, append([]string{"-c", "gpg.format=ssh", "-c", "user.signingkey=" + key}, args...)...)
+	}
+
+	signWith(trusted, "commit", "-q", "-S", "--allow-empty", "-m", "Signed")
+	sig, err := git.VerifyRevision(client, repo, "HEAD", keys)
+	if err != nil || !sig.Verified || sig.Signer != "release@example.com" || !strings.Contains(sig.Key, "SHA256:") {
+		t.Errorf("trusted commit = %+v, %v, want verified by release@example.com", sig, err)
+	}
+
+	// Without the allowed signers SSH signatures are never trusted
+	if sig, _ := git.VerifyRevision(client, repo, "HEAD", git.SigningKeys{}); sig == nil || sig.Verified {
+		t.Errorf("commit verified without allowed signers: %+v", sig)
+	}
+
+	// Unsigned annotated tags are trusted through their commit
+	runGitIn(t, repo, "tag", "-a", "v1", "-m", "Unsigned tag")
+	if sig, err := git.VerifyRevision(client, repo, "refs/tags/v1", keys); err != nil || !sig.Verified || sig.Object != "commit" {
+		t.Errorf("unsigned tag of a signed commit = %+v, %v", sig, err)
+	}
+
+	signWith(untrusted, "commit", "-q", "-S", "--allow-empty", "-m", "Signed by someone else")
+	if sig, err := git.VerifyRevision(client, repo, "HEAD", keys); err != nil || !sig.Signed || sig.Verified || sig.Output == "" {
+		t.Errorf("untrusted commit = %+v, %v, want signed but not verified", sig, err)
+	}
+	signWith(trusted, "tag", "-s", "v2", "-m", "Signed tag")
+	if sig, err := git.VerifyRevision(client, repo, "refs/tags/v2", keys); err != nil || !sig.Verified || sig.Object != "tag" {
+		t.Errorf("signed tag = %+v, %v, want the tag verified", sig, err)
+	}
+
+	runGitIn(t, repo, "commit", "-q", "--allow-empty", "-m", "Unsigned")
+	if sig, err := git.VerifyRevision(client, repo, "HEAD", keys); err != nil || sig.Signed || sig.Verified {
+		t.Errorf("unsigned commit = %+v, %v", sig, err)
+	}
+}
+
+func TestVerifyGPGSignatures(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+	root := t.TempDir()
+	signerHome := filepath.Join(root, "gnupg")
+	os.MkdirAll(signerHome, 0700)
+	gpg := func(args ...string) string {
+		cmd := exec.Command("gpg", append([]string{"--batch", "--homedir", signerHome}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("gpg %v failed: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+	gpg("--passphrase", "", "--quick-generate-key", "Release Bot <release@example.com>", "ed25519", "sign", "never")
+	defer exec.Command("gpgconf", "--homedir", signerHome, "--kill", "all").Run()
+	keyring := filepath.Join(root, "release.asc")
+	os.WriteFile(keyring, []byte(gpg("--armor", "--export", "release@example.com")), 0644)
+
+	repo := filepath.Join(root, "repo")
+	os.MkdirAll(repo, 0755)
+	initRepo(t, repo)
+	commit := exec.Command("git", "-C", repo, "-c", "user.signingkey=release@example.com", "commit", "-q", "-S", "--allow-empty", "-m", "Signed")
+	commit.Env = append(os.Environ(), "GNUPGHOME="+signerHome)
+	if out, err := commit.CombinedOutput(); err != nil {
+		t.Fatalf("signed commit failed: %v\n%s", err, out)
+	}
+
+	client := newExecClient(t)
+	sig, err := git.VerifyRevision(client, repo, "HEAD", git.SigningKeys{GPGKeyring: keyring})
+	if err != nil || !sig.Verified || !strings.Contains(sig.Signer, "release@example.com") || len(sig.Key) != 40 {
+		t.Errorf("trusted commit = %+v, %v, want verified with the key's fingerprint", sig, err)
+	}
+
+	// The user's own keyring is never consulted
+	sig, err = git.VerifyRevision(client, repo, "HEAD", git.SigningKeys{})
+	if err != nil || !sig.Signed || sig.Verified || sig.Key == "" {
+		t.Errorf("commit without a keyring = %+v, %v, want signed by an unknown key", sig, err)
+	}
+}
+
+func TestValidateSigningKeys(t *testing.T) {
+	cfg := &config.Config{
+		Git:     config.GitConfig{RepoUrl: "https://github.com/user/repo.git", AuthType: "token", Token: "t", RequireSigned: true},
+		Project: config.ProjectConfig{Type: "backend"},
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "git.allowed_signers") {
+		t.Errorf("Validate() without trusted keys = %v", err)
+	}
+
+	cfg.Git.AllowedSigners = filepath.Join(t.TempDir(), "missing")
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Validate() with a missing allowed signers file = %v", err)
+	}
+
+	os.WriteFile(cfg.Git.AllowedSigners, nil, 0644)
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
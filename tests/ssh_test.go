@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"automateLife/git"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// GitHub's published ed25519 host key and fingerprint
+const (
+	githubHostKey         = "github.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+	githubHostFingerprint = "SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"
+)
+
+func TestHostKeyFingerprint(t *testing.T) {
+	fingerprint, err := git.HostKeyFingerprint(githubHostKey)
+	if err != nil {
+		t.Fatalf("git.HostKeyFingerprint() failed: %v", err)
+	}
+	if fingerprint != githubHostFingerprint {
+		t.Errorf("git.HostKeyFingerprint() = %q, want %q", fingerprint, githubHostFingerprint)
+	}
+
+	if _, err := git.HostKeyFingerprint("github.com no key here"); err == nil {
+		t.Error("git.HostKeyFingerprint() expected error for a line without a key, got nil")
+	}
+}
+
+func TestPinnedHostKeys(t *testing.T) {
+	other := "github.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBtRMvrDLzKFPw+Y1N5x6Q0DJ3Ed5VZr2cJ3XJpK8T7n"
+	lines := []string{githubHostKey, other, "# comment"}
+
+	pinned := git.PinnedHostKeys(lines, []string{githubHostFingerprint + "="})
+	if len(pinned) != 1 || pinned[0] != githubHostKey {
+		t.Errorf("git.PinnedHostKeys() = %v, want only the GitHub key", pinned)
+	}
+	if pinned := git.PinnedHostKeys(lines, []string{"SHA256:doesnotmatch"}); len(pinned) != 0 {
+		t.Errorf("git.PinnedHostKeys() = %v, want none", pinned)
+	}
+}
+
+func TestEnsureKnownHost(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "config"))
+
+	// Unknown hosts without pinned fingerprints are refused
+	err := git.EnsureKnownHost("git.example.invalid", "", nil)
+	if err == nil || !strings.Contains(err.Error(), "git.ssh_host_fingerprints") {
+		t.Errorf("git.EnsureKnownHost() = %v, want an error asking for fingerprints", err)
+	}
+
+	// A pinned key already in the managed known_hosts needs no network
+	knownHosts, err := git.KnownHostsFile()
+	if err != nil {
+		t.Fatalf("git.KnownHostsFile() failed: %v", err)
+	}
+	os.MkdirAll(filepath.Dir(knownHosts), 0700)
+	os.WriteFile(knownHosts, []byte(githubHostKey+"\n"), 0600)
+	if err := git.EnsureKnownHost("github.com", "22", []string{githubHostFingerprint}); err != nil {
+		t.Errorf("git.EnsureKnownHost() with a pinned known key failed: %v", err)
+	}
+
+	// Keys the user already trusts are accepted without pins
+	os.Remove(knownHosts)
+	os.MkdirAll(filepath.Join(tmpDir, ".ssh"), 0700)
+	os.WriteFile(filepath.Join(tmpDir, ".ssh", "known_hosts"), []byte(githubHostKey+"\n"), 0600)
+	if err := git.EnsureKnownHost("github.com", "", nil); err != nil {
+		t.Errorf("git.EnsureKnownHost() with a key in ~/.ssh/known_hosts failed: %v", err)
+	}
+}
+
+func TestEnsureKnownHostConcurrently(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmpDir, "config"))
+
+	// A slow ssh-keyscan offering the pinned key, in place of the network
+	bin := filepath.Join(tmpDir, "bin")
+	os.Mkdir(bin, 0755)
+	os.WriteFile(filepath.Join(bin, "ssh-keyscan"), []byte("#!/bin/sh\nsleep 0.2\necho '"+githubHostKey+"'\n"), 0755)
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// The managed file still has a key that is no longer pinned
+	stale := filepath.Join(tmpDir, "stale")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", stale).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen failed: %v\n%s", err, out)
+	}
+	pub, _ := os.ReadFile(stale + ".pub")
+	fields := strings.Fields(string(pub))
+	knownHosts, _ := git.KnownHostsFile()
+	os.MkdirAll(filepath.Dir(knownHosts), 0700)
+	os.WriteFile(knownHosts, []byte("github.com "+fields[0]+" "+fields[1]+"\n"), 0600)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 6)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = git.EnsureKnownHost("github.com", "22", []string{githubHostFingerprint})
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("git.EnsureKnownHost() failed: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(knownHosts)
+	var entries []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	if len(entries) != 1 || entries[0] != githubHostKey {
+		t.Errorf("known_hosts = %q, want only the pinned key once", entries)
+	}
+}
+
+func TestKeyNeedsPassphrase(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is not installed")
+	}
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "plain")
+	encrypted := filepath.Join(dir, "encrypted")
+	for path, passphrase := range map[string]string{plain: "", encrypted: "secret"} {
+		if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", passphrase, "-f", path).CombinedOutput(); err != nil {
+			t.Fatalf("ssh-keygen failed: %v: %s", err, out)
+		}
+	}
+
+	if needs, err := git.KeyNeedsPassphrase(plain); err != nil || needs {
+		t.Errorf("git.KeyNeedsPassphrase(plain) = %v, %v, want false", needs, err)
+	}
+	if needs, err := git.KeyNeedsPassphrase(encrypted); err != nil || !needs {
+		t.Errorf("git.KeyNeedsPassphrase(encrypted) = %v, %v, want true", needs, err)
+	}
+	if _, err := git.KeyNeedsPassphrase(filepath.Join(dir, "missing")); err == nil {
+		t.Error("git.KeyNeedsPassphrase() expected error for a missing key, got nil")
+	}
+}
+
+func TestAskpass(t *testing.T) {
+	t.Setenv("AUTOMATELIFE_ASKPASS", "")
+	if _, ok := git.Askpass([]string{"Enter passphrase for key:"}); ok {
+		t.Error("git.Askpass() handled a normal invocation")
+	}
+
+	t.Setenv("AUTOMATELIFE_ASKPASS", "1")
+	if code, ok := git.Askpass([]string{"Are you sure you want to continue connecting (yes/no)?"}); !ok || code != 1 {
+		t.Errorf("git.Askpass() for a host key question = %d, %v, want 1, true", code, ok)
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"automateLife/config"
+	"automateLife/state"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "ConfigFile.json")
+
+	empty, err := state.Load(configFile)
+	if err != nil || empty.CloneDir != "" {
+		t.Fatalf("state.Load() without a state file = %+v, %v", empty, err)
+	}
+
+	saved := &state.State{RepoUrl: "https://github.com/user/repo.git", CloneDir: "/work/repo", Branch: "main"}
+	if err := state.Save(configFile, saved); err != nil {
+		t.Fatalf("state.Save() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(configFile), state.DirName, "state.json")); err != nil {
+		t.Errorf("state file was not written next to the config: %v", err)
+	}
+
+	loaded, err := state.Load(configFile)
+	if err != nil {
+		t.Fatalf("state.Load() failed: %v", err)
+	}
+	if loaded.CloneDir != "/work/repo" || loaded.Branch != "main" || loaded.UpdatedAt.IsZero() {
+		t.Errorf("state.Load() = %+v", loaded)
+	}
+}
+
+func TestCloneDirResolution(t *testing.T) {
+	root := t.TempDir()
+	configFile := filepath.Join(root, "ConfigFile.json")
+	gitCfg := &config.GitConfig{RepoUrl: "git@github.com:user/service.git"}
+
+	dir, err := state.CloneDir(configFile, gitCfg)
+	if err != nil || dir != filepath.Join(root, "service") {
+		t.Errorf("default clone dir = %q, %v", dir, err)
+	}
+
+	gitCfg.CloneDir = "checkouts/svc"
+	dir, _ = state.CloneDir(configFile, gitCfg)
+	if dir != filepath.Join(root, "checkouts", "svc") {
+		t.Errorf("relative clone_dir = %q", dir)
+	}
+
+	gitCfg.CloneDir = "/opt/svc"
+	dir, _ = state.CloneDir(configFile, gitCfg)
+	if dir != "/opt/svc" {
+		t.Errorf("absolute clone_dir = %q", dir)
+	}
+
+	// A recorded checkout wins when clone_dir is not set
+	gitCfg.CloneDir = ""
+	state.Save(configFile, &state.State{RepoUrl: "git@github.com:user/service", CloneDir: "/recorded/service"})
+	dir, _ = state.CloneDir(configFile, gitCfg)
+	if dir != "/recorded/service" {
+		t.Errorf("recorded clone dir = %q", dir)
+	}
+
+	// ...but not when it belongs to another repository
+	gitCfg.RepoUrl = "git@github.com:user/other.git"
+	dir, _ = state.CloneDir(configFile, gitCfg)
+	if dir != filepath.Join(root, "other") {
+		t.Errorf("clone dir for a different repository = %q", dir)
+	}
+
+	if _, err := state.CloneDir(configFile, &config.GitConfig{}); err == nil {
+		t.Error("state.CloneDir() expected error without repo_url, got nil")
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, config.DefaultConfigFileName), []byte("{}"), 0644)
+	nested := filepath.Join(root, "service", "pkg")
+	os.MkdirAll(nested, 0755)
+
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	os.Chdir(nested)
+
+	found := config.Find(config.DefaultConfigFileName)
+	resolvedRoot, _ := filepath.EvalSymlinks(root)
+	resolvedFound, _ := filepath.EvalSymlinks(found)
+	if resolvedFound != filepath.Join(resolvedRoot, config.DefaultConfigFileName) {
+		t.Errorf("config.Find() = %q, want the file in %q", found, root)
+	}
+
+	if got := config.Find("missing.json"); got != "missing.json" {
+		t.Errorf("config.Find() for a missing file = %q, want the name unchanged", got)
+	}
+}
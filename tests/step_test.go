@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"automateLife/builder"
+	"automateLife/config"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStepTimeout(t *testing.T) {
+	dir := t.TempDir()
+	step := builder.Step{
+		Name:    "test",
+		Command: "(sleep 1; touch late) & sleep 30",
+		Shell:   true,
+		Dir:     dir,
+		Timeout: 200 * time.Millisecond,
+		Grace:   200 * time.Millisecond,
+	}
+
+	start := time.Now()
+	err := step.Run(context.Background(), io.Discard, io.Discard)
+	var timeout *builder.TimeoutError
+	if !errors.As(err, &timeout) || timeout.Step != "test" || timeout.Pipeline {
+		t.Fatalf("Run() = %v, want the test step to time out", err)
+	}
+	if !strings.Contains(err.Error(), "test timed out after") || !strings.Contains(err.Error(), "limit 200ms") {
+		t.Errorf("Error() = %q, want the step, elapsed time and limit", err.Error())
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() took %s to stop the step", elapsed)
+	}
+
+	// Stopping the step stops what it started in the background too
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(filepath.Join(dir, "late")); !os.IsNotExist(err) {
+		t.Error("a process started by the step outlived it")
+	}
+}
+
+func TestStepKilledAfterGracePeriod(t *testing.T) {
+	// The ignored SIGTERM is inherited by sleep, only SIGKILL ends them
+	step := builder.Step{Name: "build", Command: "trap '' TERM; sleep 30", Shell: true, Timeout: 100 * time.Millisecond, Grace: 300 * time.Millisecond}
+
+	start := time.Now()
+	err := step.Run(context.Background(), io.Discard, io.Discard)
+	var timeout *builder.TimeoutError
+	if !errors.As(err, &timeout) {
+		t.Fatalf("Run() = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 350*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("Run() returned after %s, want the grace period waited out", elapsed)
+	}
+}
+
+func TestStepPipelineTimeout(t *testing.T) {
+	ctx, cancel := config.TimeoutsConfig{Pipeline: "200ms"}.Context(context.Background())
+	defer cancel()
+
+	step := builder.Step{Name: "install", Command: "sleep 30", Timeout: time.Minute, Grace: 100 * time.Millisecond}
+	err := step.Run(ctx, io.Discard, io.Discard)
+	var timeout *builder.TimeoutError
+	if !errors.As(err, &timeout) || !timeout.Pipeline || timeout.Step != "install" {
+		t.Fatalf("Run() = %v, want the pipeline to run out of time in install", err)
+	}
+
+	// Later steps don't start once the pipeline is out of time
+	if err := (builder.Step{Name: "test", Command: "true"}).Run(ctx, io.Discard, io.Discard); !errors.As(err, &timeout) || !timeout.Pipeline {
+		t.Errorf("Run() after the deadline = %v, want a pipeline timeout", err)
+	}
+
+	if err := (builder.Step{Name: "test", Command: "false", Timeout: time.Minute}).Run(context.Background(), io.Discard, io.Discard); err == nil || errors.As(err, &timeout) {
+		t.Errorf("Run() of a failing command = %v, want a plain failure", err)
+	}
+}
+
+func TestValidateTimeouts(t *testing.T) {
+	cfg := &config.Config{
+		Git:     config.GitConfig{RepoUrl: "https://github.com/user/repo.git", AuthType: "token", Token: "t"},
+		Project: config.ProjectConfig{Type: "backend"},
+		Build:   config.BuildConfig{Language: "go", Timeouts: config.TimeoutsConfig{Test: "15m", Pipeline: "1h", GracePeriod: "5s"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if got := cfg.Build.Timeouts.Step("test"); got != 15*time.Minute {
+		t.Errorf("Step(test) = %s, want 15m", got)
+	}
+	if got := cfg.Build.Timeouts.Step("install"); got != 0 {
+		t.Errorf("Step(install) = %s, want no limit", got)
+	}
+
+	cfg.Build.Timeouts = config.TimeoutsConfig{Install: "ten minutes", Test: "-1s"}
+	errs := cfg.ValidateAll()
+	if len(errs) != 2 || errs[0].Field != "build.timeouts.install" || errs[1].Field != "build.timeouts.test" {
+		t.Errorf("ValidateAll() = %v, want build.timeouts.install and build.timeouts.test", errs)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package tests
+
+import (
+	"automateLife/builder"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStepKilledOnSecondSignal(t *testing.T) {
+	// SIGINT and SIGTERM are ignored, only SIGKILL ends the step
+	step := builder.Step{Name: "test", Command: "trap '' INT TERM; sleep 30", Shell: true, Grace: 30 * time.Second}
+	go func() {
+		for range 2 {
+			time.Sleep(300 * time.Millisecond)
+			syscall.Kill(os.Getpid(), syscall.SIGINT)
+		}
+	}()
+
+	start := time.Now()
+	err := step.Run(context.Background(), io.Discard, io.Discard)
+	if !errors.Is(err, builder.ErrInterrupted) {
+		t.Fatalf("Run() = %v, want ErrInterrupted", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() returned after %s, want the second signal to kill the step", elapsed)
+	}
+}
@@ -0,0 +1,304 @@
+package tests
+
+import (
+	"automateLife/git"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloneOrUpdateWithRealRemote(t *testing.T) {
+	url, work := newBareRemote(t)
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+	opts := git.SyncOptions{URL: url, Dir: dest, Branch: "main"}
+
+	// First run clones
+	result, err := git.CloneOrUpdate(client, opts)
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() first run failed: %v", err)
+	}
+	if !result.Cloned || result.NewCommit != runGitIn(t, work, "rev-parse", "main") {
+		t.Errorf("first run result = %+v", result)
+	}
+
+	// Second run with nothing new is a no-op
+	result, err = git.CloneOrUpdate(client, opts)
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() second run failed: %v", err)
+	}
+	if result.Cloned || result.Updated() {
+		t.Errorf("second run result = %+v, want unchanged", result)
+	}
+
+	// New upstream commit is fast-forwarded
+	newSHA := commitFile(t, work, "NEWS.md", "news", "Add news")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+	result, err = git.CloneOrUpdate(client, opts)
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() update failed: %v", err)
+	}
+	if !result.Updated() || result.NewCommit != newSHA {
+		t.Errorf("update result = %+v, want new commit %s", result, newSHA)
+	}
+
+	// Switching branch checks out the remote branch
+	result, err = git.CloneOrUpdate(client, git.SyncOptions{URL: url + "/", Dir: dest, Branch: "feature"})
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() branch switch failed: %v", err)
+	}
+	if result.NewCommit != runGitIn(t, work, "rev-parse", "feature") {
+		t.Errorf("branch switch result = %+v", result)
+	}
+}
+
+func TestCloneOrUpdateDivergedHistory(t *testing.T) {
+	url, work := newBareRemote(t)
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+	opts := git.SyncOptions{URL: url, Dir: dest, Branch: "main"}
+
+	if _, err := git.CloneOrUpdate(client, opts); err != nil {
+		t.Fatalf("CloneOrUpdate() clone failed: %v", err)
+	}
+	runGitIn(t, dest, "config", "user.email", "test@example.com")
+	runGitIn(t, dest, "config", "user.name", "Test User")
+	commitFile(t, dest, "local.txt", "local", "Local commit")
+
+	upstream := commitFile(t, work, "remote.txt", "remote", "Remote commit")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+
+	if _, err := git.CloneOrUpdate(client, opts); !errors.Is(err, git.ErrDiverged) {
+		t.Fatalf("CloneOrUpdate() on diverged history = %v, want ErrDiverged", err)
+	}
+
+	opts.Force = true
+	result, err := git.CloneOrUpdate(client, opts)
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() with force failed: %v", err)
+	}
+	if result.NewCommit != upstream {
+		t.Errorf("forced update landed on %s, want %s", result.NewCommit, upstream)
+	}
+}
+
+func TestCloneOrUpdateReportsFailedFastForward(t *testing.T) {
+	url, work := newBareRemote(t)
+	client := newExecClient(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+	opts := git.SyncOptions{URL: url, Dir: dest, Branch: "main"}
+
+	if _, err := git.CloneOrUpdate(client, opts); err != nil {
+		t.Fatalf("CloneOrUpdate() clone failed: %v", err)
+	}
+	commitFile(t, work, "remote.txt", "remote", "Remote commit")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+	// An untracked file in the way stops the fast-forward without any divergence
+	os.WriteFile(filepath.Join(dest, "remote.txt"), []byte("local"), 0644)
+
+	_, err := git.CloneOrUpdate(client, opts)
+	if err == nil || errors.Is(err, git.ErrDiverged) {
+		t.Fatalf("CloneOrUpdate() with an untracked file in the way = %v, want the merge error", err)
+	}
+	if !strings.Contains(err.Error(), "untracked") {
+		t.Errorf("CloneOrUpdate() error = %v, want git's reason", err)
+	}
+}
+
+func TestCloneOrUpdateRefusesUnsafeDirectories(t *testing.T) {
+	url, _ := newBareRemote(t)
+	otherURL, _ := newBareRemote(t)
+	client := newExecClient(t)
+
+	t.Run("Dirty working tree", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "clone")
+		opts := git.SyncOptions{URL: url, Dir: dest, Branch: "main"}
+		if _, err := git.CloneOrUpdate(client, opts); err != nil {
+			t.Fatalf("clone failed: %v", err)
+		}
+		os.WriteFile(filepath.Join(dest, "README.md"), []byte("changed"), 0644)
+
+		opts.Force = true
+		if _, err := git.CloneOrUpdate(client, opts); !errors.Is(err, git.ErrDirtyWorkTree) {
+			t.Errorf("CloneOrUpdate() = %v, want ErrDirtyWorkTree", err)
+		}
+	})
+
+	t.Run("Different remote", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "clone")
+		if _, err := git.CloneOrUpdate(client, git.SyncOptions{URL: otherURL, Dir: dest}); err != nil {
+			t.Fatalf("clone failed: %v", err)
+		}
+		if _, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest}); !errors.Is(err, git.ErrRemoteMismatch) {
+			t.Errorf("CloneOrUpdate() = %v, want ErrRemoteMismatch", err)
+		}
+	})
+
+	t.Run("Non-empty directory that is not a clone", func(t *testing.T) {
+		dest := t.TempDir()
+		os.WriteFile(filepath.Join(dest, "file.txt"), []byte("x"), 0644)
+		if _, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest}); err == nil {
+			t.Error("CloneOrUpdate() expected error for a non-empty directory, got nil")
+		}
+	})
+}
+
+func TestCloneOrUpdateWithFake(t *testing.T) {
+	const url = "https://github.com/user/repo.git"
+	fake := git.NewFakeClient()
+	fake.AddCommit(url, "main", git.Commit{SHA: "1111111111"})
+	opts := git.SyncOptions{URL: url, Dir: "repo", Branch: "main"}
+
+	if result, err := git.CloneOrUpdate(fake, opts); err != nil || !result.Cloned {
+		t.Fatalf("CloneOrUpdate() clone = %+v, %v", result, err)
+	}
+
+	fake.AddCommit(url, "main", git.Commit{SHA: "2222222222"})
+	result, err := git.CloneOrUpdate(fake, opts)
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() update failed: %v", err)
+	}
+	if result.OldCommit != "1111111111" || result.NewCommit != "2222222222" {
+		t.Errorf("update result = %+v", result)
+	}
+
+	fake.AddCommit(url, "main", git.Commit{SHA: "3333333333"})
+	fake.Errors["FastForward"] = errors.New("not possible to fast-forward")
+	if _, err := git.CloneOrUpdate(fake, opts); !errors.Is(err, git.ErrDiverged) {
+		t.Errorf("CloneOrUpdate() = %v, want ErrDiverged", err)
+	}
+
+	opts.Force = true
+	if result, err := git.CloneOrUpdate(fake, opts); err != nil || result.NewCommit != "3333333333" {
+		t.Errorf("CloneOrUpdate() with force = %+v, %v", result, err)
+	}
+}
+
+func TestSameRemote(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"https://github.com/user/repo.git", "https://github.com/user/repo", true},
+		{"https://github.com/User/Repo/", "https://github.com/user/repo.git", true},
+		{"https://github.com/user/repo.git", "https://github.com/user/other.git", false},
+	}
+	for _, tt := range tests {
+		if got := git.SameRemote(tt.a, tt.b); got != tt.expected {
+			t.Errorf("git.SameRemote(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestCloneOrUpdatePinnedRef(t *testing.T) {
+	url, work := newBareRemote(t)
+	tagged := runGitIn(t, work, "rev-parse", "main")
+	runGitIn(t, work, "tag", "-a", "v1.0.0", "-m", "Release 1.0.0")
+	runGitIn(t, work, "push", "-q", "origin", "v1.0.0")
+	latest := commitFile(t, work, "CHANGELOG.md", "changes", "Add changelog")
+	runGitIn(t, work, "push", "-q", "origin", "main")
+	client := newExecClient(t)
+
+	t.Run("Tag", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "clone")
+		result, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, Ref: "v1.0.0"})
+		if err != nil {
+			t.Fatalf("CloneOrUpdate() failed: %v", err)
+		}
+		if result.NewCommit != tagged || result.Ref != "v1.0.0" || result.Branch != "" {
+			t.Errorf("result = %+v, want tag commit %s", result, tagged)
+		}
+		if result.Commit.Subject != "Initial commit" || result.Commit.Author != "Test User" {
+			t.Errorf("result.Commit = %+v", result.Commit)
+		}
+	})
+
+	t.Run("Short SHA then full SHA", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "clone")
+		result, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, Ref: tagged[:8]})
+		if err != nil {
+			t.Fatalf("CloneOrUpdate() failed: %v", err)
+		}
+		if !result.Cloned || result.NewCommit != tagged {
+			t.Errorf("result = %+v, want %s", result, tagged)
+		}
+
+		// Moving an existing clone to another commit
+		result, err = git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, Ref: latest})
+		if err != nil {
+			t.Fatalf("CloneOrUpdate() failed: %v", err)
+		}
+		if result.OldCommit != tagged || result.NewCommit != latest {
+			t.Errorf("result = %+v, want %s -> %s", result, tagged, latest)
+		}
+		if branch := runGitIn(t, dest, "rev-parse", "--abbrev-ref", "HEAD"); branch != "HEAD" {
+			t.Errorf("HEAD is on %s, want detached", branch)
+		}
+	})
+
+	t.Run("Branch", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "clone")
+		result, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, Branch: "main", Ref: "feature"})
+		if err != nil {
+			t.Fatalf("CloneOrUpdate() failed: %v", err)
+		}
+		if result.Branch != "feature" || result.Ref != "" {
+			t.Errorf("result = %+v, want branch feature", result)
+		}
+	})
+
+	t.Run("Unknown ref", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "clone")
+		if _, err := git.CloneOrUpdate(client, git.SyncOptions{URL: url, Dir: dest, Ref: "no-such-ref"}); err == nil {
+			t.Error("CloneOrUpdate() expected error for an unknown ref, got nil")
+		}
+	})
+}
+
+func TestCloneOrUpdatePinnedRefWithFake(t *testing.T) {
+	const url = "https://github.com/user/repo.git"
+	fake := git.NewFakeClient()
+	fake.AddCommit(url, "main", git.Commit{SHA: "1111111111", Subject: "First"})
+	fake.AddTag(url, "v1", "1111111111")
+	fake.AddCommit(url, "main", git.Commit{SHA: "2222222222", Subject: "Second"})
+
+	result, err := git.CloneOrUpdate(fake, git.SyncOptions{URL: url, Dir: "repo", Ref: "v1"})
+	if err != nil {
+		t.Fatalf("CloneOrUpdate() failed: %v", err)
+	}
+	if result.NewCommit != "1111111111" || result.Commit.Subject != "First" {
+		t.Errorf("result = %+v", result)
+	}
+	if fake.Clones["repo"].Branch != "" {
+		t.Errorf("tag checkout is on branch %s, want detached", fake.Clones["repo"].Branch)
+	}
+}
+
+func TestCommitEnv(t *testing.T) {
+	commit := git.Commit{
+		SHA:     "0123456789abcdef0123456789abcdef01234567",
+		Author:  "Test User",
+		Email:   "test@example.com",
+		Date:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Subject: "Fix things",
+	}
+	env := commit.Env("v1.2.3")
+
+	expected := map[string]string{
+		"AUTOMATELIFE_COMMIT_SHA":       commit.SHA,
+		"AUTOMATELIFE_COMMIT_SHORT_SHA": "0123456",
+		"AUTOMATELIFE_COMMIT_REF":       "v1.2.3",
+		"AUTOMATELIFE_COMMIT_AUTHOR":    "Test User",
+		"AUTOMATELIFE_COMMIT_EMAIL":     "test@example.com",
+		"AUTOMATELIFE_COMMIT_DATE":      "2024-05-01T12:00:00Z",
+		"AUTOMATELIFE_COMMIT_SUBJECT":   "Fix things",
+	}
+	for key, want := range expected {
+		if env[key] != want {
+			t.Errorf("%s = %q, want %q", key, env[key], want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"automateLife/config"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -201,6 +202,28 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateBasicAuthNamesOnlyTheMissingField(t *testing.T) {
+	cfg := config.Config{
+		Git:     config.GitConfig{RepoUrl: "https://github.com/test/repo", AuthType: "basic", UserName: "user"},
+		Project: config.ProjectConfig{Type: "backend"},
+	}
+	errs := cfg.ValidateAll()
+	if len(errs) != 1 || errs[0].Field != "git.password" || !strings.HasPrefix(errs[0].Message, "git.password is missing, ") {
+		t.Errorf("ValidateAll() without a password = %v, want only git.password reported missing", errs)
+	}
+
+	cfg.Git.UserName, cfg.Git.Password = "", "pass"
+	if err := cfg.Validate(); err == nil || !strings.HasPrefix(err.Error(), "git.username is missing, ") {
+		t.Errorf("Validate() without a username = %v, want git.username reported missing", err)
+	}
+
+	cfg.Git.Password = ""
+	errs = cfg.ValidateAll()
+	if len(errs) != 2 || !strings.HasPrefix(errs[0].Message, "git.username is missing, ") || !strings.HasPrefix(errs[1].Message, "git.password is missing, ") {
+		t.Errorf("ValidateAll() without either = %v, want each field reported on its own", errs)
+	}
+}
+
 func TestValidateSSHWithExpandedPath(t *testing.T) {
 	// Save original HOME
 	originalHome := os.Getenv("HOME")
@@ -245,3 +268,128 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestValidateAll(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         config.Config
+		expectedFields []string
+	}{
+		{
+			name: "Valid config reports nothing",
+			config: config.Config{
+				Git:     config.GitConfig{RepoUrl: "https://github.com/test/repo", AuthType: "token", Token: "t"},
+				Project: config.ProjectConfig{Type: "backend"},
+			},
+			expectedFields: nil,
+		},
+		{
+			name:           "Every problem is reported",
+			config:         config.Config{Git: config.GitConfig{AuthType: "basic"}},
+			expectedFields: []string{"git.repo_url", "project.type", "git.username", "git.password"},
+		},
+		{
+			name: "Legacy password auth type is rejected",
+			config: config.Config{
+				Git:     config.GitConfig{RepoUrl: "https://github.com/test/repo", AuthType: "password"},
+				Project: config.ProjectConfig{Type: "backend"},
+			},
+			expectedFields: []string{"git.auth_type"},
+		},
+		{
+			name: "Missing SSH key file",
+			config: config.Config{
+				Git:     config.GitConfig{RepoUrl: "git@github.com:test/repo.git", AuthType: "ssh", SSHKeyPath: "/nonexistent/key"},
+				Project: config.ProjectConfig{Type: "backend"},
+			},
+			expectedFields: []string{"git.ssh_key_path"},
+		},
+		{
+			name: "Unknown provider",
+			config: config.Config{
+				Git:     config.GitConfig{Provider: "sourceforge", RepoUrl: "https://github.com/test/repo", AuthType: "token", Token: "t"},
+				Project: config.ProjectConfig{Type: "backend"},
+			},
+			expectedFields: []string{"git.provider"},
+		},
+		{
+			name: "Repo URL of another provider",
+			config: config.Config{
+				Git:     config.GitConfig{Provider: "gitlab", RepoUrl: "https://github.com/test/repo", AuthType: "token", Token: "t"},
+				Project: config.ProjectConfig{Type: "backend"},
+			},
+			expectedFields: []string{"git.repo_url"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.config.ValidateAll()
+			if len(errs) != len(tt.expectedFields) {
+				t.Fatalf("ValidateAll() returned %d errors (%v), want %d", len(errs), errs, len(tt.expectedFields))
+			}
+			for i, field := range tt.expectedFields {
+				if errs[i].Field != field {
+					t.Errorf("ValidateAll()[%d].Field = %q, want %q", i, errs[i].Field, field)
+				}
+			}
+
+			// Validate keeps reporting the first problem only
+			err := tt.config.Validate()
+			if len(errs) == 0 && err != nil {
+				t.Errorf("Validate() unexpected error: %v", err)
+			}
+			if len(errs) > 0 && (err == nil || err.Error() != errs[0].Message) {
+				t.Errorf("Validate() = %v, want %q", err, errs[0].Message)
+			}
+		})
+	}
+}
+
+func TestValidateCloneTuning(t *testing.T) {
+	base := func() config.Config {
+		return config.Config{
+			Git:     config.GitConfig{RepoUrl: "https://github.com/test/repo", AuthType: "token", Token: "t"},
+			Project: config.ProjectConfig{Type: "backend"},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		modify      func(c *config.Config)
+		expectField string
+	}{
+		{name: "Valid tuning", modify: func(c *config.Config) {
+			c.Git.Depth = 1
+			c.Git.SingleBranch = true
+			c.Git.SparsePaths = []string{"services/api", "libs"}
+			c.Git.RecurseSubmodules = true
+			c.Git.LFS = "skip"
+		}},
+		{name: "Negative depth", modify: func(c *config.Config) { c.Git.Depth = -1 }, expectField: "git.depth"},
+		{name: "Absolute sparse path", modify: func(c *config.Config) { c.Git.SparsePaths = []string{"/etc"} }, expectField: "git.sparse_paths"},
+		{name: "Sparse path escaping the repo", modify: func(c *config.Config) { c.Git.SparsePaths = []string{"../other"} }, expectField: "git.sparse_paths"},
+		{name: "Empty sparse path", modify: func(c *config.Config) { c.Git.SparsePaths = []string{" "} }, expectField: "git.sparse_paths"},
+		{name: "Unknown lfs mode", modify: func(c *config.Config) { c.Git.LFS = "fetch" }, expectField: "git.lfs"},
+		{name: "Pinned ref", modify: func(c *config.Config) { c.Git.Ref = "v1.2.3" }},
+		{name: "Ref that looks like an option", modify: func(c *config.Config) { c.Git.Ref = "--upload-pack=evil" }, expectField: "git.ref"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base()
+			tt.modify(&cfg)
+			errs := cfg.ValidateAll()
+
+			if tt.expectField == "" {
+				if len(errs) != 0 {
+					t.Errorf("ValidateAll() unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Field != tt.expectField {
+				t.Errorf("ValidateAll() = %v, want one error for %s", errs, tt.expectField)
+			}
+		})
+	}
+}
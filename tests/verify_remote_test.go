@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"automateLife/git"
+	"errors"
+	"testing"
+)
+
+func TestVerifyRemoteWithRealRemote(t *testing.T) {
+	url, work := newBareRemote(t)
+	runGitIn(t, work, "tag", "-a", "v1.0.0", "-m", "Release", "feature")
+	runGitIn(t, work, "push", "-q", "origin", "v1.0.0")
+	client := newExecClient(t)
+
+	tests := []struct {
+		name   string
+		branch string
+		ref    string
+		kind   string
+		sha    string
+	}{
+		{name: "Default branch", kind: "branch", sha: "main"},
+		{name: "Configured branch", branch: "feature", kind: "branch", sha: "feature"},
+		{name: "Ref naming a branch", branch: "main", ref: "feature", kind: "branch", sha: "feature"},
+		{name: "Annotated tag", ref: "v1.0.0", kind: "tag", sha: "feature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := git.VerifyRemote(client, url, tt.branch, tt.ref)
+			if err != nil {
+				t.Fatalf("VerifyRemote() failed: %v", err)
+			}
+			if check.DefaultBranch != "main" {
+				t.Errorf("DefaultBranch = %q, want main", check.DefaultBranch)
+			}
+			if check.Kind != tt.kind {
+				t.Errorf("Kind = %q, want %q", check.Kind, tt.kind)
+			}
+			if expected := runGitIn(t, work, "rev-parse", tt.sha); check.SHA != expected {
+				t.Errorf("SHA = %q, want %q", check.SHA, expected)
+			}
+		})
+	}
+
+	if _, err := git.VerifyRemote(client, url, "missing", ""); !errors.Is(err, git.ErrBranchNotFound) {
+		t.Errorf("VerifyRemote() of a missing branch = %v, want ErrBranchNotFound", err)
+	}
+	if _, err := git.VerifyRemote(client, url+"-missing", "main", ""); !errors.Is(err, git.ErrRepoNotFound) {
+		t.Errorf("VerifyRemote() of a missing repository = %v, want ErrRepoNotFound", err)
+	}
+}
+
+func TestVerifyRemoteWithFake(t *testing.T) {
+	const url = "https://github.com/user/repo.git"
+	fake := git.NewFakeClient()
+	fake.AddCommit(url, "main", git.Commit{SHA: "1111111111"})
+
+	// Commits that are no branch or tag tip can only be checked when cloning
+	check, err := git.VerifyRemote(fake, url, "", "0123456789abcdef0123456789abcdef01234567")
+	if err != nil {
+		t.Fatalf("VerifyRemote() failed: %v", err)
+	}
+	if check.Kind != "commit" || check.SHA != "" {
+		t.Errorf("VerifyRemote() = %+v, want an unadvertised commit", check)
+	}
+
+	if _, err := git.VerifyRemote(fake, url, "", "not-a-ref"); !errors.Is(err, git.ErrBranchNotFound) {
+		t.Errorf("VerifyRemote() of an unknown ref = %v, want ErrBranchNotFound", err)
+	}
+
+	fake.Errors["LsRemote"] = &git.CommandError{Args: []string{"ls-remote"}, Stderr: "fatal: Authentication failed", Err: errors.New("exit status 128")}
+	if _, err := git.VerifyRemote(fake, url, "main", ""); !errors.Is(err, git.ErrAuthFailed) {
+		t.Errorf("VerifyRemote() with bad credentials = %v, want ErrAuthFailed", err)
+	}
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"automateLife/git"
+	"automateLife/state"
+	"automateLife/watch"
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPollerDetectsNewCommits(t *testing.T) {
+	const url = "https://github.com/user/repo.git"
+	configFile := filepath.Join(t.TempDir(), "ConfigFile.json")
+	fake := git.NewFakeClient()
+	fake.AddCommit(url, "main", git.Commit{SHA: "1111111111", Subject: "First"})
+	poller := &watch.Poller{Client: fake, ConfigFile: configFile, RepoUrl: url, URL: url}
+
+	check, changed, err := poller.Poll()
+	if err != nil || !changed || check.Ref != "main" || check.SHA != "1111111111" {
+		t.Fatalf("first Poll() = %+v, %v, %v, want the default branch tip as new", check, changed, err)
+	}
+	if err := poller.Record(check.Ref, check.SHA, true); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+	if _, changed, _ := poller.Poll(); changed {
+		t.Error("Poll() after Record() reports the same commit as new")
+	}
+
+	// A restarted watch picks up the recorded commit
+	restarted := &watch.Poller{Client: fake, ConfigFile: configFile, RepoUrl: url, URL: url, Branch: "main"}
+	if _, changed, _ := restarted.Poll(); changed {
+		t.Error("Poll() after a restart re-runs the processed commit")
+	}
+	if last, err := state.LoadWatch(configFile); err != nil || last.SHA != "1111111111" || !last.Passed || last.ProcessedAt.IsZero() {
+		t.Errorf("state.LoadWatch() = %+v, %v", last, err)
+	}
+
+	fake.AddCommit(url, "main", git.Commit{SHA: "2222222222", Subject: "Second"})
+	if check, changed, _ := restarted.Poll(); !changed || check.SHA != "2222222222" {
+		t.Errorf("Poll() after a push = %+v, %v, want the new commit", check, changed)
+	}
+
+	// State recorded for another repository doesn't count
+	other := &watch.Poller{Client: fake, ConfigFile: configFile, RepoUrl: "https://github.com/user/other.git", URL: url}
+	if _, changed, _ := other.Poll(); !changed {
+		t.Error("Poll() used the state recorded for a different repository")
+	}
+}
+
+func TestPollerOnlyWatchesBranches(t *testing.T) {
+	const url = "https://github.com/user/repo.git"
+	fake := git.NewFakeClient()
+	fake.AddCommit(url, "main", git.Commit{SHA: "1111111111", Subject: "First"})
+	fake.AddTag(url, "v1", "1111111111")
+
+	poller := &watch.Poller{Client: fake, ConfigFile: filepath.Join(t.TempDir(), "ConfigFile.json"), RepoUrl: url, URL: url, Ref: "v1"}
+	if _, _, err := poller.Poll(); !errors.Is(err, watch.ErrNotBranch) {
+		t.Errorf("Poll() of a tag = %v, want ErrNotBranch", err)
+	}
+
+	poller.Ref = "missing"
+	if _, _, err := poller.Poll(); !errors.Is(err, git.ErrBranchNotFound) {
+		t.Errorf("Poll() of a missing branch = %v, want ErrBranchNotFound", err)
+	}
+}
+
+// buildBinary builds automateLife for tests that run it end to end
+func buildBinary(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	binary := filepath.Join(t.TempDir(), "automateLife")
+	if out, err := exec.Command("go", "build", "-o", binary, "..").CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, out)
+	}
+	return binary
+}
+
+func TestWatchBuildsEveryUpdate(t *testing.T) {
+	url, work := newBareRemote(t)
+	runGitIn(t, work, "checkout", "-q", "main")
+	binary := buildBinary(t)
+
+	dir := t.TempDir()
+	config := `{"project": {"name": "app", "type": "backend"},
+  "git": {"repo_url": "` + url + `", "auth_type": "ssh", "ssh_agent": true, "branch": "main", "clone_dir": "clone"},
+  "build": {"language": "go", "install_command": "true", "build_command": "sh -c 'git rev-parse HEAD >> ../builds'", "test_command": "true"}}`
+	os.WriteFile(filepath.Join(dir, "ConfigFile.json"), []byte(config), 0600)
+
+	cmd := exec.Command(binary, "watch", "--remote", "--interval", "1s")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "SSH_AUTH_SOCK="+filepath.Join(dir, "no-agent"))
+	stdout, _ := cmd.StdoutPipe()
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting watch failed: %v", err)
+	}
+	timer := time.AfterFunc(60*time.Second, func() { cmd.Process.Kill() })
+	defer timer.Stop()
+
+	// The first poll clones, then a push is picked up as an update
+	var output strings.Builder
+	pipelines := 0
+	updated := ""
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		output.WriteString(scanner.Text() + "\n")
+		if !strings.Contains(scanner.Text(), "Pipeline ") {
+			continue
+		}
+		pipelines++
+		if pipelines == 1 {
+			updated = commitFile(t, work, "next.txt", "next", "Next change")
+			runGitIn(t, work, "push", "-q", "origin", "main")
+			continue
+		}
+		cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.Wait()
+
+	data, _ := os.ReadFile(filepath.Join(dir, "builds"))
+	builds := strings.Fields(string(data))
+	if len(builds) != 2 || builds[1] != updated {
+		t.Errorf("builds = %q, want the clone and then %s built\n%s", builds, updated, output.String())
+	}
+}
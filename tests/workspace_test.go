@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"automateLife/config"
+	"automateLife/git"
+	"automateLife/runlog"
+	"automateLife/workspace"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceInheritance(t *testing.T) {
+	cfg := &config.Config{
+		Git:   config.GitConfig{Provider: "github", AuthType: "token", Token: "t", Branch: "main", Depth: 1},
+		Build: config.BuildConfig{Language: "go", TestCommand: "go test ./..."},
+		Workspace: []config.RepositoryConfig{
+			{RepoUrl: "https://github.com/org/api.git", Branch: "develop"},
+			{Name: "web", RepoUrl: "https://github.com/org/frontend.git", Build: config.BuildConfig{Language: "node", TestCommand: "npm test"}},
+		},
+	}
+
+	api := &cfg.Workspace[0]
+	if name := api.RepoName(); name != "api" {
+		t.Errorf("RepoName() = %q, want api", name)
+	}
+	apiGit := cfg.RepoGit(api)
+	if apiGit.RepoUrl != api.RepoUrl || apiGit.Branch != "develop" || apiGit.Token != "t" || apiGit.Depth != 1 {
+		t.Errorf("RepoGit() = %+v, want the repository's URL and branch with the shared auth and tuning", apiGit)
+	}
+	if build := cfg.RepoBuild(api); build.TestCommand != "go test ./..." {
+		t.Errorf("RepoBuild().TestCommand = %q, want the shared command", build.TestCommand)
+	}
+
+	web := &cfg.Workspace[1]
+	if gitCfg := cfg.RepoGit(web); gitCfg.Branch != "" {
+		t.Errorf("RepoGit().Branch = %q, want the remote default", gitCfg.Branch)
+	}
+	if build := cfg.RepoBuild(web); build.Language != "node" || build.TestCommand != "npm test" {
+		t.Errorf("RepoBuild() = %+v, want the repository's own settings", build)
+	}
+
+	cfg.Project.Type = "backend"
+	if errs := cfg.ValidateAll(); len(errs) != 0 {
+		t.Errorf("ValidateAll() = %v, a workspace needs no git.repo_url", errs)
+	}
+
+	cfg.Workspace = append(cfg.Workspace, config.RepositoryConfig{RepoUrl: "https://github.com/other/api.git"}, config.RepositoryConfig{})
+	errs := cfg.ValidateAll()
+	if len(errs) != 2 || errs[0].Field != "workspace[2].name" || errs[1].Field != "workspace[3].repo_url" {
+		t.Errorf("ValidateAll() = %v, want a duplicate name and a missing repo_url", errs)
+	}
+}
+
+func TestWorkspaceRepos(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "ConfigFile.json")
+	cfg := &config.Config{
+		Git: config.GitConfig{AuthType: "ssh"},
+		Workspace: []config.RepositoryConfig{
+			{RepoUrl: "git@github.com:org/api.git"},
+			{RepoUrl: "git@github.com:org/web.git", CloneDir: "services/web"},
+		},
+	}
+
+	repos, err := workspace.Repos(configFile, cfg)
+	if err != nil {
+		t.Fatalf("Repos() failed: %v", err)
+	}
+	root := filepath.Dir(configFile)
+	if repos[0].Dir != filepath.Join(root, "api") || repos[1].Dir != filepath.Join(root, "services", "web") {
+		t.Errorf("Repos() dirs = %q, %q", repos[0].Dir, repos[1].Dir)
+	}
+
+	cfg.Workspace[1].CloneDir = "api"
+	if _, err := workspace.Repos(configFile, cfg); err == nil {
+		t.Error("Repos() expected an error for two repositories sharing a clone directory, got nil")
+	}
+}
+
+func TestWorkspaceSyncAndRun(t *testing.T) {
+	apiURL, apiWork := newBareRemote(t)
+	webURL, _ := newBareRemote(t)
+	root := t.TempDir()
+	cfg := &config.Config{
+		Git: config.GitConfig{AuthType: "ssh"},
+		Workspace: []config.RepositoryConfig{
+			{Name: "api", RepoUrl: apiURL, CloneDir: "api", Branch: "feature", Build: config.BuildConfig{TestCommand: "git rev-parse --abbrev-ref HEAD"}},
+			{Name: "web", RepoUrl: webURL, CloneDir: "web", Build: config.BuildConfig{TestCommand: "git rev-parse --verify no-such-rev"}},
+			{Name: "missing", RepoUrl: webURL + "-missing", CloneDir: "missing"},
+		},
+	}
+	repos, err := workspace.Repos(filepath.Join(root, "ConfigFile.json"), cfg)
+	if err != nil {
+		t.Fatalf("Repos() failed: %v", err)
+	}
+	newClient := func(*config.GitConfig) (git.Client, error) { return newExecClient(t), nil }
+
+	statuses := workspace.Sync(repos, 2, false, nil, newClient)
+	if statuses[0].Err != nil || !statuses[0].Result.Cloned || statuses[0].Result.Branch != "feature" {
+		t.Errorf("Sync() api = %+v", statuses[0])
+	}
+	if statuses[1].Err != nil || statuses[1].Result.Branch != "main" {
+		t.Errorf("Sync() web = %+v", statuses[1])
+	}
+	if statuses[2].Err == nil {
+		t.Error("Sync() expected an error for the missing repository, got nil")
+	}
+
+	commitFile(t, apiWork, "api.txt", "v2", "Update api")
+	runGitIn(t, apiWork, "push", "-q", "origin", "main")
+	statuses = workspace.Sync(repos[:2], 2, false, nil, newClient)
+	for _, status := range statuses {
+		if status.Err != nil || status.Result.Cloned || status.Result.Updated() {
+			t.Errorf("second Sync() %s = %+v, want up to date", status.Repo.Name, status)
+		}
+	}
+
+	var out bytes.Buffer
+	runs, err := workspace.Run(repos[:2], "test", 2, &out, nil)
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if runs[0].Err != nil || runs[1].Err == nil {
+		t.Errorf("Run() = %+v, want api to pass and web to fail", runs)
+	}
+	if !strings.Contains(out.String(), "api | feature\n") {
+		t.Errorf("Run() output = %q, want lines prefixed with the repository name", out.String())
+	}
+
+	// Every repository's stages see its own commit
+	described := []workspace.Repo{repos[0], repos[1]}
+	for i := range described {
+		described[i].Build.TestCommand = "echo $AUTOMATELIFE_COMMIT_REF $AUTOMATELIFE_COMMIT_SHA"
+	}
+	described[1].Build.Shell = true
+	out.Reset()
+	workspace.Run(described, "test", 2, &out, nil)
+	for _, repo := range described {
+		line := fmt.Sprintf("%s | %s %s\n", repo.Name, runGitIn(t, repo.Dir, "rev-parse", "--abbrev-ref", "HEAD"), runGitIn(t, repo.Dir, "rev-parse", "HEAD"))
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Run() output = %q, want %q", out.String(), line)
+		}
+	}
+
+	if runs, _ := workspace.Run(repos[:2], "build", 2, &out, nil); runs[0].Command != "" || runs[0].Err != nil {
+		t.Errorf("Run(build) = %+v, want repositories without a build command skipped", runs[0])
+	}
+	if _, err := workspace.Run(repos, "deploy", 2, &out, nil); err == nil {
+		t.Error("Run() expected an error for an unknown stage, got nil")
+	}
+}
+
+func TestWorkspacePipeline(t *testing.T) {
+	dir := t.TempDir()
+	repos := []workspace.Repo{
+		{Name: "ok", Dir: dir, Build: config.BuildConfig{InstallCommand: "true", TestCommand: "echo tested"}},
+		{Name: "broken", Dir: dir, Build: config.BuildConfig{InstallCommand: "false", TestCommand: "echo tested"}},
+	}
+
+	run, err := runlog.Start(t.TempDir(), "test", runlog.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	statuses, err := workspace.Pipeline(repos, []string{"install", "test"}, 2, &out, run)
+	if err != nil {
+		t.Fatalf("Pipeline() failed: %v", err)
+	}
+	if statuses[0].Err != nil || statuses[0].Stage != "test" {
+		t.Errorf("Pipeline() ok = %+v, want both stages passed", statuses[0])
+	}
+	if statuses[1].Err == nil || statuses[1].Stage != "install" {
+		t.Errorf("Pipeline() broken = %+v, want a failed install", statuses[1])
+	}
+	if strings.Contains(out.String(), "broken | tested") {
+		t.Error("Pipeline() ran the tests after the install failed")
+	}
+
+	// Every stage that ran is logged per repository, without the prefix
+	step, err := run.FindStep("ok/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(run.LogPath(step)); string(data) != "tested\n" || step.Result != "passed" {
+		t.Errorf("ok/test log = %q, result %q", data, step.Result)
+	}
+	if step, err := run.FindStep("broken/install"); err != nil || step.Result != "failed" {
+		t.Errorf("broken/install = %+v, %v, want a failed step", step, err)
+	}
+	if _, err := run.FindStep("broken/test"); err == nil {
+		t.Error("FindStep() found a log of the test that never ran")
+	}
+}
@@ -1,6 +1,9 @@
 package ui
 
-import "fmt"
+import (
+	"automateLife/redact"
+	"fmt"
+)
 
 const (
 	Bold    = "\033[1m"
@@ -28,13 +31,30 @@ func PrintWelcome() {
 	Printf("Welcome to %s%sAutomate Life%s, your gateway to automation\n\n", Bold, Green, Reset)
 	Printf("Run %s%sautomateLife%s then one of the following commands to start:\n\n", Bold, Blue, Reset)
 	println("init: creates a config file in your current directory")
+	println("      --detect [dir]: fills the config from an existing checkout")
+	println("      --from-ci <file>: imports build settings from a CI definition")
 	println("start: starts the automation process using the created config file")
+	println("       --force: hard-resets an existing clone that cannot be fast-forwarded")
+	println("       --pr N [--merge]: checks out a pull request, optionally merged into the target branch")
 	println("verify: verifies that the current directory has the necessary parameters for automation")
+	println("        --remote: also checks the repository, branch and credentials against the remote")
 	println("test: runs the tests deployed in your project")
+	println("test --branches a,b [--jobs 4]: tests several branches side by side in worktrees of the clone")
+	println("watch --remote [--interval 1m]: runs the tests whenever new commits land on the configured branch")
+	println("bisect --good <ref> [--bad <ref>]: finds the first commit that breaks the tests with git bisect")
+	println("hooks install|uninstall|status: runs the tests from git hooks before commits and pushes in the clone")
+	println("logs [run [step]] [--tail N] [--grep PATTERN]: lists past runs and shows, tails or searches their step logs")
+	println("config edit [section]: edits the config file and re-validates it before saving")
+	println("workspace sync: clones or updates every repository of the workspace in parallel")
+	println("workspace run <install|build|test>: runs a stage in every workspace repository")
+	println("cache: shows the mirrors in the shared clone cache and their size")
+	println("      prune [--max-age 30d] [--max-size 5GB]: removes old mirrors and trims the cache to a size")
+	println("credential <get|store|erase>: git credential helper serving the config's credentials")
 }
 
+// Printf prints with any registered secrets masked
 func Printf(format string, args ...interface{}) {
-	print(fmt.Sprintf(format, args...))
+	print(redact.String(fmt.Sprintf(format, args...)))
 }
 
 func Println(args ...interface{}) {
@@ -0,0 +1,62 @@
+package watch
+
+import (
+	"automateLife/git"
+	"automateLife/state"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultInterval is how often the remote is polled unless told otherwise
+const DefaultInterval = time.Minute
+
+// ErrNotBranch is returned when the configured ref is a tag or commit,
+// which never move
+var ErrNotBranch = errors.New("only branches can be watched")
+
+// Poller checks a remote branch for a commit the pipeline hasn't run for
+type Poller struct {
+	Client     git.Client
+	ConfigFile string // Locates the watch state
+	RepoUrl    string // As configured, to tell watch states of different repositories apart
+	URL        string // Remote to poll, with credentials
+	Branch     string // Empty for the remote's default branch
+	Ref        string // Overrides Branch, must name a branch
+}
+
+// Poll lists the branch on the remote and reports whether its tip differs
+// from the last commit recorded for it
+func (p *Poller) Poll() (*git.RemoteCheck, bool, error) {
+	check, err := git.VerifyRemote(p.Client, p.URL, p.Branch, p.Ref)
+	if err != nil {
+		return nil, false, err
+	}
+	if check.Kind != "branch" {
+		return nil, false, fmt.Errorf("%w: %s is a %s, set git.branch instead of git.ref", ErrNotBranch, check.Ref, check.Kind)
+	}
+
+	last, err := p.Last()
+	if err != nil {
+		return nil, false, err
+	}
+	return check, last == nil || last.Branch != check.Ref || last.SHA != check.SHA, nil
+}
+
+// Last returns the last commit recorded for this repository, or nil
+func (p *Poller) Last() (*state.Watch, error) {
+	last, err := state.LoadWatch(p.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	if last.SHA == "" || !git.SameRemote(last.RepoUrl, p.RepoUrl) {
+		return nil, nil
+	}
+	return last, nil
+}
+
+// Record marks sha on branch as processed so it isn't run again, not even
+// after a restart
+func (p *Poller) Record(branch string, sha string, passed bool) error {
+	return state.SaveWatch(p.ConfigFile, &state.Watch{RepoUrl: p.RepoUrl, Branch: branch, SHA: sha, Passed: passed})
+}
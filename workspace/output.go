@@ -0,0 +1,48 @@
+package workspace
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// prefixWriter writes whole lines to out, each starting with prefix, so
+// output of repositories running side by side stays readable. Writers
+// sharing out must share mu.
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	mu     *sync.Mutex
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := io.WriteString(w.out, w.prefix+string(w.buf[:i+1])); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes out a last line that did not end with a newline
+func (w *prefixWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w.out, w.prefix+string(w.buf)+"\n")
+	w.buf = nil
+	return err
+}
@@ -0,0 +1,224 @@
+package workspace
+
+import (
+	"automateLife/builder"
+	"automateLife/config"
+	"automateLife/git"
+	"automateLife/runlog"
+	"automateLife/state"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultJobs is how many repositories are worked on at once unless told otherwise
+const DefaultJobs = 4
+
+// Stages are the build steps 'workspace run' can fan out, in pipeline order
+var Stages = []string{"install", "build", "test"}
+
+// Repo is a workspace repository with its inherited settings resolved
+type Repo struct {
+	Name  string
+	Dir   string // Absolute clone directory
+	Git   config.GitConfig
+	Build config.BuildConfig
+}
+
+// Repos resolves the workspace repositories of cfg. Clone directories are
+// relative to the config file, like git.clone_dir.
+func Repos(configFile string, cfg *config.Config) ([]Repo, error) {
+	if len(cfg.Workspace) == 0 {
+		return nil, fmt.Errorf("no repositories in the workspace section of %s", configFile)
+	}
+
+	repos := make([]Repo, 0, len(cfg.Workspace))
+	owners := map[string]string{}
+	for i := range cfg.Workspace {
+		entry := &cfg.Workspace[i]
+		repo := Repo{Name: entry.RepoName(), Git: cfg.RepoGit(entry), Build: cfg.RepoBuild(entry)}
+
+		dir, err := state.ConfiguredCloneDir(configFile, &repo.Git)
+		if err != nil {
+			return nil, fmt.Errorf("workspace repository %s: %w", repo.Name, err)
+		}
+		if owner, ok := owners[dir]; ok {
+			return nil, fmt.Errorf("workspace repositories %s and %s would both be cloned into %s, set clone_dir on one of them", owner, repo.Name, dir)
+		}
+		owners[dir] = repo.Name
+		repo.Dir = dir
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+// SyncStatus is the outcome of cloning or updating one repository
+type SyncStatus struct {
+	Repo     Repo
+	Result   *git.SyncResult // Nil when Err is set
+	Err      error
+	CacheErr error // The clone went ahead without the cache
+}
+
+// Sync clones or updates every repository, at most jobs at a time. New
+// clones of repositories with git.cache set go through cache when it isn't
+// nil. The statuses are returned in the order of repos.
+func Sync(repos []Repo, jobs int, force bool, cache *git.Cache, newClient func(cfg *config.GitConfig) (git.Client, error)) []SyncStatus {
+	statuses := make([]SyncStatus, len(repos))
+	forEach(len(repos), jobs, func(i int) {
+		repo := repos[i]
+		statuses[i] = SyncStatus{Repo: repo}
+
+		repoUrl, err := git.BuildAuthURL(&repo.Git)
+		if err != nil {
+			statuses[i].Err = err
+			return
+		}
+		client, err := newClient(&repo.Git)
+		if err != nil {
+			statuses[i].Err = err
+			return
+		}
+		reference := ""
+		if cache != nil && repo.Git.Cache {
+			reference, statuses[i].CacheErr = cache.ReferenceFor(client, repoUrl, repo.Dir)
+		}
+		statuses[i].Result, statuses[i].Err = git.CloneOrUpdate(client, git.SyncOptions{
+			URL:       repoUrl,
+			Dir:       repo.Dir,
+			Branch:    repo.Git.Branch,
+			Ref:       repo.Git.Ref,
+			Force:     force,
+			Reference: reference,
+			Tuning:    git.TuningFromConfig(&repo.Git),
+		})
+	})
+	return statuses
+}
+
+// RunStatus is the outcome of running stages in one repository
+type RunStatus struct {
+	Repo     Repo
+	Stage    string // The stage that failed, or the last one that ran
+	Command  string // Empty when the repository had nothing to run
+	Duration time.Duration
+	Err      error
+}
+
+// StageCommand returns the command repo runs for stage. Tests fall back to
+// the language's default test command; install and build are skipped when
+// no command is configured.
+func (r *Repo) StageCommand(stage string) (string, error) {
+	switch stage {
+	case "install":
+		return r.Build.InstallCommand, nil
+	case "build":
+		return r.Build.BuildCommand, nil
+	case "test":
+		if r.Build.TestCommand != "" {
+			return r.Build.TestCommand, nil
+		}
+		return builder.GetDefaultTestCommand(r.Build.Language), nil
+	}
+	return "", fmt.Errorf("unknown stage %q, expected one of: install, build, test", stage)
+}
+
+// CommitEnv returns the AUTOMATELIFE_COMMIT_* variables describing the
+// commit checked out in the repository, or nil when it can't be read. The
+// ref is the branch checked out, or else git.ref or the commit itself.
+func (r *Repo) CommitEnv() map[string]string {
+	client, err := git.NewExecClient(nil)
+	if err != nil {
+		return nil
+	}
+	commits, err := client.Log(r.Dir, "HEAD", 1)
+	if err != nil || len(commits) == 0 {
+		return nil
+	}
+	ref, _ := client.CurrentBranch(r.Dir)
+	if ref == "" {
+		ref = r.Git.Ref
+	}
+	if ref == "" {
+		ref = commits[0].SHA
+	}
+	return commits[0].Env(ref)
+}
+
+// Run runs stage in every repository, at most jobs at a time. Output lines
+// are written to out prefixed with the repository name.
+func Run(repos []Repo, stage string, jobs int, out io.Writer, log *runlog.Run) ([]RunStatus, error) {
+	return Pipeline(repos, []string{stage}, jobs, out, log)
+}
+
+// Pipeline runs stages one after the other in every repository, at most
+// jobs repositories at a time. A repository stops at its first failing
+// stage. Output lines are written to out prefixed with the repository name,
+// and saved in log as the step <repository>/<stage> unless log is nil. The
+// stages see the repository's commit in AUTOMATELIFE_COMMIT_* variables.
+func Pipeline(repos []Repo, stages []string, jobs int, out io.Writer, log *runlog.Run) ([]RunStatus, error) {
+	for _, stage := range stages {
+		if _, err := (&Repo{}).StageCommand(stage); err != nil {
+			return nil, err
+		}
+	}
+
+	var mu sync.Mutex
+	statuses := make([]RunStatus, len(repos))
+	forEach(len(repos), jobs, func(i int) {
+		repo := repos[i]
+		status := &statuses[i]
+		status.Repo = repo
+		prefixed := &prefixWriter{prefix: repo.Name + " | ", out: out, mu: &mu}
+		ctx, cancel := repo.Build.Timeouts.Context(context.Background())
+		defer cancel()
+		var env []string
+		for key, value := range repo.CommitEnv() {
+			env = append(env, key+"="+value)
+		}
+		start := time.Now()
+		for _, stage := range stages {
+			command, _ := repo.StageCommand(stage)
+			if command == "" {
+				continue
+			}
+			status.Stage, status.Command = stage, command
+			step := builder.Step{
+				Name:    repo.Name + "/" + stage,
+				Command: command,
+				Shell:   repo.Build.Shell,
+				Dir:     repo.Dir,
+				Env:     env,
+				Timeout: repo.Build.Timeouts.Step(stage),
+				Grace:   repo.Build.Timeouts.Grace(),
+			}
+			if status.Err = log.RunStep(ctx, step, prefixed, prefixed); status.Err != nil {
+				break
+			}
+		}
+		prefixed.Flush()
+		status.Duration = time.Since(start)
+	})
+	return statuses, nil
+}
+
+// forEach calls fn for 0..n-1 with at most jobs calls running at once
+func forEach(n int, jobs int, fn func(i int)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	slots := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}